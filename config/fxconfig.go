@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -18,6 +19,134 @@ type Config struct {
 			Port     string `json:"port"`
 		} `json:"yugabyte"`
 	} `json:"db"`
+	Nats struct {
+		Url            string `json:"url"`
+		Stream         string `json:"stream"`
+		Consumer       string `json:"consumer"`
+		ListenSubject  string `json:"listen_subject"`
+		PublishSubject string `json:"publish_subject"`
+		// Workers is the maximum number of messages handled at the same time
+		Workers string `json:"workers"`
+		// AckWait is how long a message may go unacknowledged before it is redelivered
+		AckWait      string `json:"ack_wait"`
+		FetchMaxWait string `json:"fetch_max_wait"`
+		// MaxDeliver is how many times a message is tried before it is dead lettered
+		MaxDeliver      string `json:"max_deliver"`
+		RetryBackoff    string `json:"retry_backoff"`
+		MaxRetryBackoff string `json:"max_retry_backoff"`
+		// DeadLetterSubject receives the messages that could not be handled, they are
+		// kept on DeadLetterStream until they are replayed
+		DeadLetterSubject string `json:"dead_letter_subject"`
+		DeadLetterStream  string `json:"dead_letter_stream"`
+		// RateEventsPrefix starts the subjects rate changes are published to, followed by
+		// the tenant and the currency pair
+		RateEventsPrefix        string `json:"rate_events_prefix"`
+		RateEventsStream        string `json:"rate_events_stream"`
+		RateEventsRelayInterval string `json:"rate_events_relay_interval"`
+		// RateEventsRetention is how long published rate events stay in the outbox
+		RateEventsRetention string `json:"rate_events_retention"`
+		// ServicePrefix starts the subjects of the request-reply endpoints, the instances
+		// of the service share requests through ServiceQueueGroup
+		ServicePrefix     string `json:"service_prefix"`
+		ServiceQueueGroup string `json:"service_queue_group"`
+		// EventSource is the source of the published events and EventMode their
+		// content mode, structured or binary
+		EventSource string `json:"event_source"`
+		EventMode   string `json:"event_mode"`
+	} `json:"nats"`
+	Stream struct {
+		// Transport is the broker of the stream, nats or kafka. Subjects, consumer and
+		// dead letters are set in the block of the broker, both use the workers, retry
+		// and event settings of Nats
+		Transport string `json:"transport"`
+	} `json:"stream"`
+	Kafka struct {
+		// Brokers is a comma separated list of host:port
+		Brokers string `json:"brokers"`
+		// ConsumerGroup is the group of the instances consuming ListenTopic
+		ConsumerGroup   string `json:"consumer_group"`
+		ListenTopic     string `json:"listen_topic"`
+		PublishTopic    string `json:"publish_topic"`
+		DeadLetterTopic string `json:"dead_letter_topic"`
+		// RateEventsTopic receives the rate events, whose subjects start with the topic
+		RateEventsTopic string `json:"rate_events_topic"`
+	} `json:"kafka"`
+	Ingest struct {
+		Source      string `json:"source"`
+		Format      string `json:"format"`
+		TargetsFile string `json:"targets_file"`
+		Interval    string `json:"interval"`
+	} `json:"ingest"`
+	Providers struct {
+		File string `json:"file"`
+	} `json:"providers"`
+	Pricing struct {
+		RulesFile string `json:"rules_file"`
+		BaseTier  string `json:"base_tier"`
+	} `json:"pricing"`
+	Conversion struct {
+		ContractThresholdMode string `json:"contract_threshold_mode"`
+		QuoteTTL              string `json:"quote_ttl"`
+	} `json:"conversion"`
+	RateLimit struct {
+		// File holds the limits and daily quotas, requests are not limited when empty
+		File string `json:"file"`
+		// Store keeps the buckets in memory, or in the database when shared by instances
+		Store string `json:"store"`
+	} `json:"rate_limit"`
+	Auth struct {
+		// Mode is a comma separated list of jwt and apikey, or none
+		Mode        string `json:"mode"`
+		JwksUrl     string `json:"jwks_url"`
+		JwksFile    string `json:"jwks_file"`
+		Issuer      string `json:"issuer"`
+		Audience    string `json:"audience"`
+		TenantClaim string `json:"tenant_claim"`
+		BanksClaim  string `json:"banks_claim"`
+		RolesClaim  string `json:"roles_claim"`
+		ApiKeysFile string `json:"api_keys_file"`
+		// PolicyFile maps roles to permissions, the built in policy is used when empty
+		PolicyFile string `json:"policy_file"`
+	} `json:"auth"`
+	Telemetry struct {
+		// Exporter sends the traces and metrics over otlp-grpc or otlp-http, prints them
+		// with stdout, or drops them with none
+		Exporter string `json:"exporter"`
+		// Endpoint is the host:port of the collector, the OTEL_EXPORTER_OTLP_* variables
+		// are used when empty
+		Endpoint string `json:"endpoint"`
+		Insecure string `json:"insecure"`
+		// CaFile holds the certificates the collector is verified with instead of the
+		// ones of the system
+		CaFile string `json:"ca_file"`
+		// Headers is a comma separated list of key=value sent with every export
+		Headers string `json:"headers"`
+		// SampleRatio is the share of the traces started here that are sampled, traces
+		// continued from a caller follow its decision
+		SampleRatio    string `json:"sample_ratio"`
+		ServiceName    string `json:"service_name"`
+		ServiceVersion string `json:"service_version"`
+		Environment    string `json:"environment"`
+	} `json:"telemetry"`
+	Log struct {
+		// Level is the least severe level logged, it can be changed on
+		// /api/admin/log-level while running
+		Level  string `json:"level"`
+		Format string `json:"format"`
+		// Export sends the logs through the telemetry exporter along with the traces
+		Export string `json:"export"`
+	} `json:"log"`
+	Metrics struct {
+		// PrometheusAddress serves the metrics for scraping on /metrics, e.g. :9464,
+		// they are only pushed over OTLP when empty
+		PrometheusAddress string `json:"prometheus_address"`
+	} `json:"metrics"`
+	// Mode runs the api server (serve), the stream consumer (consume) or both (all)
+	Mode string `json:"mode"`
+	// ShutdownTimeout is how long requests, messages and rate updates in flight are
+	// waited for on shutdown, the telemetry flush and closing the connections then get
+	// as long again
+	ShutdownTimeout string `json:"shutdown_timeout"`
 }
 
 func GetConfig() *Config {
@@ -43,8 +172,202 @@ func GetConfig() *Config {
 		}
 	}
 
+	config.Mode = "serve"
+	if os.Getenv("RUN_MODE") != "" {
+		config.Mode = os.Getenv("RUN_MODE")
+	}
+	config.Log.Level = "info"
+	if os.Getenv("LOG_LEVEL") != "" {
+		config.Log.Level = os.Getenv("LOG_LEVEL")
+	}
+	config.Log.Format = "json"
+	if os.Getenv("LOG_FORMAT") != "" {
+		config.Log.Format = os.Getenv("LOG_FORMAT")
+	}
+	config.Log.Export = "false"
+	if os.Getenv("LOG_EXPORT") != "" {
+		config.Log.Export = os.Getenv("LOG_EXPORT")
+	}
+	config.Telemetry.Exporter = "otlp-grpc"
+	if os.Getenv("TELEMETRY_EXPORTER") != "" {
+		config.Telemetry.Exporter = os.Getenv("TELEMETRY_EXPORTER")
+	}
+	config.Telemetry.Endpoint = os.Getenv("TELEMETRY_ENDPOINT")
+	config.Telemetry.Insecure = "false"
+	if os.Getenv("TELEMETRY_INSECURE") != "" {
+		config.Telemetry.Insecure = os.Getenv("TELEMETRY_INSECURE")
+	}
+	config.Telemetry.CaFile = os.Getenv("TELEMETRY_CA_FILE")
+	config.Telemetry.Headers = os.Getenv("TELEMETRY_HEADERS")
+	config.Telemetry.SampleRatio = "1"
+	if os.Getenv("TELEMETRY_SAMPLE_RATIO") != "" {
+		config.Telemetry.SampleRatio = os.Getenv("TELEMETRY_SAMPLE_RATIO")
+	}
+	config.Telemetry.ServiceName = "aci-fx-go"
+	if os.Getenv("OTEL_SERVICE_NAME") != "" {
+		config.Telemetry.ServiceName = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	config.Telemetry.ServiceVersion = os.Getenv("SERVICE_VERSION")
+	config.Telemetry.Environment = os.Getenv("DEPLOYMENT_ENVIRONMENT")
+	config.Metrics.PrometheusAddress = os.Getenv("PROMETHEUS_ADDRESS")
+	config.ShutdownTimeout = "30s"
+	if os.Getenv("SHUTDOWN_TIMEOUT") != "" {
+		config.ShutdownTimeout = os.Getenv("SHUTDOWN_TIMEOUT")
+	}
+
+	config.Nats.Url = os.Getenv("NATS_URI")
+	config.Nats.Stream = os.Getenv("STREAM")
+	config.Nats.Consumer = os.Getenv("CONSUMER")
+	config.Nats.ListenSubject = os.Getenv("LISTEN_SUBJECT")
+	config.Nats.PublishSubject = os.Getenv("PUBLISH_SUBJECT")
+	config.Nats.Workers = "16"
+	if os.Getenv("NATS_WORKERS") != "" {
+		config.Nats.Workers = os.Getenv("NATS_WORKERS")
+	}
+	config.Nats.AckWait = "30s"
+	if os.Getenv("NATS_ACK_WAIT") != "" {
+		config.Nats.AckWait = os.Getenv("NATS_ACK_WAIT")
+	}
+	config.Nats.FetchMaxWait = "5s"
+	if os.Getenv("NATS_FETCH_MAX_WAIT") != "" {
+		config.Nats.FetchMaxWait = os.Getenv("NATS_FETCH_MAX_WAIT")
+	}
+	config.Nats.MaxDeliver = "5"
+	if os.Getenv("NATS_MAX_DELIVER") != "" {
+		config.Nats.MaxDeliver = os.Getenv("NATS_MAX_DELIVER")
+	}
+	config.Nats.RetryBackoff = "1s"
+	if os.Getenv("NATS_RETRY_BACKOFF") != "" {
+		config.Nats.RetryBackoff = os.Getenv("NATS_RETRY_BACKOFF")
+	}
+	config.Nats.MaxRetryBackoff = "1m"
+	if os.Getenv("NATS_MAX_RETRY_BACKOFF") != "" {
+		config.Nats.MaxRetryBackoff = os.Getenv("NATS_MAX_RETRY_BACKOFF")
+	}
+	config.Nats.DeadLetterSubject = "fx.deadletter"
+	if os.Getenv("NATS_DEAD_LETTER_SUBJECT") != "" {
+		config.Nats.DeadLetterSubject = os.Getenv("NATS_DEAD_LETTER_SUBJECT")
+	}
+	config.Nats.DeadLetterStream = "FX_DEADLETTER"
+	if os.Getenv("NATS_DEAD_LETTER_STREAM") != "" {
+		config.Nats.DeadLetterStream = os.Getenv("NATS_DEAD_LETTER_STREAM")
+	}
+	config.Nats.RateEventsPrefix = "fx.rates"
+	if os.Getenv("RATE_EVENTS_PREFIX") != "" {
+		config.Nats.RateEventsPrefix = os.Getenv("RATE_EVENTS_PREFIX")
+	}
+	config.Nats.RateEventsStream = "FX_RATES"
+	if os.Getenv("RATE_EVENTS_STREAM") != "" {
+		config.Nats.RateEventsStream = os.Getenv("RATE_EVENTS_STREAM")
+	}
+	config.Nats.ServicePrefix = "fx.svc"
+	if os.Getenv("NATS_SERVICE_PREFIX") != "" {
+		config.Nats.ServicePrefix = os.Getenv("NATS_SERVICE_PREFIX")
+	}
+	config.Nats.ServiceQueueGroup = "fx-rates"
+	if os.Getenv("NATS_SERVICE_QUEUE_GROUP") != "" {
+		config.Nats.ServiceQueueGroup = os.Getenv("NATS_SERVICE_QUEUE_GROUP")
+	}
+	config.Nats.RateEventsRelayInterval = "1s"
+	if os.Getenv("RATE_EVENTS_RELAY_INTERVAL") != "" {
+		config.Nats.RateEventsRelayInterval = os.Getenv("RATE_EVENTS_RELAY_INTERVAL")
+	}
+	config.Nats.RateEventsRetention = "168h"
+	if os.Getenv("RATE_EVENTS_RETENTION") != "" {
+		config.Nats.RateEventsRetention = os.Getenv("RATE_EVENTS_RETENTION")
+	}
+	config.Stream.Transport = "nats"
+	if os.Getenv("STREAM_TRANSPORT") != "" {
+		config.Stream.Transport = os.Getenv("STREAM_TRANSPORT")
+	}
+	config.Kafka.Brokers = os.Getenv("KAFKA_BROKERS")
+	config.Kafka.ConsumerGroup = os.Getenv("KAFKA_CONSUMER_GROUP")
+	config.Kafka.ListenTopic = os.Getenv("KAFKA_LISTEN_TOPIC")
+	config.Kafka.PublishTopic = os.Getenv("KAFKA_PUBLISH_TOPIC")
+	config.Kafka.DeadLetterTopic = "fx.deadletter"
+	if os.Getenv("KAFKA_DEAD_LETTER_TOPIC") != "" {
+		config.Kafka.DeadLetterTopic = os.Getenv("KAFKA_DEAD_LETTER_TOPIC")
+	}
+	config.Kafka.RateEventsTopic = "fx.rates"
+	if os.Getenv("KAFKA_RATE_EVENTS_TOPIC") != "" {
+		config.Kafka.RateEventsTopic = os.Getenv("KAFKA_RATE_EVENTS_TOPIC")
+	}
+	config.Nats.EventSource = "/aci-fx-go"
+	if os.Getenv("NATS_EVENT_SOURCE") != "" {
+		config.Nats.EventSource = os.Getenv("NATS_EVENT_SOURCE")
+	}
+	config.Nats.EventMode = "structured"
+	if os.Getenv("NATS_EVENT_MODE") != "" {
+		config.Nats.EventMode = os.Getenv("NATS_EVENT_MODE")
+	}
+
 	if mongoURI := os.Getenv("MONGODB_URI"); mongoURI != "" {
 		config.Db.Mongo.Url = mongoURI
 	}
+
+	if ingestSource := os.Getenv("INGEST_SOURCE"); ingestSource != "" {
+		config.Ingest.Source = ingestSource
+		config.Ingest.Format = "ecb"
+		if os.Getenv("INGEST_FORMAT") != "" {
+			config.Ingest.Format = os.Getenv("INGEST_FORMAT")
+		}
+		config.Ingest.TargetsFile = os.Getenv("INGEST_TARGETS_FILE")
+		config.Ingest.Interval = "1h"
+		if os.Getenv("INGEST_INTERVAL") != "" {
+			config.Ingest.Interval = os.Getenv("INGEST_INTERVAL")
+		}
+	}
+
+	config.Providers.File = os.Getenv("RATE_PROVIDERS_FILE")
+
+	config.Pricing.RulesFile = os.Getenv("PRICING_RULES_FILE")
+	config.Pricing.BaseTier = "MID"
+	if os.Getenv("PRICING_BASE_TIER") != "" {
+		config.Pricing.BaseTier = os.Getenv("PRICING_BASE_TIER")
+	}
+	config.Conversion.ContractThresholdMode = "flag"
+	if os.Getenv("CONTRACT_THRESHOLD_MODE") != "" {
+		config.Conversion.ContractThresholdMode = os.Getenv("CONTRACT_THRESHOLD_MODE")
+	}
+	config.Conversion.QuoteTTL = "30s"
+	if os.Getenv("QUOTE_TTL") != "" {
+		config.Conversion.QuoteTTL = os.Getenv("QUOTE_TTL")
+	}
+
+	config.RateLimit.File = os.Getenv("RATE_LIMITS_FILE")
+	config.RateLimit.Store = "memory"
+	if os.Getenv("RATE_LIMIT_STORE") != "" {
+		config.RateLimit.Store = os.Getenv("RATE_LIMIT_STORE")
+	}
+
+	config.Auth.JwksUrl = os.Getenv("JWKS_URL")
+	config.Auth.JwksFile = os.Getenv("JWKS_FILE")
+	config.Auth.Issuer = os.Getenv("JWT_ISSUER")
+	config.Auth.Audience = os.Getenv("JWT_AUDIENCE")
+	config.Auth.ApiKeysFile = os.Getenv("API_KEYS_FILE")
+	config.Auth.PolicyFile = os.Getenv("AUTH_POLICY_FILE")
+	config.Auth.Mode = os.Getenv("AUTH_MODE")
+	if config.Auth.Mode == "" {
+		var modes []string
+		if config.Auth.JwksUrl != "" || config.Auth.JwksFile != "" {
+			modes = append(modes, "jwt")
+		}
+		if config.Auth.ApiKeysFile != "" {
+			modes = append(modes, "apikey")
+		}
+		config.Auth.Mode = strings.Join(modes, ",")
+	}
+	config.Auth.TenantClaim = "tenant_id"
+	if os.Getenv("JWT_TENANT_CLAIM") != "" {
+		config.Auth.TenantClaim = os.Getenv("JWT_TENANT_CLAIM")
+	}
+	config.Auth.BanksClaim = "bank_ids"
+	if os.Getenv("JWT_BANKS_CLAIM") != "" {
+		config.Auth.BanksClaim = os.Getenv("JWT_BANKS_CLAIM")
+	}
+	config.Auth.RolesClaim = "roles"
+	if os.Getenv("JWT_ROLES_CLAIM") != "" {
+		config.Auth.RolesClaim = os.Getenv("JWT_ROLES_CLAIM")
+	}
 	return &config
 }
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultReplayLimit = 100
+
+var deadLetters = stream.GetDeadLetters(fxConfig)
+
+// ReplayDeadLetters publishes dead letters back to the stream so they are handled again.
+func ReplayDeadLetters(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	if deadLetters == nil {
+		e := &[]response.Error{
+			{Code: "NOT_CONFIGURED", Message: "Dead letters are not configured", Details: "NATS_URI or KAFKA_BROKERS and a dead letter subject must be configured"},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.DeadLetterReplayResponse](nil, response.BadRequest, e))
+	}
+	limit := c.QueryInt("limit", defaultReplayLimit)
+	if limit <= 0 {
+		e := &[]response.Error{
+			{Code: "INVALID_INPUT", Message: "limit is invalid", Details: "limit must be greater than zero"},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.DeadLetterReplayResponse](nil, response.BadRequest, e))
+	}
+
+	replayed, err := deadLetters.Replay(ctx, limit)
+	resp := &response.DeadLetterReplayResponse{Replayed: replayed}
+	if err != nil {
+		common.Log(ctx).Errorf("Error in replaying dead letters. Exception:%v", err)
+		e := &[]response.Error{
+			{Code: "FAILURE", Message: "Unable to replay dead letters", Details: err.Error()},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.DeadLetterReplayResponse](resp, response.InternalError, e))
+	}
+	return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.DeadLetterReplayResponse](resp, response.Success, nil))
+}
+
+// GetLogLevel returns the level of the logs.
+func GetLogLevel(c *fiber.Ctx) error {
+	resp := &response.LogLevelResponse{Level: common.LogLevel()}
+	return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.LogLevelResponse](resp, response.Success, nil))
+}
+
+// SetLogLevel changes the level of the logs until the next restart.
+func SetLogLevel(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	var levelReq request.LogLevelRequest
+	if err := c.BodyParser(&levelReq); err != nil {
+		return err
+	}
+	if err := common.SetLogLevel(levelReq.Level); err != nil {
+		e := &[]response.Error{
+			{Code: "INVALID_INPUT", Message: "level is invalid", Details: err.Error()},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.LogLevelResponse](nil, response.BadRequest, e))
+	}
+	common.Log(ctx).Warnf("Log level changed to %s", common.LogLevel())
+	resp := &response.LogLevelResponse{Level: common.LogLevel()}
+	return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.LogLevelResponse](resp, response.Success, nil))
+}
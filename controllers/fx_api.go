@@ -8,15 +8,53 @@ import (
 	"github.com/PeerIslands/aci-fx-go/service/bal"
 	"github.com/PeerIslands/aci-fx-go/service/common"
 	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/PeerIslands/aci-fx-go/service/pricing"
+	"github.com/PeerIslands/aci-fx-go/stream"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 var fxConfig = config.GetConfig()
 var dbService = dal.GetDataAccess(fxConfig)
 var fxService = &bal.Fx_service{
-	DbService: dbService,
+	DbService:             dbService,
+	Pricing:               pricing.GetEngine(fxConfig),
+	ContractThresholdMode: fxConfig.Conversion.ContractThresholdMode,
+	QuoteService:          dal.GetQuoteAccess(fxConfig),
+	QuoteTTL:              getQuoteTTL(),
+	TenantService:         dal.GetTenantAccess(fxConfig),
+	RateEvents:            getRateEventAccess(),
+	Transactions:          dal.GetTransactor(fxConfig),
+}
+
+// getRateEventAccess returns the outbox of rate events, which are only recorded when
+// there is a broker to relay them to.
+func getRateEventAccess() dal.RateEventDBService {
+	if !stream.Configured(fxConfig) {
+		return nil
+	}
+	return dal.GetRateEventAccess(fxConfig)
+}
+
+// getQuoteTTL returns how long quotes can be redeemed, the service does not start when
+// QUOTE_TTL is not a positive duration.
+func getQuoteTTL() time.Duration {
+	ttl, err := time.ParseDuration(fxConfig.Conversion.QuoteTTL)
+	if err != nil {
+		log.Fatal("Invalid quote ttl:", err)
+	}
+	if ttl <= 0 {
+		log.Fatalf("Invalid quote ttl: %s is not positive", fxConfig.Conversion.QuoteTTL)
+	}
+	return ttl
+}
+
+// GetFxService returns the service instance shared by the api routes.
+func GetFxService() *bal.Fx_service {
+	return fxService
 }
 
 func CreateForexRate(c *gin.Context) {
@@ -39,7 +77,8 @@ func GetForexRateByFilter(c *gin.Context) {
 	bankId, _ := strconv.Atoi(c.Query("bankId"))
 	baseCurrency := c.Query("baseCurrency")
 	targetCurrency := c.Query("targetCurrency")
-	c.IndentedJSON(http.StatusOK, fxService.GetForexRateByFilter(nil, tenantId, bankId, baseCurrency, targetCurrency))
+	tier := c.Query("tier")
+	c.IndentedJSON(http.StatusOK, fxService.GetForexRateByFilter(nil, tenantId, bankId, baseCurrency, targetCurrency, tier))
 }
 
 func UpdateForexRateById(c *gin.Context) {
@@ -61,7 +100,9 @@ func GetConvertedRate(c *gin.Context) {
 
 func UpdateForex(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Query("id"))
-	c.IndentedJSON(http.StatusOK, fxService.UpdateForexById(nil, id))
+	if body, err := common.ValidateAndReturnBody[request.UpdateForexDataRequest](c); err == nil {
+		c.IndentedJSON(http.StatusOK, fxService.UpdateForexById(nil, id, body))
+	}
 }
 
 func AddRoutes(e *gin.Engine) {
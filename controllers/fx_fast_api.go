@@ -1,60 +1,119 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/PeerIslands/aci-fx-go/model/dto/request"
 	"github.com/PeerIslands/aci-fx-go/model/dto/response"
 	"github.com/PeerIslands/aci-fx-go/model/validation"
+	"github.com/PeerIslands/aci-fx-go/service/auth"
 	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/pricing"
+	"github.com/PeerIslands/aci-fx-go/service/provider"
+	"github.com/PeerIslands/aci-fx-go/service/ratelimit"
 	"github.com/gofiber/fiber/v2"
-	"go.opentelemetry.io/otel"
-	"os"
+	"time"
 
 	"strconv"
 )
 
-var tracerName = "OTEL_SERVICE_NAME"
+var policy = auth.GetPolicy(fxConfig)
+var limiter = ratelimit.GetLimiter(fxConfig)
 
 func FhAddRoutes(e *fiber.App) {
 	// Convert currency
-	e.Get("/api/convert", common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
-		{ParamName: "tenantId", Required: true, ParamType: "int"},
+	e.Get("/api/convert", policy.Require(auth.PermissionConvert), limiter.Handler(), common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
+		{ParamName: "tenantId", Required: false, ParamType: "int"},
 		{ParamName: "bankId", Required: true, ParamType: "int"},
-		{ParamName: "baseCurrency", Required: true, ParamType: "string"},
+		{ParamName: "baseCurrency", Required: false, ParamType: "string"},
 		{ParamName: "targetCurrency", Required: true, ParamType: "string"},
 	}), FhGetConvertedRate)
 
 	// POST /api/forexrates
-	e.Post("/api/forexrates", InsertForexRate)
+	e.Post("/api/forexrates", policy.Require(auth.PermissionWriteRates), limiter.Handler(), InsertForexRate)
 
 	// POST /api/forexrates
-	e.Post("/api/forexrates/batch", BulkInsertForexRate)
+	e.Post("/api/forexrates/batch", policy.Require(auth.PermissionWriteRates), limiter.Handler(), BulkInsertForexRate)
 
 	// DELETE /api/forexrates?id=1
-	e.Delete("/api/forexrates/:id", DeleteForexById)
+	e.Delete("/api/forexrates/:id", policy.Require(auth.PermissionDeleteRates), limiter.Handler(), DeleteForexById)
 
 	// GET /api/forexrate?id=1
-	e.Get("/api/forexrates", common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
+	e.Get("/api/forexrates", policy.Require(auth.PermissionReadRates), limiter.Handler(), common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
 		{ParamName: "id", Required: true, ParamType: "int"},
 	}), FhGetForexRateById)
 
 	// PUT /api/forexrate?tenantId=1&bankId=1&baseCurrency=USD&targetCurrency=INR&tier=1
-	e.Put("/api/forexrates", common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
-		{ParamName: "tenantId", Required: true, ParamType: "int"},
+	e.Put("/api/forexrates", policy.Require(auth.PermissionWriteRates), limiter.Handler(), common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
+		{ParamName: "tenantId", Required: false, ParamType: "int"},
 		{ParamName: "bankId", Required: true, ParamType: "int"},
 		{ParamName: "baseCurrency", Required: true, ParamType: "string"},
 		{ParamName: "targetCurrency", Required: true, ParamType: "string"},
 	}), UpdateForexRate)
 
+	// POST /api/quotes
+	e.Post("/api/quotes", policy.Require(auth.PermissionConvert), limiter.Handler(), IssueQuote)
+
+	// GET /api/quotes/:id
+	e.Get("/api/quotes/:id", policy.Require(auth.PermissionReadRates), limiter.Handler(), GetQuote)
+
+	// POST /api/quotes/:id/redeem
+	e.Post("/api/quotes/:id/redeem", policy.Require(auth.PermissionConvert), limiter.Handler(), RedeemQuote)
+
+	// GET /api/tenants
+	e.Get("/api/tenants", policy.Require(auth.PermissionReadRates), limiter.Handler(), GetTenantConfigs)
+
+	// POST /api/tenants
+	e.Post("/api/tenants", policy.Require(auth.PermissionWriteTenants), limiter.Handler(), CreateTenantConfig)
+
+	// GET /api/tenants/:tenantId
+	e.Get("/api/tenants/:tenantId", policy.Require(auth.PermissionReadRates), limiter.Handler(), GetTenantConfig)
+
+	// PUT /api/tenants/:tenantId
+	e.Put("/api/tenants/:tenantId", policy.Require(auth.PermissionWriteTenants), limiter.Handler(), UpdateTenantConfig)
+
+	// DELETE /api/tenants/:tenantId
+	e.Delete("/api/tenants/:tenantId", policy.Require(auth.PermissionWriteTenants), limiter.Handler(), DeleteTenantConfig)
+
+	// PUT /api/tenants/:tenantId/banks/:bankId?docVersion=1
+	e.Put("/api/tenants/:tenantId/banks/:bankId", policy.Require(auth.PermissionWriteTenants), limiter.Handler(), UpsertBankConfig)
+
+	// DELETE /api/tenants/:tenantId/banks/:bankId?docVersion=1
+	e.Delete("/api/tenants/:tenantId/banks/:bankId", policy.Require(auth.PermissionWriteTenants), limiter.Handler(), DeleteBankConfig)
+
+	// GET /api/pricing/rules
+	e.Get("/api/pricing/rules", policy.Require(auth.PermissionReadRates), limiter.Handler(), FhGetPricingRules)
+
+	// PUT /api/pricing/rules
+	e.Put("/api/pricing/rules", policy.Require(auth.PermissionWritePricing), limiter.Handler(), FhSetPricingRules)
+
+	// GET /api/providers/status
+	e.Get("/api/providers/status", policy.Require(auth.PermissionReadRates), limiter.Handler(), FhGetProviderStatus)
+
+	// GET /api/events/schemas
+	e.Get("/api/events/schemas", policy.Require(auth.PermissionReadRates), limiter.Handler(), GetEventSchemas)
+
+	// GET /api/events/schemas/fx.request/v1
+	e.Get("/api/events/schemas/:type/:version", policy.Require(auth.PermissionReadRates), limiter.Handler(), GetEventSchema)
+
+	// POST /api/admin/deadletters/replay?limit=100
+	e.Post("/api/admin/deadletters/replay", policy.Require(auth.PermissionReplayMessages), limiter.Handler(), ReplayDeadLetters)
+
+	// GET /api/admin/log-level
+	e.Get("/api/admin/log-level", policy.Require(auth.PermissionManageLogs), limiter.Handler(), GetLogLevel)
+
+	// PUT /api/admin/log-level
+	e.Put("/api/admin/log-level", policy.Require(auth.PermissionManageLogs), limiter.Handler(), SetLogLevel)
+
 	// not in use
-	e.Put("/api/forexrate", common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
+	e.Put("/api/forexrate", policy.Require(auth.PermissionWriteRates), limiter.Handler(), common.ParamValidationMiddlewareFiber[response.ForexDataResponse]([]validation.ValidationRule{
 		{ParamName: "id", Required: true, ParamType: "int"},
 	}), UpdateForexById)
 }
 
 func InsertForexRate(c *fiber.Ctx) error {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	ctx, span := tracer.Start(c.Context(), c.Path())
-	defer span.End()
+	ctx := c.UserContext()
 	// convert body to forex_data_request
 	var forexRateReq request.CreateForexDataRequest
 	if err := c.BodyParser(&forexRateReq); err != nil {
@@ -63,15 +122,12 @@ func InsertForexRate(c *fiber.Ctx) error {
 	err := c.Status(fiber.StatusOK).JSON(fxService.CreateForexData(&ctx, forexRateReq))
 	if err != nil {
 		return err
-		return err
 	}
 	return nil
 }
 
 func BulkInsertForexRate(c *fiber.Ctx) error {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	ctx, span := tracer.Start(c.Context(), c.Path())
-	defer span.End()
+	ctx := c.UserContext()
 	// convert body to forex_data_request
 	var forexRatesReq []request.CreateForexDataRequest
 	if err := c.BodyParser(&forexRatesReq); err != nil {
@@ -86,9 +142,7 @@ func BulkInsertForexRate(c *fiber.Ctx) error {
 }
 
 func FhGetConvertedRate(c *fiber.Ctx) error {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	ctx, span := tracer.Start(c.Context(), c.Path())
-	defer span.End()
+	ctx := c.UserContext()
 	tenantId, _ := strconv.Atoi(c.Query("tenantId"))
 	bankId, _ := strconv.Atoi(c.Query("bankId"))
 	baseCurrency := c.Query("baseCurrency")
@@ -104,9 +158,7 @@ func FhGetConvertedRate(c *fiber.Ctx) error {
 }
 
 func FhGetForexRateById(c *fiber.Ctx) error {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	ctx, span := tracer.Start(c.Context(), c.Path())
-	defer span.End()
+	ctx := c.UserContext()
 	id, _ := strconv.Atoi(c.Query("id"))
 	err := c.Status(fiber.StatusOK).JSON(fxService.GetConvertedRateById(&ctx, id))
 	if err != nil {
@@ -116,16 +168,18 @@ func FhGetForexRateById(c *fiber.Ctx) error {
 }
 
 func UpdateForexRate(c *fiber.Ctx) error {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	ctx, span := tracer.Start(c.Context(), c.Path())
-	defer span.End()
+	ctx := c.UserContext()
 	tenantId, _ := strconv.Atoi(c.Query("tenantId"))
 	bankId, _ := strconv.Atoi(c.Query("bankId"))
 	baseCurrency := c.Query("baseCurrency")
 	targetCurrency := c.Query("targetCurrency")
 	tier := c.Query("tier")
+	var updateReq request.UpdateForexDataRequest
+	if err := c.BodyParser(&updateReq); err != nil {
+		return err
+	}
 
-	err := c.Status(fiber.StatusOK).JSON(fxService.UpdateForexRate(&ctx, tenantId, bankId, baseCurrency, targetCurrency, tier))
+	err := c.Status(fiber.StatusOK).JSON(fxService.UpdateForexRate(&ctx, tenantId, bankId, baseCurrency, targetCurrency, tier, updateReq))
 	if err != nil {
 		return err
 	}
@@ -133,12 +187,14 @@ func UpdateForexRate(c *fiber.Ctx) error {
 }
 
 func UpdateForexById(c *fiber.Ctx) error {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	ctx, span := tracer.Start(c.Context(), c.Path())
-	defer span.End()
+	ctx := c.UserContext()
 	id, _ := strconv.Atoi(c.Query("id"))
+	var updateReq request.UpdateForexDataRequest
+	if err := c.BodyParser(&updateReq); err != nil {
+		return err
+	}
 
-	err := c.Status(fiber.StatusOK).JSON(fxService.UpdateForexById(&ctx, id))
+	err := c.Status(fiber.StatusOK).JSON(fxService.UpdateForexById(&ctx, id, updateReq))
 	if err != nil {
 		return err
 	}
@@ -146,12 +202,93 @@ func UpdateForexById(c *fiber.Ctx) error {
 }
 
 func DeleteForexById(c *fiber.Ctx) error {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	ctx, span := tracer.Start(c.Context(), c.Path())
-	defer span.End()
+	ctx := c.UserContext()
 	err := c.Status(fiber.StatusOK).JSON(fxService.DeleteForexRateById(&ctx, c.Params("id")))
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func IssueQuote(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	var quoteReq request.QuoteRequest
+	if err := c.BodyParser(&quoteReq); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fxService.IssueQuote(&ctx, quoteReq))
+}
+
+func GetQuote(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	return c.Status(fiber.StatusOK).JSON(fxService.GetQuote(&ctx, c.Params("id")))
+}
+
+func RedeemQuote(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	return c.Status(fiber.StatusOK).JSON(fxService.RedeemQuote(&ctx, c.Params("id")))
+}
+
+// FhGetProviderStatus returns the state of the rate providers, which are shared by every
+// tenant, to unscoped admins only.
+func FhGetProviderStatus(c *fiber.Ctx) error {
+	if _, scoped := common.TenantScopeFromContext(c.UserContext()); scoped {
+		e := &[]response.Error{
+			{Code: "TENANT_FORBIDDEN", Message: "Access denied", Details: "The provider status is only available to admins"},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetArrayResponse[response.ProviderStatusResponse](nil, response.Forbidden, e))
+	}
+	now := time.Now()
+	var data []response.ProviderStatusResponse
+	for _, status := range provider.Statuses() {
+		item := response.ProviderStatusResponse{
+			Provider:     status.Provider,
+			LastError:    status.LastError,
+			RatesUpdated: status.RatesUpdated,
+			Stale:        status.Stale(now),
+		}
+		if !status.LastAttempt.IsZero() {
+			lastAttempt := status.LastAttempt
+			item.LastAttempt = &lastAttempt
+		}
+		if !status.LastSuccess.IsZero() {
+			lastSuccess := status.LastSuccess
+			item.LastSuccess = &lastSuccess
+		}
+		data = append(data, item)
+	}
+	return c.Status(fiber.StatusOK).JSON(common.GetArrayResponse[response.ProviderStatusResponse](&data, response.Success, nil))
+}
+
+// pricingTenant returns the tenant whose pricing rules the caller manages, tenant 0 holds
+// the global rules which only unscoped admins manage.
+func pricingTenant(c *fiber.Ctx) int {
+	scope, _ := common.TenantScopeFromContext(c.UserContext())
+	return scope.TenantId
+}
+
+func FhGetPricingRules(c *fiber.Ctx) error {
+	rules := fxService.Pricing.Rules(pricingTenant(c))
+	return c.Status(fiber.StatusOK).JSON(common.GetArrayResponse[pricing.Rule](&rules, response.Success, nil))
+}
+
+func FhSetPricingRules(c *fiber.Ctx) error {
+	var rules []pricing.Rule
+	if err := c.BodyParser(&rules); err != nil {
+		return err
+	}
+	tenantId := pricingTenant(c)
+	if err := fxService.Pricing.SetRules(tenantId, rules); errors.Is(err, common.ErrCrossTenant) {
+		e := &[]response.Error{
+			{Code: "TENANT_FORBIDDEN", Message: "Access denied", Details: fmt.Sprintf("Pricing rules must belong to tenant %d", tenantId)},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetArrayResponse[pricing.Rule](nil, response.Forbidden, e))
+	} else if err != nil {
+		e := &[]response.Error{
+			{Code: "INVALID_INPUT", Message: "Invalid pricing rules", Details: err.Error()},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetArrayResponse[pricing.Rule](nil, response.BadRequest, e))
+	}
+	rules = fxService.Pricing.Rules(tenantId)
+	return c.Status(fiber.StatusOK).JSON(common.GetArrayResponse[pricing.Rule](&rules, response.Success, nil))
+}
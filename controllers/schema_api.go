@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetEventSchemas lists the published schemas of the stream events.
+func GetEventSchemas(c *fiber.Ctx) error {
+	schemas := make([]response.EventSchemaResponse, 0, len(stream.EventSchemas))
+	for _, schema := range stream.EventSchemas {
+		schemas = append(schemas, response.EventSchemaResponse{
+			Type:       schema.Type,
+			Version:    schema.Version,
+			DataSchema: stream.SchemaURI(schema.Type, schema.Version),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(common.GetArrayResponse[response.EventSchemaResponse](&schemas, response.Success, nil))
+}
+
+// GetEventSchema returns the JSON Schema of an event type version, e.g. /api/events/schemas/fx.request/v1.
+func GetEventSchema(c *fiber.Ctx) error {
+	version, err := strconv.Atoi(strings.TrimPrefix(c.Params("version"), "v"))
+	schema, ok := stream.Schema(c.Params("type"), version)
+	if err != nil || !ok {
+		e := &[]response.Error{
+			{Code: "DATA_NOT_FOUND", Message: "Schema not found", Details: c.Params("type") + " " + c.Params("version") + " is not a published schema"},
+		}
+		return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.EventSchemaResponse](nil, response.NotFound, e))
+	}
+	c.Set(fiber.HeaderContentType, "application/schema+json")
+	return c.Status(fiber.StatusOK).Send(schema)
+}
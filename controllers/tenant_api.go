@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/gofiber/fiber/v2"
+)
+
+// invalidTenantParam answers a tenant route whose path parameter is not a number.
+func invalidTenantParam(c *fiber.Ctx, name string) error {
+	e := &[]response.Error{
+		{Code: "INVALID_INPUT", Message: name + " is invalid", Details: fmt.Sprintf("%s %q must be a number", name, c.Params(name))},
+	}
+	return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.TenantConfigResponse](nil, response.BadRequest, e))
+}
+
+func CreateTenantConfig(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	var tenantReq request.TenantConfigRequest
+	if err := c.BodyParser(&tenantReq); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fxService.CreateTenantConfig(&ctx, tenantReq))
+}
+
+func GetTenantConfigs(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	return c.Status(fiber.StatusOK).JSON(fxService.GetTenantConfigs(&ctx))
+}
+
+func GetTenantConfig(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantId, err := c.ParamsInt("tenantId")
+	if err != nil {
+		return invalidTenantParam(c, "tenantId")
+	}
+	return c.Status(fiber.StatusOK).JSON(fxService.GetTenantConfig(&ctx, tenantId))
+}
+
+// UpdateTenantConfig replaces the configuration of a tenant, the body carries the
+// docVersion it was read at.
+func UpdateTenantConfig(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantId, err := c.ParamsInt("tenantId")
+	if err != nil {
+		return invalidTenantParam(c, "tenantId")
+	}
+	var tenantReq request.TenantConfigRequest
+	if err := c.BodyParser(&tenantReq); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fxService.UpdateTenantConfig(&ctx, tenantId, tenantReq))
+}
+
+func DeleteTenantConfig(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantId, err := c.ParamsInt("tenantId")
+	if err != nil {
+		return invalidTenantParam(c, "tenantId")
+	}
+	return c.Status(fiber.StatusOK).JSON(fxService.DeleteTenantConfig(&ctx, tenantId))
+}
+
+// UpsertBankConfig adds or replaces a bank of a tenant at the ?docVersion= of the tenant.
+func UpsertBankConfig(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantId, err := c.ParamsInt("tenantId")
+	if err != nil {
+		return invalidTenantParam(c, "tenantId")
+	}
+	bankId, err := c.ParamsInt("bankId")
+	if err != nil {
+		return invalidTenantParam(c, "bankId")
+	}
+	var bankReq entity.BankConfig
+	if err := c.BodyParser(&bankReq); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fxService.UpsertBankConfig(&ctx, tenantId, bankId, c.QueryInt("docVersion"), bankReq))
+}
+
+// DeleteBankConfig removes a bank of a tenant at the ?docVersion= of the tenant.
+func DeleteBankConfig(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	tenantId, err := c.ParamsInt("tenantId")
+	if err != nil {
+		return invalidTenantParam(c, "tenantId")
+	}
+	bankId, err := c.ParamsInt("bankId")
+	if err != nil {
+		return invalidTenantParam(c, "bankId")
+	}
+	return c.Status(fiber.StatusOK).JSON(fxService.DeleteBankConfig(&ctx, tenantId, bankId, c.QueryInt("docVersion")))
+}
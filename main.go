@@ -2,19 +2,33 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/PeerIslands/aci-fx-go/config"
 	"github.com/PeerIslands/aci-fx-go/controllers"
 	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/auth"
 	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/PeerIslands/aci-fx-go/service/ingest"
+	"github.com/PeerIslands/aci-fx-go/service/provider"
+	"github.com/PeerIslands/aci-fx-go/service/telemetry"
+	"github.com/PeerIslands/aci-fx-go/stream"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/propagation"
-	sdkresource "go.opentelemetry.io/otel/sdk/resource"
 
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	//"github.com/gofiber/fiber/v2"
@@ -22,34 +36,177 @@ import (
 	"log"
 )
 
-var (
-	resource          *sdkresource.Resource
-	initResourcesOnce sync.Once
+const (
+	ModeServe   = "serve"
+	ModeConsume = "consume"
+	ModeAll     = "all"
 )
 
-func otelMiddleware() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		tracer := otel.Tracer(os.Getenv("OTEL_SERVICE_NAME"))
-		ctx, span := tracer.Start(c.Context(), c.Path())
-		defer span.End()
+func main() {
+	fxConfig := config.GetConfig()
+	if err := common.InitLog(fxConfig.Log.Level, fxConfig.Log.Format); err != nil {
+		log.Fatalf("Logs: %v", err)
+	}
+	tp, mp, lp, metricsServer := initTelemetry(fxConfig)
+	mode := fxConfig.Mode
+	if len(os.Args) > 1 {
+		mode = os.Args[1]
+	}
+	if mode != ModeServe && mode != ModeConsume && mode != ModeAll {
+		log.Fatalf("Unknown mode %q, use %s, %s or %s", mode, ModeServe, ModeConsume, ModeAll)
+	}
+	shutdownTimeout, err := time.ParseDuration(fxConfig.ShutdownTimeout)
+	if err != nil {
+		log.Fatalf("Shutdown timeout: %v", err)
+	}
 
-		c.SetUserContext(ctx)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		return c.Next()
+	if metricsServer != nil {
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				common.Logger.Errorf("Error in serving the metrics. Exception:%v", err)
+			}
+		}()
 	}
-}
 
-func main() {
-	common.InitLog()
+	ingesting := startIngestion(ctx, fxConfig)
+	refreshing := startRateRefresh(ctx, fxConfig)
+
+	// both modes share the service and data access built by the controllers package
+	var relay *stream.RelayRunner
+	if outbox := controllers.GetFxService().RateEvents; outbox != nil {
+		relay, err = stream.StartRelay(fxConfig, outbox)
+		if err != nil {
+			log.Fatal("Error:", err)
+		}
+	}
+
+	var consumer *stream.Consumer
+	var natsService *stream.Service
+	if mode == ModeConsume || mode == ModeAll {
+		consumer, err = stream.Start(fxConfig, controllers.GetFxService())
+		if err != nil {
+			log.Fatal("Error:", err)
+		}
+		// request-reply is served over core NATS only
+		if fxConfig.Stream.Transport == stream.TransportNats {
+			natsService, err = stream.StartService(fxConfig, controllers.GetFxService())
+			if err != nil {
+				log.Fatal("Error:", err)
+			}
+		}
+	}
+
+	var fiberApp *fiber.App
+	serverErr := make(chan error, 1)
+	if mode == ModeServe || mode == ModeAll {
+		fiberApp = newFiberApp(fxConfig)
+		go func() {
+			serverErr <- fiberApp.Listen("0.0.0.0:8080")
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		common.Logger.Errorf("Error in running the api server. Exception:%v", err)
+	}
+
+	// a second signal kills the process right away
+	stop()
+	common.Logger.Infof("Shutting down %s mode", mode)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// stop taking requests and messages, then wait for those in flight
+	var wg sync.WaitGroup
+	for _, done := range []<-chan struct{}{ingesting, refreshing} {
+		if done == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(done <-chan struct{}) {
+			defer wg.Done()
+			select {
+			case <-done:
+			case <-shutdownCtx.Done():
+				common.Logger.Errorf("Error in stopping the rate updates. Exception:%v", shutdownCtx.Err())
+			}
+		}(done)
+	}
+	if natsService != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = natsService.Shutdown(shutdownCtx)
+		}()
+	}
+	if fiberApp != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fiberApp.ShutdownWithContext(shutdownCtx); err != nil {
+				common.Logger.Errorf("Error in shutting down the api server. Exception:%v", err)
+			}
+		}()
+	}
+	if consumer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = consumer.Shutdown(shutdownCtx)
+		}()
+	}
+	if relay != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = relay.Shutdown(shutdownCtx)
+		}()
+	}
+	wg.Wait()
 
-	initTracerProvider()
+	// flushing and closing get their own deadline, the one above may be used up
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelClose()
 
+	// export the spans and metrics of the work just done before the connections go away
+	if err := tp.Shutdown(closeCtx); err != nil {
+		common.Logger.Errorf("Error in shutting down the tracer provider. Exception:%v", err)
+	}
+	if err := mp.Shutdown(closeCtx); err != nil {
+		common.Logger.Errorf("Error in shutting down the meter provider. Exception:%v", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(closeCtx); err != nil {
+			common.Logger.Errorf("Error in shutting down the metrics server. Exception:%v", err)
+		}
+	}
+	if err := dal.Close(closeCtx); err != nil {
+		common.Logger.Errorf("Error in closing the database connections. Exception:%v", err)
+	}
+	if consumer != nil {
+		consumer.Close()
+	}
+	if relay != nil {
+		relay.Close()
+	}
+	common.Logger.Infof("Shut down %s mode", mode)
+	if lp != nil {
+		_ = lp.Shutdown(closeCtx)
+	}
+}
+
+func newFiberApp(fxConfig *config.Config) *fiber.App {
 	/*router := gin.Default()
 	router.Use(GlobalErrorHandler)
 	controllers.AddRoutes(router)
 	log.Fatal(router.Run("0.0.0.0:8080"))*/
 
 	fiberApp := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
 		ErrorHandler: func(ctx *fiber.Ctx, err error) error {
 			code := fiber.StatusOK
 			err = ctx.Status(code).JSON(common.GetSimpleResponse[response.ForexDataResponse](nil, response.InternalError, &[]response.Error{
@@ -63,48 +220,81 @@ func main() {
 	})
 
 	fiberApp.Use(logger.New())
-	fiberApp.Use(otelMiddleware())
+	fiberApp.Use(telemetry.Middleware())
+	if authenticators := auth.GetAuthenticators(fxConfig); authenticators != nil {
+		fiberApp.Use(auth.Middleware(authenticators))
+		fiberApp.Use(auth.TenantScope())
+	} else {
+		common.Logger.Warn("Authentication is disabled, every caller can manage rates of any tenant")
+	}
 
 	controllers.FhAddRoutes(fiberApp)
-	err := fiberApp.Listen("0.0.0.0:8080")
+	return fiberApp
+}
+
+func startIngestion(ctx context.Context, fxConfig *config.Config) <-chan struct{} {
+	if fxConfig.Ingest.Source == "" {
+		return nil
+	}
+	ingester, err := ingest.NewIngester(fxConfig, controllers.GetFxService())
 	if err != nil {
-		log.Fatal("Error:", err)
+		log.Fatalf("Reference rate ingestion: %v", err)
 	}
-	//stream.Connect()
-
+	return ingester.Start(ctx)
 }
 
-func initTracerProvider() *sdktrace.TracerProvider {
-	ctx := context.Background()
+func startRateRefresh(ctx context.Context, fxConfig *config.Config) <-chan struct{} {
+	if fxConfig.Providers.File == "" {
+		return nil
+	}
+	settings, err := provider.LoadSettings(fxConfig.Providers.File)
+	if err != nil {
+		log.Fatalf("Rate providers: %v", err)
+	}
+	refresher, err := provider.NewRefresher(settings, controllers.GetFxService())
+	if err != nil {
+		log.Fatalf("Rate providers: %v", err)
+	}
+	return refresher.Start(ctx)
+}
 
-	exporter, err := otlptracegrpc.New(ctx)
+// initTelemetry installs the tracer and meter providers as configured, along with the
+// server exposing the metrics for scraping when one is configured. The logger provider
+// is only installed when the logs are exported.
+func initTelemetry(fxConfig *config.Config) (*sdktrace.TracerProvider, *sdkmetric.MeterProvider, *sdklog.LoggerProvider, *http.Server) {
+	// a collector that cannot be reached only loses the telemetry, the service goes on
+	otel.SetErrorHandler(telemetry.ErrorHandler(common.Logger))
+	resource, err := telemetry.NewResource(fxConfig)
+	if err != nil {
+		log.Fatalf("Telemetry resource: %v", err)
+	}
+	tp, err := telemetry.NewTracerProvider(fxConfig, resource)
 	if err != nil {
-		log.Fatalf("OTLP Trace gRPC Creation: %v", err)
+		log.Fatalf("Tracer provider: %v", err)
 	}
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(initResource()),
-	)
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
-	return tp
-}
 
-func initResource() *sdkresource.Resource {
-	initResourcesOnce.Do(func() {
-		extraResources, _ := sdkresource.New(
-			context.Background(),
-			sdkresource.WithOS(),
-			sdkresource.WithProcess(),
-			sdkresource.WithContainer(),
-			sdkresource.WithHost(),
-		)
-		resource, _ = sdkresource.Merge(
-			sdkresource.Default(),
-			extraResources,
-		)
-	})
-	return resource
+	mp, metricsServer, err := telemetry.NewMeterProvider(fxConfig, resource)
+	if err != nil {
+		log.Fatalf("Meter provider: %v", err)
+	}
+	otel.SetMeterProvider(mp)
+
+	export, err := strconv.ParseBool(fxConfig.Log.Export)
+	if err != nil {
+		log.Fatalf("Log export: %v", err)
+	}
+	var lp *sdklog.LoggerProvider
+	if export {
+		lp, err = telemetry.NewLoggerProvider(fxConfig, resource)
+		if err != nil {
+			log.Fatalf("Logger provider: %v", err)
+		}
+		global.SetLoggerProvider(lp)
+		common.Logger.AddHook(telemetry.LogHook(lp))
+	}
+	return tp, mp, lp, metricsServer
 }
 
 /*func GlobalErrorHandler(c *gin.Context) {
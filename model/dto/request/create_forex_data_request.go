@@ -1,6 +1,10 @@
 package request
 
-import "time"
+import (
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+)
 
 type CreateForexDataRequest struct {
 	TenantId int `json:"tenantId" binding:"required"`
@@ -27,7 +31,7 @@ type CreateForexDataRequest struct {
 
 	ExpirationDate *time.Time `json:"expirationDate"`
 
-	ContractRequirementThreshold string `json:"contractRequirementThreshold,omitempty"`
+	ContractRequirementThreshold *entity.Threshold `json:"contractRequirementThreshold,omitempty"`
 }
 
 type FxDataRequest struct {
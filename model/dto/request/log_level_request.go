@@ -0,0 +1,6 @@
+package request
+
+type LogLevelRequest struct {
+	// One of panic, fatal, error, warn, info, debug or trace
+	Level string `json:"level" binding:"required"`
+}
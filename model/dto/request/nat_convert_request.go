@@ -1,6 +1,14 @@
 package request
 
+const (
+	NatActionConvert = "convert"
+	NatActionQuote   = "quote"
+	NatActionRedeem  = "redeem"
+)
+
 type NatConvertRequest struct {
+	// Action is one of convert, quote or redeem, convert when empty
+	Action         string  `json:"action,omitempty"`
 	TenantID       int     `json:"tenantId"`
 	BankID         int     `json:"bankId"`
 	BaseCurrency   string  `json:"baseCurrency"`
@@ -8,4 +16,8 @@ type NatConvertRequest struct {
 	Tier           string  `json:"tier"`
 	Amount         float64 `json:"amount"`
 	InitiatedOn    int64   `json:"initiatedOn"`
+	// Side of a quote, BUY or SELL
+	Side string `json:"side,omitempty"`
+	// QuoteId of the quote to redeem
+	QuoteId string `json:"quoteId,omitempty"`
 }
@@ -0,0 +1,10 @@
+package request
+
+type NatRateRequest struct {
+	TenantID       int    `json:"tenantId"`
+	BankID         int    `json:"bankId"`
+	BaseCurrency   string `json:"baseCurrency"`
+	TargetCurrency string `json:"targetCurrency"`
+	// Tier of the rate, every tier when empty
+	Tier string `json:"tier,omitempty"`
+}
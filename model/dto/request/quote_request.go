@@ -0,0 +1,19 @@
+package request
+
+type QuoteRequest struct {
+	TenantId int `json:"tenantId" binding:"required"`
+
+	BankId int `json:"bankId" binding:"required"`
+
+	BaseCurrency string `json:"baseCurrency" binding:"required"`
+
+	TargetCurrency string `json:"targetCurrency" binding:"required"`
+
+	Tier string `json:"tier,omitempty"`
+
+	// BUY when the bank buys the base currency from the customer, SELL when it sells it
+	Side string `json:"side" binding:"required"`
+
+	// The amount in base currency
+	Amount float64 `json:"amount" binding:"required"`
+}
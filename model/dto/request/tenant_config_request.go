@@ -0,0 +1,28 @@
+package request
+
+import "github.com/PeerIslands/aci-fx-go/model/entity"
+
+type TenantConfigRequest struct {
+	TenantId int `json:"tenantId"`
+
+	Name string `json:"name"`
+
+	BaseCurrency string `json:"baseCurrency"`
+
+	// The currency used to cross rates of pairs without a direct rate
+	PivotCurrency string `json:"pivotCurrency,omitempty"`
+
+	// The tier of conversions that do not ask for one
+	DefaultTier string `json:"defaultTier,omitempty"`
+
+	// Pairs as BASE/TARGET, every pair is allowed when empty
+	AllowedPairs []string `json:"allowedPairs,omitempty"`
+
+	Rounding *entity.RoundingPolicy `json:"rounding,omitempty"`
+
+	Banks []entity.BankConfig `json:"banks,omitempty"`
+
+	// The version of the configuration an update was made to, it is rejected when the
+	// configuration was changed since
+	DocVersion int `json:"docVersion,omitempty"`
+}
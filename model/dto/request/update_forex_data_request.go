@@ -1,6 +1,10 @@
 package request
 
-import "time"
+import (
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+)
 
 type UpdateForexDataRequest struct {
 	DirectIndirectFlag string `json:"directIndirectFlag,omitempty"`
@@ -17,5 +21,5 @@ type UpdateForexDataRequest struct {
 
 	ExpirationDate *time.Time `json:"expirationDate,omitempty"`
 
-	ContractRequirementThreshold string `json:"contractRequirementThreshold,omitempty"`
+	ContractRequirementThreshold *entity.Threshold `json:"contractRequirementThreshold,omitempty"`
 }
@@ -8,6 +8,8 @@
  */
 package response
 
+import "github.com/PeerIslands/aci-fx-go/model/entity"
+
 type ConversionResponse struct {
 	// The initial amount
 	Amount float64 `json:"amount,omitempty"`
@@ -17,6 +19,8 @@ type ConversionResponse struct {
 	BaseCurrency string `json:"baseCurrency,omitempty"`
 	// The target currency code
 	TargetCurrency string `json:"targetCurrency,omitempty"`
+	// The tier the rate was taken from
+	Tier string `json:"tier,omitempty"`
 	// Timestamp when the conversion was initiated(Epoch time)
 	InitiatedOn int64 `json:"initiatedOn,omitempty"`
 	// Timestamp when the conversion was initiated(Epoch time)
@@ -25,4 +29,22 @@ type ConversionResponse struct {
 	HostName     string `json:"hostName,omitempty"`
 	// The conversion rate
 	Rate float64 `json:"rate,omitempty"`
+	// The pricing rule the rate was derived with, empty when the stored tier rate was used
+	AppliedRule *AppliedRule `json:"appliedRule,omitempty"`
+	// Whether the amount exceeds the threshold above which a deal contract is required
+	ContractRequired bool `json:"contractRequired,omitempty"`
+	// The threshold that was exceeded
+	ContractRequirementThreshold *entity.Threshold `json:"contractRequirementThreshold,omitempty"`
+}
+
+type AppliedRule struct {
+	// The id of the pricing rule
+	RuleId string `json:"ruleId"`
+	// The base rate the customer rate was derived from
+	MidRate float64 `json:"midRate"`
+	// The spread applied to the mid rate in basis points
+	SpreadBps float64 `json:"spreadBps"`
+	// The amount band that decided the spread
+	BandMinAmount *float64 `json:"bandMinAmount,omitempty"`
+	BandMaxAmount *float64 `json:"bandMaxAmount,omitempty"`
 }
@@ -0,0 +1,6 @@
+package response
+
+type DeadLetterReplayResponse struct {
+	// The number of dead letters published back to the subject they were received on
+	Replayed int `json:"replayed"`
+}
@@ -0,0 +1,10 @@
+package response
+
+type EventSchemaResponse struct {
+	// The CloudEvents type of the event
+	Type string `json:"type"`
+	// The version of the event data
+	Version int `json:"version"`
+	// The dataschema attribute of events of this version
+	DataSchema string `json:"dataSchema"`
+}
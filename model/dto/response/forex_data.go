@@ -1,6 +1,10 @@
 package response
 
-import "time"
+import (
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+)
 
 type ForexDataResponse struct {
 	Id any `json:"id"`
@@ -29,7 +33,11 @@ type ForexDataResponse struct {
 
 	ExpirationDate *time.Time `json:"expirationDate"`
 
-	ContractRequirementThreshold string `json:"contractRequirementThreshold"`
+	ContractRequirementThreshold *entity.Threshold `json:"contractRequirementThreshold"`
 
 	DocVersion int `json:"docVersion"`
+
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	UpdatedBy string `json:"updatedBy,omitempty"`
 }
@@ -0,0 +1,6 @@
+package response
+
+type LogLevelResponse struct {
+	// The least severe level logged
+	Level string `json:"level"`
+}
@@ -0,0 +1,17 @@
+package response
+
+import "time"
+
+type ProviderStatusResponse struct {
+	Provider string `json:"provider"`
+
+	LastAttempt *time.Time `json:"lastAttempt"`
+
+	LastSuccess *time.Time `json:"lastSuccess"`
+
+	LastError string `json:"lastError,omitempty"`
+
+	RatesUpdated int `json:"ratesUpdated"`
+
+	Stale bool `json:"stale"`
+}
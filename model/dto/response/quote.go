@@ -0,0 +1,39 @@
+package response
+
+import "time"
+
+type QuoteResponse struct {
+	// The id to redeem the quote with
+	QuoteId string `json:"quoteId"`
+
+	TenantId int `json:"tenantId"`
+
+	BankId int `json:"bankId"`
+
+	BaseCurrency string `json:"baseCurrency"`
+
+	TargetCurrency string `json:"targetCurrency"`
+
+	Tier string `json:"tier"`
+
+	Side string `json:"side"`
+
+	// The amount in base currency
+	Amount float64 `json:"amount"`
+
+	// The locked rate
+	Rate float64 `json:"rate"`
+
+	// The amount in target currency at the locked rate
+	ConvertedAmount float64 `json:"convertedAmount"`
+
+	Status string `json:"status"`
+
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	RedeemedAt *time.Time `json:"redeemedAt,omitempty"`
+
+	ContractRequired bool `json:"contractRequired,omitempty"`
+
+	AppliedRule *AppliedRule `json:"appliedRule,omitempty"`
+}
@@ -7,4 +7,14 @@ const (
 	BadRequest    StatusCode = "BadRequest"
 	InternalError StatusCode = "InternalServerError"
 	NotFound      StatusCode = "NotFound"
+	// ContractRequired is returned when a conversion exceeds the contract requirement threshold
+	ContractRequired StatusCode = "ContractRequired"
+	// Conflict is returned when the state of a record does not allow the operation
+	Conflict StatusCode = "Conflict"
+	// Unauthorized is returned when the caller could not be authenticated
+	Unauthorized StatusCode = "Unauthorized"
+	// Forbidden is returned when the caller is not allowed to access the tenant or resource
+	Forbidden StatusCode = "Forbidden"
+	// TooManyRequests is returned when the caller exceeded its rate limit or daily quota
+	TooManyRequests StatusCode = "TooManyRequests"
 )
@@ -0,0 +1,27 @@
+package response
+
+import "github.com/PeerIslands/aci-fx-go/model/entity"
+
+type TenantConfigResponse struct {
+	TenantId int `json:"tenantId"`
+
+	Name string `json:"name"`
+
+	BaseCurrency string `json:"baseCurrency"`
+
+	PivotCurrency string `json:"pivotCurrency,omitempty"`
+
+	DefaultTier string `json:"defaultTier,omitempty"`
+
+	AllowedPairs []string `json:"allowedPairs,omitempty"`
+
+	Rounding *entity.RoundingPolicy `json:"rounding,omitempty"`
+
+	Banks []entity.BankConfig `json:"banks,omitempty"`
+
+	DocVersion int `json:"docVersion"`
+
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	UpdatedBy string `json:"updatedBy,omitempty"`
+}
@@ -14,12 +14,26 @@ type ForexData struct {
 	TolerancePercentage          int        `bson:"tolerancePercentage"`
 	EffectiveDate                *time.Time `bson:"effectiveDate"`
 	ExpirationDate               *time.Time `bson:"expirationDate"`
-	ContractRequirementThreshold string     `bson:"contractRequirementThreshold"`
+	ContractRequirementThreshold *Threshold `bson:"contractRequirementThreshold"`
 	TenantID                     int        `bson:"tenantId"`
 	BankID                       int        `bson:"bankId"`
 	BaseCurrency                 string     `bson:"baseCurrency"`
 	TargetCurrency               string     `bson:"targetCurrency"`
 	CreatedDate                  time.Time  `bson:"createdDate"`
+	CreatedBy                    string     `bson:"createdBy"`
 	DocVersion                   int        `bson:"docVersion"`
 	UpdatedDate                  time.Time  `bson:"updatedDate"`
+	UpdatedBy                    string     `bson:"updatedBy"`
+}
+
+func (f ForexData) GetTenantId() int {
+	return f.TenantID
+}
+
+func (f ForexData) GetBankId() int {
+	return f.BankID
+}
+
+func (f ForexData) GetContractRequirementThreshold() *Threshold {
+	return f.ContractRequirementThreshold
 }
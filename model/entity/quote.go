@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+const (
+	QuoteSideBuy  = "BUY"
+	QuoteSideSell = "SELL"
+
+	QuoteStatusIssued   = "ISSUED"
+	QuoteStatusRedeemed = "REDEEMED"
+)
+
+type Quote struct {
+	ID              string     `bson:"_id"`
+	TenantID        int        `bson:"tenantId"`
+	BankID          int        `bson:"bankId"`
+	BaseCurrency    string     `bson:"baseCurrency"`
+	TargetCurrency  string     `bson:"targetCurrency"`
+	Tier            string     `bson:"tier"`
+	Side            string     `bson:"side"`
+	Amount          float64    `bson:"amount"`
+	Rate            float64    `bson:"rate"`
+	ConvertedAmount float64    `bson:"convertedAmount"`
+	Status          string     `bson:"status"`
+	ExpiresAt       time.Time  `bson:"expiresAt"`
+	RedeemedAt      *time.Time `bson:"redeemedAt"`
+	RedeemedBy      string     `bson:"redeemedBy"`
+	CreatedDate     time.Time  `bson:"createdDate"`
+	CreatedBy       string     `bson:"createdBy"`
+}
+
+func (q Quote) GetTenantId() int {
+	return q.TenantID
+}
+
+func (q Quote) GetBankId() int {
+	return q.BankID
+}
@@ -0,0 +1,79 @@
+package entity
+
+import "time"
+
+const (
+	RateEventCreated = "rate.created"
+	RateEventUpdated = "rate.updated"
+	RateEventDeleted = "rate.deleted"
+
+	// RateEventVersion is the version of the rate event schema, it changes when a field
+	// is removed or changes meaning
+	RateEventVersion = 1
+)
+
+// RateEvent records a change of a forex rate. Events are stored in the outbox along with
+// the change and published to the stream by a relay, PublishedAt is empty until then.
+type RateEvent struct {
+	ID             string      `bson:"_id" json:"id" pg:",pk"`
+	Type           string      `bson:"type" json:"type"`
+	Version        int         `bson:"version" json:"version"`
+	TenantID       int         `bson:"tenantId" json:"tenantId"`
+	BankID         int         `bson:"bankId" json:"bankId"`
+	BaseCurrency   string      `bson:"baseCurrency" json:"baseCurrency"`
+	TargetCurrency string      `bson:"targetCurrency" json:"targetCurrency"`
+	Tier           string      `bson:"tier" json:"tier"`
+	DocVersion     int         `bson:"docVersion" json:"docVersion"`
+	Before         *RateValues `bson:"before" json:"before,omitempty"`
+	After          *RateValues `bson:"after" json:"after,omitempty"`
+	Actor          string      `bson:"actor" json:"actor,omitempty"`
+	OccurredAt     time.Time   `bson:"occurredAt" json:"occurredAt"`
+	PublishedAt    *time.Time  `bson:"publishedAt" json:"-"`
+	// TraceContext is the trace of the change, the relay continues it when publishing
+	TraceContext map[string]string `bson:"traceContext,omitempty" json:"-"`
+}
+
+// RateValues are the values of a rate before or after a change.
+type RateValues struct {
+	Id                           any        `bson:"id" json:"id,omitempty"`
+	DirectIndirectFlag           string     `bson:"directIndirectFlag" json:"directIndirectFlag,omitempty"`
+	Multiplier                   float64    `bson:"multiplier" json:"multiplier"`
+	BuyRate                      float64    `bson:"buyRate" json:"buyRate"`
+	SellRate                     float64    `bson:"sellRate" json:"sellRate"`
+	TolerancePercentage          int        `bson:"tolerancePercentage" json:"tolerancePercentage"`
+	EffectiveDate                *time.Time `bson:"effectiveDate" json:"effectiveDate,omitempty"`
+	ExpirationDate               *time.Time `bson:"expirationDate" json:"expirationDate,omitempty"`
+	ContractRequirementThreshold *Threshold `bson:"contractRequirementThreshold" json:"contractRequirementThreshold,omitempty"`
+	DocVersion                   int        `bson:"docVersion" json:"docVersion"`
+	UpdatedDate                  time.Time  `bson:"updatedDate" json:"updatedDate"`
+	UpdatedBy                    string     `bson:"updatedBy" json:"updatedBy,omitempty"`
+}
+
+// NewRateValues returns the values of a rate record, nil for a missing record.
+func NewRateValues(record *ForexData) *RateValues {
+	if record == nil {
+		return nil
+	}
+	return &RateValues{
+		Id:                           record.ID,
+		DirectIndirectFlag:           record.DirectIndirectFlag,
+		Multiplier:                   record.Multiplier,
+		BuyRate:                      record.BuyRate,
+		SellRate:                     record.SellRate,
+		TolerancePercentage:          record.TolerancePercentage,
+		EffectiveDate:                record.EffectiveDate,
+		ExpirationDate:               record.ExpirationDate,
+		ContractRequirementThreshold: record.ContractRequirementThreshold,
+		DocVersion:                   record.DocVersion,
+		UpdatedDate:                  record.UpdatedDate,
+		UpdatedBy:                    record.UpdatedBy,
+	}
+}
+
+func (e RateEvent) GetTenantId() int {
+	return e.TenantID
+}
+
+func (e RateEvent) GetBankId() int {
+	return e.BankID
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// RateLimitBucket is the token bucket of a caller on a route.
+type RateLimitBucket struct {
+	Key       string    `bson:"_id" pg:",pk"`
+	Tokens    float64   `bson:"tokens" pg:",use_zero"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+	// ExpiresAt is when an idle bucket is full again and can be removed
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// RateLimitUsage counts the requests of a tenant on a route for a quota period.
+type RateLimitUsage struct {
+	Key       string    `bson:"_id" pg:",pk"`
+	Count     int64     `bson:"count"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
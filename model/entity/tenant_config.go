@@ -0,0 +1,139 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	RoundingHalfUp   = "half_up"
+	RoundingHalfEven = "half_even"
+	RoundingDown     = "down"
+	RoundingUp       = "up"
+)
+
+// TenantConfig is the onboarding configuration of a tenant and its banks.
+type TenantConfig struct {
+	TenantID      int    `bson:"_id" pg:"tenant_id,pk"`
+	Name          string `bson:"name"`
+	BaseCurrency  string `bson:"baseCurrency"`
+	PivotCurrency string `bson:"pivotCurrency"`
+	DefaultTier   string `bson:"defaultTier"`
+	// AllowedPairs lists the currency pairs the tenant trades as BASE/TARGET, every pair
+	// is allowed when empty
+	AllowedPairs []string        `bson:"allowedPairs" pg:",array"`
+	Rounding     *RoundingPolicy `bson:"rounding"`
+	Banks        []BankConfig    `bson:"banks"`
+	CreatedDate  time.Time       `bson:"createdDate"`
+	CreatedBy    string          `bson:"createdBy"`
+	DocVersion   int             `bson:"docVersion"`
+	UpdatedDate  time.Time       `bson:"updatedDate"`
+	UpdatedBy    string          `bson:"updatedBy"`
+}
+
+// BankConfig overrides the tenant configuration for one of its banks. Empty values keep
+// the value of the tenant.
+type BankConfig struct {
+	BankID       int             `bson:"bankId" json:"bankId"`
+	Name         string          `bson:"name" json:"name"`
+	DefaultTier  string          `bson:"defaultTier" json:"defaultTier,omitempty"`
+	AllowedPairs []string        `bson:"allowedPairs" json:"allowedPairs,omitempty"`
+	Rounding     *RoundingPolicy `bson:"rounding" json:"rounding,omitempty"`
+}
+
+// RoundingPolicy rounds converted amounts to a number of decimals.
+type RoundingPolicy struct {
+	Decimals int    `bson:"decimals" json:"decimals"`
+	Mode     string `bson:"mode" json:"mode"`
+}
+
+func (t TenantConfig) GetTenantId() int {
+	return t.TenantID
+}
+
+// GetBankId returns zero, the configuration of a tenant is not bound to a bank.
+func (t TenantConfig) GetBankId() int {
+	return 0
+}
+
+// Bank returns the configuration of the bank, nil when it has none.
+func (t *TenantConfig) Bank(bankId int) *BankConfig {
+	for i := range t.Banks {
+		if t.Banks[i].BankID == bankId {
+			return &t.Banks[i]
+		}
+	}
+	return nil
+}
+
+// EffectiveSettings merges the overrides of the bank into the tenant configuration.
+func (t *TenantConfig) EffectiveSettings(bankId int) (defaultTier string, allowedPairs []string, rounding *RoundingPolicy) {
+	defaultTier, allowedPairs, rounding = t.DefaultTier, t.AllowedPairs, t.Rounding
+	if bank := t.Bank(bankId); bank != nil {
+		if bank.DefaultTier != "" {
+			defaultTier = bank.DefaultTier
+		}
+		if len(bank.AllowedPairs) > 0 {
+			allowedPairs = bank.AllowedPairs
+		}
+		if bank.Rounding != nil {
+			rounding = bank.Rounding
+		}
+	}
+	return defaultTier, allowedPairs, rounding
+}
+
+// PairAllowed reports whether the pair is in the list, an empty list allows every pair.
+func PairAllowed(allowedPairs []string, baseCurrency string, targetCurrency string) bool {
+	if len(allowedPairs) == 0 {
+		return true
+	}
+	pair := Pair(baseCurrency, targetCurrency)
+	for _, allowed := range allowedPairs {
+		if strings.EqualFold(allowed, pair) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pair formats a currency pair as BASE/TARGET.
+func Pair(baseCurrency string, targetCurrency string) string {
+	return strings.ToUpper(baseCurrency) + "/" + strings.ToUpper(targetCurrency)
+}
+
+// Validate checks the mode and number of decimals of the policy.
+func (r *RoundingPolicy) Validate() error {
+	if r.Decimals < 0 || r.Decimals > 10 {
+		return fmt.Errorf("rounding decimals must be between 0 and 10")
+	}
+	switch r.Mode {
+	case "", RoundingHalfUp, RoundingHalfEven, RoundingDown, RoundingUp:
+		return nil
+	}
+	return fmt.Errorf("unknown rounding mode %q", r.Mode)
+}
+
+// Round rounds the amount by the policy, half up when no mode is set. A nil policy keeps
+// the amount as it is.
+func (r *RoundingPolicy) Round(amount float64) float64 {
+	if r == nil {
+		return amount
+	}
+	scale := math.Pow10(r.Decimals)
+	// drop the binary representation error first, so 1.1 is not rounded up to 1.11
+	scaled := math.Round(amount*scale*1e6) / 1e6
+	switch r.Mode {
+	case RoundingHalfEven:
+		scaled = math.RoundToEven(scaled)
+	case RoundingDown:
+		scaled = math.Floor(scaled)
+	case RoundingUp:
+		scaled = math.Ceil(scaled)
+	default:
+		scaled = math.Round(scaled)
+	}
+	return scaled / scale
+}
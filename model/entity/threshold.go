@@ -0,0 +1,107 @@
+package entity
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Threshold is an amount in a currency above which a conversion requires a deal contract.
+// When Currency is empty the amount is in the base currency of the rate.
+type Threshold struct {
+	Amount   float64 `bson:"amount" json:"amount"`
+	Currency string  `bson:"currency" json:"currency,omitempty"`
+}
+
+// IsSet reports whether the threshold holds a usable amount.
+func (t *Threshold) IsSet() bool {
+	return t != nil && t.Amount > 0
+}
+
+// ParseThreshold reads the legacy string form of a threshold such as "10000", "10000 USD"
+// or "USD 10000". An empty string yields a zero threshold.
+func ParseThreshold(value string) (Threshold, error) {
+	var threshold Threshold
+	for _, field := range strings.Fields(value) {
+		if amount, err := strconv.ParseFloat(field, 64); err == nil {
+			threshold.Amount = amount
+		} else {
+			threshold.Currency = strings.ToUpper(field)
+		}
+	}
+	if strings.TrimSpace(value) != "" && threshold.Amount <= 0 {
+		return threshold, fmt.Errorf("invalid contract requirement threshold %q", value)
+	}
+	return threshold, nil
+}
+
+// UnmarshalJSON accepts both the object form and the legacy string form.
+func (t *Threshold) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		threshold, err := ParseThreshold(value)
+		*t = threshold
+		return err
+	}
+	type plain Threshold
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// UnmarshalBSONValue reads thresholds stored as documents as well as records written
+// before the threshold was typed, which hold a string.
+func (t *Threshold) UnmarshalBSONValue(bsonType bsontype.Type, data []byte) error {
+	switch bsonType {
+	case bsontype.String:
+		var value string
+		if err := bson.UnmarshalValue(bsonType, data, &value); err != nil {
+			return err
+		}
+		threshold, err := ParseThreshold(value)
+		*t = threshold
+		return err
+	case bsontype.Null:
+		*t = Threshold{}
+		return nil
+	}
+	type plain Threshold
+	return bson.UnmarshalValue(bsonType, data, (*plain)(t))
+}
+
+// Scan reads a threshold column, stored as json or, in rows written before the threshold
+// was typed, as its legacy string form.
+func (t *Threshold) Scan(src any) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*t = Threshold{}
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("unsupported contract requirement threshold %T", src)
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && (data[0] == '{' || data[0] == '"') {
+		return t.UnmarshalJSON(data)
+	}
+	threshold, err := ParseThreshold(string(data))
+	*t = threshold
+	return err
+}
+
+// Value writes a threshold column as json.
+func (t Threshold) Value() (driver.Value, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
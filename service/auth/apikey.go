@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// APIKey is an entry of the api keys file. Either the key itself or the hex encoded
+// SHA-256 hash of it can be given, hashes keep the keys out of the file.
+type APIKey struct {
+	Key      string   `json:"key,omitempty"`
+	KeyHash  string   `json:"keyHash,omitempty"`
+	Subject  string   `json:"subject"`
+	TenantId int      `json:"tenantId"`
+	BankIds  []int    `json:"bankIds,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+// APIKeyAuthenticator verifies the key sent in the X-API-Key header.
+type APIKeyAuthenticator struct {
+	principals map[string]Principal
+}
+
+// NewAPIKeyAuthenticator indexes the keys by their hash.
+func NewAPIKeyAuthenticator(keys []APIKey) (*APIKeyAuthenticator, error) {
+	authenticator := &APIKeyAuthenticator{principals: map[string]Principal{}}
+	for _, key := range keys {
+		hash := strings.ToLower(key.KeyHash)
+		if key.Key != "" {
+			hash = hashKey(key.Key)
+		}
+		if hash == "" || key.Subject == "" {
+			return nil, errors.New("api keys need a key or keyHash and a subject")
+		}
+		authenticator.principals[hash] = Principal{
+			Subject:  key.Subject,
+			TenantId: key.TenantId,
+			BankIds:  key.BankIds,
+			Roles:    key.Roles,
+			Method:   MethodAPIKey,
+		}
+	}
+	return authenticator, nil
+}
+
+// LoadAPIKeys reads the api keys from a JSON file.
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("unable to read api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (a *APIKeyAuthenticator) Authenticate(c *fiber.Ctx) (*Principal, error) {
+	key := c.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	principal, ok := a.principals[hashKey(key)]
+	if !ok {
+		return nil, errors.New("unknown api key")
+	}
+	return &principal, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	keySetRefreshInterval = time.Hour
+	// keySetMinRefreshInterval limits how often an unknown key id can trigger a refresh
+	keySetMinRefreshInterval = time.Minute
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// KeySet holds the public keys of a JSON Web Key Set read from a file or an http(s) url.
+// Keys from an url are refreshed periodically and when a token names an unknown key id.
+type KeySet struct {
+	source string
+
+	mutex   sync.RWMutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// LoadKeySet reads the key set from a local file or an http(s) url.
+func LoadKeySet(source string) (*KeySet, error) {
+	keySet := &KeySet{source: source}
+	if err := keySet.refresh(); err != nil {
+		return nil, err
+	}
+	return keySet, nil
+}
+
+// NewStaticKeySet creates a key set from keys held in memory, keyed by key id.
+func NewStaticKeySet(keys map[string]crypto.PublicKey) *KeySet {
+	return &KeySet{keys: keys, fetched: time.Now()}
+}
+
+// Key returns the public key with the given id.
+func (k *KeySet) Key(kid string) (crypto.PublicKey, error) {
+	k.mutex.RLock()
+	key, ok := k.keys[kid]
+	age := time.Since(k.fetched)
+	k.mutex.RUnlock()
+
+	if k.isRemote() && (age > keySetRefreshInterval || (!ok && age > keySetMinRefreshInterval)) {
+		if err := k.refresh(); err != nil {
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		k.mutex.RLock()
+		key, ok = k.keys[kid]
+		k.mutex.RUnlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (k *KeySet) isRemote() bool {
+	return strings.HasPrefix(k.source, "http://") || strings.HasPrefix(k.source, "https://")
+}
+
+func (k *KeySet) refresh() error {
+	var content []byte
+	var err error
+	if k.isRemote() {
+		content, err = fetchKeySet(k.source)
+	} else {
+		content, err = os.ReadFile(k.source)
+	}
+	if err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(content)
+	if err != nil {
+		return err
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys = keys
+	k.fetched = time.Now()
+	return nil
+}
+
+func fetchKeySet(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching key set from %s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ParseJWKS reads the RSA and EC signing keys of a JSON Web Key Set.
+func ParseJWKS(content []byte) (map[string]crypto.PublicKey, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("key set does not contain any signing keys")
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
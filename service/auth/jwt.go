@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTAuthenticator verifies bearer tokens signed with a key of the key set. The tenant,
+// banks and roles of the principal are read from the configured claims.
+type JWTAuthenticator struct {
+	Keys        *KeySet
+	Issuer      string
+	Audience    string
+	TenantClaim string
+	BanksClaim  string
+	RolesClaim  string
+}
+
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+func (a *JWTAuthenticator) Authenticate(c *fiber.Ctx) (*Principal, error) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return nil, ErrNoCredentials
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(signingMethods), jwt.WithExpirationRequired()}
+	if a.Issuer != "" {
+		options = append(options, jwt.WithIssuer(a.Issuer))
+	}
+	if a.Audience != "" {
+		options = append(options, jwt.WithAudience(a.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(strings.TrimSpace(header[7:]), claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.Keys.Key(kid)
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	principal := &Principal{Subject: subject, Method: MethodJWT}
+	if principal.TenantId, err = claimInt(claims[a.TenantClaim]); err != nil {
+		return nil, fmt.Errorf("claim %s: %w", a.TenantClaim, err)
+	}
+	if principal.BankIds, err = claimInts(claims[a.BanksClaim]); err != nil {
+		return nil, fmt.Errorf("claim %s: %w", a.BanksClaim, err)
+	}
+	principal.Roles = claimStrings(claims[a.RolesClaim])
+	return principal, nil
+}
+
+func claimInt(value any) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	}
+	return 0, fmt.Errorf("unexpected value %v", value)
+}
+
+func claimInts(value any) ([]int, error) {
+	values, ok := value.([]any)
+	if !ok {
+		if value == nil {
+			return nil, nil
+		}
+		single, err := claimInt(value)
+		return []int{single}, err
+	}
+	var ints []int
+	for _, item := range values {
+		converted, err := claimInt(item)
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, converted)
+	}
+	return ints, nil
+}
+
+// claimStrings accepts an array of strings or a space separated string, as used by scope.
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var strs []string
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+	return nil
+}
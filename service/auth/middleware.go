@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrNoCredentials is returned by an authenticator when the request carries no credentials
+// of its kind, so the next authenticator can be tried.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator verifies the credentials of a request.
+type Authenticator interface {
+	Authenticate(c *fiber.Ctx) (*Principal, error)
+}
+
+// Middleware authenticates every request with the first authenticator that finds
+// credentials. The principal is stored in the user context, where it is also used to
+// attribute changes. Requests to the public paths pass through unauthenticated.
+func Middleware(authenticators []Authenticator, publicPaths ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, path := range publicPaths {
+			if c.Path() == path {
+				return c.Next()
+			}
+		}
+
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(c)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				common.Log(c.UserContext()).Warnf("Rejected credentials for %s %s. Exception:%v", c.Method(), c.Path(), err)
+				return unauthorized(c, "INVALID_CREDENTIALS", "The supplied credentials are invalid or expired")
+			}
+
+			ctx := WithPrincipal(c.UserContext(), principal)
+			c.SetUserContext(common.WithActor(ctx, principal.Subject))
+			return c.Next()
+		}
+		return unauthorized(c, "UNAUTHENTICATED", "A bearer token or api key is required")
+	}
+}
+
+func unauthorized(c *fiber.Ctx, code string, details string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return c.Status(fiber.StatusUnauthorized).JSON(common.GetSimpleResponse[response.ForexDataResponse](nil, response.Unauthorized, &[]response.Error{
+		{Code: code, Message: "Authentication failed", Details: details},
+	}))
+}
+
+// GetAuthenticators creates the authenticators enabled in the auth section of the
+// configuration. It returns nil when authentication is disabled.
+func GetAuthenticators(fxConfig *config.Config) []Authenticator {
+	var authenticators []Authenticator
+	for _, mode := range strings.Split(fxConfig.Auth.Mode, ",") {
+		switch strings.TrimSpace(mode) {
+		case "", "none":
+		case MethodJWT:
+			source := fxConfig.Auth.JwksUrl
+			if source == "" {
+				source = fxConfig.Auth.JwksFile
+			}
+			keys, err := LoadKeySet(source)
+			if err != nil {
+				log.Fatal("Unable to load the JWT key set:", err)
+			}
+			authenticators = append(authenticators, &JWTAuthenticator{
+				Keys:        keys,
+				Issuer:      fxConfig.Auth.Issuer,
+				Audience:    fxConfig.Auth.Audience,
+				TenantClaim: fxConfig.Auth.TenantClaim,
+				BanksClaim:  fxConfig.Auth.BanksClaim,
+				RolesClaim:  fxConfig.Auth.RolesClaim,
+			})
+		case MethodAPIKey:
+			keys, err := LoadAPIKeys(fxConfig.Auth.ApiKeysFile)
+			if err != nil {
+				log.Fatal("Unable to load api keys:", err)
+			}
+			authenticator, err := NewAPIKeyAuthenticator(keys)
+			if err != nil {
+				log.Fatal("Invalid api keys:", err)
+			}
+			authenticators = append(authenticators, authenticator)
+		default:
+			log.Fatalf("Unsupported auth mode %q", mode)
+		}
+	}
+	return authenticators
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	PermissionReadRates    = "rates:read"
+	PermissionConvert      = "rates:convert"
+	PermissionWriteRates   = "rates:write"
+	PermissionDeleteRates  = "rates:delete"
+	PermissionWritePricing = "pricing:write"
+	PermissionWriteTenants = "tenants:write"
+	// PermissionReplayMessages replays dead lettered stream messages
+	PermissionReplayMessages = "messages:replay"
+	// PermissionManageLogs reads and changes the level of the logs
+	PermissionManageLogs = "logs:manage"
+	// PermissionAll grants every permission
+	PermissionAll = "*"
+)
+
+var permissions = []string{PermissionReadRates, PermissionConvert, PermissionWriteRates,
+	PermissionDeleteRates, PermissionWritePricing, PermissionWriteTenants, PermissionReplayMessages, PermissionManageLogs, PermissionAll}
+
+// Policy grants permissions to roles.
+type Policy struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+// DefaultPolicy is used when no policy file is configured. Viewers read rates, converters
+// also convert and quote, rate editors maintain rates and approvers own deletions and
+// pricing rules. Admins can do everything, which includes onboarding tenants.
+func DefaultPolicy() *Policy {
+	return &Policy{Roles: map[string][]string{
+		RoleViewer:     {PermissionReadRates},
+		RoleConverter:  {PermissionReadRates, PermissionConvert},
+		RoleRateEditor: {PermissionReadRates, PermissionWriteRates},
+		RoleApprover:   {PermissionReadRates, PermissionDeleteRates, PermissionWritePricing},
+		RoleAdmin:      {PermissionAll},
+	}}
+}
+
+// LoadPolicy reads a policy from a json file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err = json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	if err = policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate checks that the policy only grants known permissions.
+func (p *Policy) Validate() error {
+	if len(p.Roles) == 0 {
+		return fmt.Errorf("policy grants no roles")
+	}
+	for role, granted := range p.Roles {
+		for _, permission := range granted {
+			if !isPermission(permission) {
+				return fmt.Errorf("role %s: unknown permission %q", role, permission)
+			}
+		}
+	}
+	return nil
+}
+
+func isPermission(permission string) bool {
+	for _, known := range permissions {
+		if known == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether any role of the principal grants the permission.
+func (p *Policy) Allows(principal *Principal, permission string) bool {
+	if principal == nil {
+		return false
+	}
+	for _, role := range principal.Roles {
+		for _, granted := range p.Roles[role] {
+			if granted == permission || granted == PermissionAll {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Require lets a request through when the principal holds the permission. Requests
+// without a principal pass, they only reach the route when authentication is disabled.
+func (p *Policy) Require(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := GetPrincipal(c)
+		if principal == nil || p.Allows(principal, permission) {
+			return c.Next()
+		}
+		common.Log(c.UserContext()).Warnf("Denied %s %s to %s with roles %s", c.Method(), c.Path(), principal.Subject, strings.Join(principal.Roles, ","))
+		return forbidden(c, "PERMISSION_DENIED", fmt.Sprintf("The %s permission is required", permission))
+	}
+}
+
+// GetPolicy loads the policy file of the configuration, or returns the default policy.
+func GetPolicy(fxConfig *config.Config) *Policy {
+	if fxConfig.Auth.PolicyFile == "" {
+		return DefaultPolicy()
+	}
+	policy, err := LoadPolicy(fxConfig.Auth.PolicyFile)
+	if err != nil {
+		log.Fatal("Unable to load the authorization policy:", err)
+	}
+	return policy
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "apikey"
+
+	RoleViewer     = "viewer"
+	RoleConverter  = "converter"
+	RoleRateEditor = "rate-editor"
+	RoleApprover   = "approver"
+	// RoleAdmin may act on behalf of any tenant
+	RoleAdmin = "admin"
+)
+
+// Principal is the verified identity of a caller.
+type Principal struct {
+	Subject  string
+	TenantId int
+	BankIds  []int
+	Roles    []string
+	// Method is the way the principal was authenticated, jwt or apikey
+	Method string
+}
+
+// HasRole reports whether the principal was granted the role.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	for _, granted := range p.Roles {
+		if granted == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of the context, nil for unauthenticated calls.
+func FromContext(ctx context.Context) *Principal {
+	if ctx == nil {
+		return nil
+	}
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// GetPrincipal returns the principal of the request, nil for unauthenticated calls.
+func GetPrincipal(c *fiber.Ctx) *Principal {
+	return FromContext(c.UserContext())
+}
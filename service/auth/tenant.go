@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"strconv"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TenantHeader names the tenant an admin acts on behalf of.
+const TenantHeader = "X-Tenant-Id"
+
+// TenantScope restricts every request to the tenant and banks of its principal. Admins
+// may name another tenant in the X-Tenant-Id header, admins without a tenant of their own
+// stay unscoped otherwise. Principals without a tenant are rejected.
+func TenantScope() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := GetPrincipal(c)
+		if principal == nil {
+			return c.Next()
+		}
+
+		if onBehalfOf := c.Get(TenantHeader); onBehalfOf != "" {
+			if !principal.HasRole(RoleAdmin) {
+				return forbidden(c, "TENANT_FORBIDDEN", "Only admins can act on behalf of another tenant")
+			}
+			tenantId, err := strconv.Atoi(onBehalfOf)
+			if err != nil || tenantId <= 0 {
+				return forbidden(c, "TENANT_INVALID", TenantHeader+" must be a tenant id")
+			}
+			common.Log(c.UserContext()).Infof("%s is acting on behalf of tenant %d for %s %s", principal.Subject, tenantId, c.Method(), c.Path())
+			c.SetUserContext(common.WithTenantScope(c.UserContext(), common.TenantScope{TenantId: tenantId}))
+			return c.Next()
+		}
+
+		if principal.TenantId == 0 {
+			if principal.HasRole(RoleAdmin) {
+				return c.Next()
+			}
+			return forbidden(c, "TENANT_REQUIRED", "The credentials are not bound to a tenant")
+		}
+		c.SetUserContext(common.WithTenantScope(c.UserContext(), common.TenantScope{
+			TenantId: principal.TenantId,
+			BankIds:  principal.BankIds,
+		}))
+		return c.Next()
+	}
+}
+
+func forbidden(c *fiber.Ctx, code string, details string) error {
+	return c.Status(fiber.StatusForbidden).JSON(common.GetSimpleResponse[response.ForexDataResponse](nil, response.Forbidden, &[]response.Error{
+		{Code: code, Message: "Access denied", Details: details},
+	}))
+}
@@ -2,26 +2,39 @@ package bal
 
 import (
 	"context"
+	"errors"
 	"github.com/PeerIslands/aci-fx-go/model/dto/request"
 	"github.com/PeerIslands/aci-fx-go/model/dto/response"
 	"github.com/PeerIslands/aci-fx-go/model/entity"
 	"github.com/PeerIslands/aci-fx-go/service/common"
 	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/PeerIslands/aci-fx-go/service/pricing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.opentelemetry.io/otel"
 	"math/rand"
-	"os"
 	"time"
 )
 
 type Fx_service struct {
 	DbService dal.DBService[entity.ForexData]
+	// Pricing derives tier rates from a base rate, stored tier rates are used when nil
+	Pricing *pricing.Engine
+	// ContractThresholdMode decides whether conversions above the contract requirement
+	// threshold are flagged or rejected, flagged when empty
+	ContractThresholdMode string
+	QuoteService          dal.QuoteDBService
+	// QuoteTTL is how long an issued quote can be redeemed
+	QuoteTTL time.Duration
+	// TenantService holds the tenant configurations requests are checked against, no
+	// checks are made when nil
+	TenantService dal.TenantDBService
+	// RateEvents is the outbox rate changes are recorded in, no events are recorded when nil
+	RateEvents dal.RateEventDBService
+	// Transactions stores a rate change and its events together, which needs a replica set
+	// on mongo. Changes are stored without a transaction when nil or no events are recorded
+	Transactions dal.Transactor
 }
 
-var dbSpanName = "db-call"
-var tracerName = "OTEL_SERVICE_NAME"
-
 func getForexDtoFromEntity(result entity.ForexData) *response.ForexDataResponse {
 	return &response.ForexDataResponse{
 		Id:                           result.ID,
@@ -39,11 +52,21 @@ func getForexDtoFromEntity(result entity.ForexData) *response.ForexDataResponse
 		ExpirationDate:               result.ExpirationDate,
 		ContractRequirementThreshold: result.ContractRequirementThreshold,
 		DocVersion:                   result.DocVersion,
+		CreatedBy:                    result.CreatedBy,
+		UpdatedBy:                    result.UpdatedBy,
 	}
 }
 
 func (s *Fx_service) CreateForexData(c *context.Context,
 	forexData request.CreateForexDataRequest) response.ResponseWithSimpleData[response.CreateForexDataResponse] {
+	tenantId, err := common.ResolveTenant(*c, forexData.TenantId, forexData.BankId)
+	if err != nil {
+		return common.GetSimpleResponse[response.CreateForexDataResponse](nil, response.Forbidden, tenantForbiddenError(forexData.TenantId, forexData.BankId))
+	}
+	forexData.TenantId = tenantId
+	if e := s.validateRateData(c, forexData); e != nil {
+		return common.GetSimpleResponse[response.CreateForexDataResponse](nil, response.BadRequest, e)
+	}
 	var dbObject = entity.ForexData{
 		ID:                           primitive.NewObjectID(),
 		Tier:                         forexData.Tier,
@@ -60,18 +83,24 @@ func (s *Fx_service) CreateForexData(c *context.Context,
 		BaseCurrency:                 forexData.BaseCurrency,
 		TargetCurrency:               forexData.TargetCurrency,
 		CreatedDate:                  time.Now(),
+		CreatedBy:                    common.ActorFromContext(*c),
 		DocVersion:                   1,
 		UpdatedDate:                  time.Now(),
+		UpdatedBy:                    common.ActorFromContext(*c),
 	}
-	common.Logger.Info("Create a forex record started")
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-	result, err := s.DbService.CreateOne(dbObject)
-	span.End()
+	common.Log(*c).Info("Create a forex record started")
+	var result entity.ForexData
+	err = s.inTransaction(c, func(c *context.Context) error {
+		var err error
+		if result, err = s.DbService.CreateOne(*c, dbObject); err != nil {
+			return err
+		}
+		return s.recordRateChanges(c, created(result))
+	})
 
-	common.Logger.Info("Create a forex record ended")
+	common.Log(*c).Info("Create a forex record ended")
 	if err != nil {
-		common.Logger.Errorf("Error in creating a new Record. Exception:%v", err)
+		common.Log(*c).Errorf("Error in creating a new Record. Exception:%v", err)
 		e := &[]response.Error{
 			{Code: "FAILURE", Message: "Unable to create record", Details: "Unable to create record due to some exception."},
 		}
@@ -85,6 +114,14 @@ func (s *Fx_service) BulkInsertForexData(c *context.Context,
 	forexData []request.CreateForexDataRequest) response.ResponseWithSimpleData[response.ForexDataResponse] {
 	var dbObjects []entity.ForexData
 	for _, item := range forexData {
+		tenantId, err := common.ResolveTenant(*c, item.TenantId, item.BankId)
+		if err != nil {
+			return common.GetSimpleResponse[response.ForexDataResponse](nil, response.Forbidden, tenantForbiddenError(item.TenantId, item.BankId))
+		}
+		item.TenantId = tenantId
+		if e := s.validateRateData(c, item); e != nil {
+			return common.GetSimpleResponse[response.ForexDataResponse](nil, response.BadRequest, e)
+		}
 		dbObjects = append(dbObjects, entity.ForexData{
 			ID:                           primitive.NewObjectID(),
 			Tier:                         item.Tier,
@@ -101,19 +138,26 @@ func (s *Fx_service) BulkInsertForexData(c *context.Context,
 			BaseCurrency:                 item.BaseCurrency,
 			TargetCurrency:               item.TargetCurrency,
 			CreatedDate:                  time.Now(),
+			CreatedBy:                    common.ActorFromContext(*c),
 			DocVersion:                   randomInt(),
 			UpdatedDate:                  time.Now(),
+			UpdatedBy:                    common.ActorFromContext(*c),
 		})
 	}
-	common.Logger.Info("Bulk insert started")
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-
-	_, err := s.DbService.BulkInsert(dbObjects)
-	span.End()
-	common.Logger.Info("Bulk insert ended")
+	common.Log(*c).Info("Bulk insert started")
+	err := s.inTransaction(c, func(c *context.Context) error {
+		if _, err := s.DbService.BulkInsert(*c, dbObjects); err != nil {
+			return err
+		}
+		var changes []rateChange
+		for _, item := range dbObjects {
+			changes = append(changes, created(item))
+		}
+		return s.recordRateChanges(c, changes...)
+	})
+	common.Log(*c).Info("Bulk insert ended")
 	if err != nil {
-		common.Logger.Errorf("Error in creating a new Record. Exception:%v", err)
+		common.Log(*c).Errorf("Error in creating a new Record. Exception:%v", err)
 		e := &[]response.Error{
 			{Code: "FAILURE", Message: "Unable to create record", Details: "Unable to create record due to some exception."},
 		}
@@ -123,17 +167,77 @@ func (s *Fx_service) BulkInsertForexData(c *context.Context,
 	return common.GetSimpleResponse[response.ForexDataResponse](nil, response.Success, nil)
 }
 
+// UpsertForexData replaces the rate identified by tenant, bank, currency pair and tier,
+// creating it when it does not exist yet.
+func (s *Fx_service) UpsertForexData(c *context.Context,
+	forexData request.CreateForexDataRequest) response.ResponseWithSimpleData[response.ForexDataResponse] {
+	tenantId, err := common.ResolveTenant(*c, forexData.TenantId, forexData.BankId)
+	if err != nil {
+		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.Forbidden, tenantForbiddenError(forexData.TenantId, forexData.BankId))
+	}
+	forexData.TenantId = tenantId
+	if e := s.validateRateData(c, forexData); e != nil {
+		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.BadRequest, e)
+	}
+	var dbObject = entity.ForexData{
+		ID:                           primitive.NewObjectID(),
+		Tier:                         forexData.Tier,
+		DirectIndirectFlag:           forexData.DirectIndirectFlag,
+		Multiplier:                   forexData.Multiplier,
+		BuyRate:                      forexData.BuyRate,
+		SellRate:                     forexData.SellRate,
+		TolerancePercentage:          forexData.TolerancePercentage,
+		EffectiveDate:                forexData.EffectiveDate,
+		ExpirationDate:               forexData.ExpirationDate,
+		ContractRequirementThreshold: forexData.ContractRequirementThreshold,
+		TenantID:                     forexData.TenantId,
+		BankID:                       forexData.BankId,
+		BaseCurrency:                 forexData.BaseCurrency,
+		TargetCurrency:               forexData.TargetCurrency,
+		CreatedDate:                  time.Now(),
+		CreatedBy:                    common.ActorFromContext(*c),
+		DocVersion:                   1,
+		UpdatedDate:                  time.Now(),
+		UpdatedBy:                    common.ActorFromContext(*c),
+	}
+	filter := request.FxDataRequest{
+		TenantId:       forexData.TenantId,
+		BankId:         forexData.BankId,
+		BaseCurrency:   forexData.BaseCurrency,
+		TargetCurrency: forexData.TargetCurrency,
+		Tier:           forexData.Tier,
+	}
+	var result entity.ForexData
+	err = s.inTransaction(c, func(c *context.Context) error {
+		before, err := s.getRateBefore(c, filter)
+		if err != nil {
+			return err
+		}
+		if result, err = s.DbService.UpsertOne(*c, dbObject, filter); err != nil {
+			return err
+		}
+		return s.recordRateChanges(c, upserted(before, result))
+	})
+
+	if err != nil {
+		common.Log(*c).Errorf("Error in upserting forex rate %s/%s for tenant %d. Exception:%v",
+			forexData.BaseCurrency, forexData.TargetCurrency, forexData.TenantId, err)
+		e := &[]response.Error{
+			{Code: "FAILURE", Message: "Unable to save record", Details: "Unable to save record due to some exception."},
+		}
+		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.InternalError, e)
+	}
+
+	return common.GetSimpleResponse[response.ForexDataResponse](getForexDtoFromEntity(result), response.Success, nil)
+}
+
 func (s *Fx_service) GetForexRateById(c *context.Context,
 	id string) response.ResponseWithSimpleData[response.ForexDataResponse] {
 	objectId, _ := primitive.ObjectIDFromHex(id)
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-
-	result, err := s.DbService.GetOne(bson.D{{"_id", objectId}})
-	span.End()
+	result, err := s.DbService.GetOne(*c, bson.D{{"_id", objectId}})
 
 	if err != nil {
-		common.Logger.Errorf("Error in retriving forex rate by id. Exception:%v", err)
+		common.Log(*c).Errorf("Error in retriving forex rate by id. Exception:%v", err)
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
 		}
@@ -145,16 +249,22 @@ func (s *Fx_service) GetForexRateById(c *context.Context,
 
 func (s *Fx_service) DeleteForexRateById(c *context.Context,
 	id string) response.ResponseWithSimpleData[response.ForexDataResponse] {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-
-	_, err := s.DbService.DeleteOne(id)
-	span.End()
+	err := s.inTransaction(c, func(c *context.Context) error {
+		result, err := s.DbService.DeleteOne(*c, id)
+		if err != nil {
+			return err
+		}
+		return s.recordRateChanges(c, deleted(result))
+	})
+	if errors.Is(err, errRateEvents) {
+		common.Log(*c).Errorf("Error in Deleting forex rate by id. Exception:%v", err)
+		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.InternalError, rateEventsFailure())
+	}
 	if err != nil {
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record Deleted", Details: "No record Deleted"},
 		}
-		common.Logger.Errorf("Error in Deleting forex rate by id. Exception:%v", err)
+		common.Log(*c).Errorf("Error in Deleting forex rate by id. Exception:%v", err)
 		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.NotFound, e)
 	}
 
@@ -162,23 +272,25 @@ func (s *Fx_service) DeleteForexRateById(c *context.Context,
 }
 
 func (s *Fx_service) GetForexRateByFilter(c *context.Context,
-	tenantId int, bankId int, baseCurrency string, targetCurrency string) response.ResponseWithArrayData[response.ForexDataResponse] {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-
-	result, err := s.DbService.Get(bson.D{
-		{"tenantId", tenantId},
-		{"bankId", bankId},
-		{"baseCurrency", baseCurrency},
-		{"targetCurrency", targetCurrency},
+	tenantId int, bankId int, baseCurrency string, targetCurrency string, tier string) response.ResponseWithArrayData[response.ForexDataResponse] {
+	scopedTenantId, err := common.ResolveTenant(*c, tenantId, bankId)
+	if err != nil {
+		return common.GetArrayResponse[response.ForexDataResponse](nil, response.Forbidden, tenantForbiddenError(tenantId, bankId))
+	}
+	tenantId = scopedTenantId
+	result, err := s.DbService.Get(*c, request.FxDataRequest{
+		TenantId:       tenantId,
+		BankId:         bankId,
+		BaseCurrency:   baseCurrency,
+		TargetCurrency: targetCurrency,
+		Tier:           tier,
 	})
-	span.End()
 
 	if err != nil {
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
 		}
-		common.Logger.Errorf("Error in retriving forex rate by filters. Exception:%v", err)
+		common.Log(*c).Errorf("Error in retriving forex rate by filters. Exception:%v", err)
 		return common.GetArrayResponse[response.ForexDataResponse](nil, response.NotFound, e)
 	}
 
@@ -194,85 +306,175 @@ func (s *Fx_service) GetForexRateByFilter(c *context.Context,
 
 func (s *Fx_service) UpdateForexRateById(c *context.Context,
 	id string, body request.UpdateForexDataRequest) response.ResponseWithSimpleData[response.ForexDataResponse] {
+	if e := validateRateUpdate(body); e != nil {
+		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.BadRequest, e)
+	}
 
 	objectId, _ := primitive.ObjectIDFromHex(id)
-
-	updateDocument := bson.D{
-		{"$set", bson.D{
-			{"sellRate", body.SellRate},
-			{"buyRate", body.BuyRate},
-			{"expirationDate", body.ExpirationDate},
-			{"effectiveDate", body.EffectiveDate},
-			{"tolerancePercentage", body.TolerancePercentage},
-			{"multiplier", body.Multiplier},
-			{"directIndirectFlag", body.DirectIndirectFlag},
-			{"contractRequirementThreshold", body.ContractRequirementThreshold},
-			{"updatedDate", time.Now()},
-		}},
-	}
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-
-	result, err := s.DbService.UpdateOne(updateDocument, bson.D{{"_id", objectId}})
-	span.End()
-
+	var record entity.ForexData
+	err := s.inTransaction(c, func(c *context.Context) error {
+		before, err := s.DbService.GetOne(*c, bson.D{{"_id", objectId}})
+		if err != nil {
+			return err
+		}
+		result, err := s.DbService.UpdateOne(*c, applyRateUpdate(c, before, body), bson.D{{"_id", objectId}})
+		if err != nil {
+			return err
+		}
+		record = result.(entity.ForexData)
+		return s.recordRateChanges(c, updated(before, record))
+	})
+	if errors.Is(err, errRateEvents) {
+		common.Log(*c).Errorf("Error in updating forex rate by id. Exception:%v", err)
+		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.InternalError, rateEventsFailure())
+	}
 	if err != nil {
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record Updated", Details: "No record Updated"},
 		}
-		common.Logger.Errorf("Error in updating forex rate by id. Exception:%v", err)
+		common.Log(*c).Errorf("Error in updating forex rate by id. Exception:%v", err)
 		return common.GetSimpleResponse[response.ForexDataResponse](nil, response.NotFound, e)
 	}
 
-	return common.GetSimpleResponse[response.ForexDataResponse](getForexDtoFromEntity(result.(entity.ForexData)), response.Success, nil)
+	return common.GetSimpleResponse[response.ForexDataResponse](getForexDtoFromEntity(record), response.Success, nil)
 }
 
 func (s *Fx_service) GetConvertedRate(c *context.Context,
-	tenantId int, bankId int, amount float64, baseCurrency string, targetCurrency string, tier string) response.ResponseWithSimpleData[response.ConversionResponse] {
+	tenantId int, bankId int, amount float64, baseCurrency string, targetCurrency string, tier string) (result response.ResponseWithSimpleData[response.ConversionResponse]) {
+	defer func() { recordConversion(*c, tenantId, baseCurrency, targetCurrency, tier, amount, result) }()
+	scopedTenantId, err := common.ResolveTenant(*c, tenantId, bankId)
+	if err != nil {
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.Forbidden, tenantForbiddenError(tenantId, bankId))
+	}
+	tenantId = scopedTenantId
 	ConvertRequest := request.FxDataRequest{
+		Amount:         amount,
 		TenantId:       tenantId,
 		BankId:         bankId,
 		BaseCurrency:   baseCurrency,
 		TargetCurrency: targetCurrency,
 		Tier:           tier,
 	}
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
+	tenant, e := s.applyTenantConfig(c, &ConvertRequest)
+	if e != nil {
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e)
+	}
 
-	result, err := s.DbService.GetOne(ConvertRequest)
-	span.End()
+	rate, err := s.getConversionRate(c, ConvertRequest, tenant)
 	if err != nil {
+		recordRateMiss(*c, tenantId, ConvertRequest.BaseCurrency, targetCurrency, ConvertRequest.Tier, err)
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
 		}
-		common.Logger.Errorf("Error in retriving and converting forex rate. Exception:%v", err)
+		common.Log(*c).Errorf("Error in retriving and converting forex rate. Exception:%v", err)
 		return common.GetSimpleResponse[response.ConversionResponse](nil, response.NotFound, e)
 	}
 
 	resp := response.ConversionResponse{
 		Amount:          amount,
-		ConvertedAmount: amount * result.BuyRate,
-		BaseCurrency:    baseCurrency,
+		ConvertedAmount: roundAmount(tenant, bankId, amount*rate.buyRate),
+		BaseCurrency:    ConvertRequest.BaseCurrency,
 		TargetCurrency:  targetCurrency,
+		Tier:            rate.record.Tier,
 		InitiatedOn:     int64(time.Nanosecond),
-		Rate:            result.BuyRate,
+		Rate:            rate.buyRate,
+		AppliedRule:     rate.appliedRule,
+	}
+
+	threshold := rate.record.ContractRequirementThreshold
+	if exceedsThreshold(threshold, resp.Amount, resp.ConvertedAmount, targetCurrency) {
+		if s.ContractThresholdMode == ContractThresholdReject {
+			return common.GetSimpleResponse[response.ConversionResponse](nil, response.ContractRequired, contractRequiredError(threshold, ConvertRequest.BaseCurrency))
+		}
+		resp.ContractRequired = true
+		resp.ContractRequirementThreshold = threshold
 	}
 	return common.GetSimpleResponse[response.ConversionResponse](&resp, response.Success, nil)
 }
 
+// conversionRate is the customer rate of a conversion along with the record it comes from,
+// which is the base tier record when the rate was derived by a pricing rule.
+type conversionRate struct {
+	record      entity.ForexData
+	buyRate     float64
+	sellRate    float64
+	appliedRule *response.AppliedRule
+}
+
+// getRate resolves the tier of the conversion when it is not given and returns the priced
+// rate when a pricing rule applies, or the rate stored for the tier otherwise.
+func (s *Fx_service) getRate(c *context.Context, convertRequest request.FxDataRequest) (conversionRate, error) {
+	if convertRequest.Tier == "" || convertRequest.Tier == AutoTier {
+		resolvedTier, err := s.resolveTier(c, convertRequest)
+		if err != nil {
+			return conversionRate{}, err
+		}
+		convertRequest.Tier = resolvedTier
+	}
+
+	if price, base, ok := s.getPrice(c, convertRequest); ok {
+		base.Tier = convertRequest.Tier
+		return conversionRate{record: base, buyRate: price.BuyRate, sellRate: price.SellRate, appliedRule: getAppliedRule(price)}, nil
+	}
+
+	result, err := s.DbService.GetOne(*c, convertRequest)
+	if err != nil {
+		return conversionRate{}, err
+	}
+	return conversionRate{record: result, buyRate: result.BuyRate, sellRate: result.SellRate}, nil
+}
+
+// getPrice derives the customer rate from the base tier record when a pricing rule matches
+// the conversion. It reports false when no rule applies or the base rate is missing, so the
+// caller falls back to the rate stored for the tier.
+func (s *Fx_service) getPrice(c *context.Context, convertRequest request.FxDataRequest) (pricing.Price, entity.ForexData, bool) {
+	var base entity.ForexData
+	if s.Pricing == nil {
+		return pricing.Price{}, base, false
+	}
+	rule, ok := s.Pricing.Match(convertRequest.TenantId, convertRequest.BankId,
+		convertRequest.BaseCurrency, convertRequest.TargetCurrency, convertRequest.Tier)
+	if !ok {
+		return pricing.Price{}, base, false
+	}
+
+	baseRequest := convertRequest
+	baseRequest.Tier = s.Pricing.BaseTier
+	base, err := s.DbService.GetOne(*c, baseRequest)
+	if err != nil {
+		common.Log(*c).Warnf("Pricing rule %s matched but no %s rate exists for %s/%s. Exception:%v",
+			rule.Id, s.Pricing.BaseTier, convertRequest.BaseCurrency, convertRequest.TargetCurrency, err)
+		return pricing.Price{}, base, false
+	}
+
+	midRate := (base.BuyRate + base.SellRate) / 2
+	return s.Pricing.Apply(rule, midRate, convertRequest.Amount), base, true
+}
+
+func getAppliedRule(price pricing.Price) *response.AppliedRule {
+	appliedRule := &response.AppliedRule{
+		RuleId:    price.RuleId,
+		MidRate:   price.MidRate,
+		SpreadBps: price.SpreadBps,
+	}
+	if price.Band != nil {
+		appliedRule.BandMinAmount = &price.Band.MinAmount
+		if price.Band.MaxAmount != 0 {
+			appliedRule.BandMaxAmount = &price.Band.MaxAmount
+		}
+	}
+	return appliedRule
+}
+
 func (s *Fx_service) GetConvertedRateById(c *context.Context,
 	id int) response.ResponseWithSimpleData[response.ConversionResponse] {
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-
-	result, err := s.DbService.GetOneById(id)
-	span.End()
+	result, err := s.DbService.GetOneById(*c, id)
 
 	if err != nil {
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
 		}
-		common.Logger.Errorf("Error in retriving and converting forex rate. Exception:%v", err)
+		common.Log(*c).Errorf("Error in retriving and converting forex rate. Exception:%v", err)
 		return common.GetSimpleResponse[response.ConversionResponse](nil, response.NotFound, e)
 	}
 
@@ -285,8 +487,18 @@ func (s *Fx_service) GetConvertedRateById(c *context.Context,
 	return common.GetSimpleResponse[response.ConversionResponse](&resp, response.Success, nil)
 }
 
-func (s *Fx_service) UpdateForexRate(c *context.Context,
-	tenantId int, bankId int, baseCurrency string, targetCurrency string, tier string) response.ResponseWithSimpleData[response.ConversionResponse] {
+// UpdateForexRate writes the rates of the body to the rate identified by tenant, bank,
+// currency pair and tier.
+func (s *Fx_service) UpdateForexRate(c *context.Context, tenantId int, bankId int, baseCurrency string, targetCurrency string, tier string,
+	body request.UpdateForexDataRequest) response.ResponseWithSimpleData[response.ConversionResponse] {
+	scopedTenantId, err := common.ResolveTenant(*c, tenantId, bankId)
+	if err != nil {
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.Forbidden, tenantForbiddenError(tenantId, bankId))
+	}
+	if e := validateRateUpdate(body); e != nil {
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e)
+	}
+	tenantId = scopedTenantId
 	updateRequest := request.FxDataRequest{
 		TenantId:       tenantId,
 		BankId:         bankId,
@@ -294,17 +506,27 @@ func (s *Fx_service) UpdateForexRate(c *context.Context,
 		TargetCurrency: targetCurrency,
 		Tier:           tier,
 	}
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-
-	_, err := s.DbService.UpdateOne(updateRequest, updateRequest)
-	span.End()
-
+	err = s.inTransaction(c, func(c *context.Context) error {
+		before, err := s.DbService.GetOne(*c, updateRequest)
+		if err != nil {
+			return err
+		}
+		result, err := s.DbService.UpdateOne(*c, applyRateUpdate(c, before, body), updateRequest)
+		if err != nil {
+			return err
+		}
+		record, _ := result.(entity.ForexData)
+		return s.recordRateChanges(c, updated(before, record))
+	})
+	if errors.Is(err, errRateEvents) {
+		common.Log(*c).Errorf("Error in updating forex rate. Exception:%v", err)
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.InternalError, rateEventsFailure())
+	}
 	if err != nil {
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
 		}
-		common.Logger.Errorf("Error in retriving and converting forex rate. Exception:%v", err)
+		common.Log(*c).Errorf("Error in retriving and converting forex rate. Exception:%v", err)
 		return common.GetSimpleResponse[response.ConversionResponse](nil, response.NotFound, e)
 	}
 
@@ -312,19 +534,34 @@ func (s *Fx_service) UpdateForexRate(c *context.Context,
 	return common.GetSimpleResponse[response.ConversionResponse](nil, response.Success, nil)
 }
 
+// UpdateForexById writes the rates of the body to the rate found by GetOneById.
 func (s *Fx_service) UpdateForexById(c *context.Context,
-	id int) response.ResponseWithSimpleData[response.ConversionResponse] {
-
-	tracer := otel.Tracer(os.Getenv(tracerName))
-	_, span := tracer.Start(*c, dbSpanName)
-	_, err := s.DbService.UpdateOneById(id)
-	span.End()
+	id int, body request.UpdateForexDataRequest) response.ResponseWithSimpleData[response.ConversionResponse] {
+	if e := validateRateUpdate(body); e != nil {
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e)
+	}
 
+	err := s.inTransaction(c, func(c *context.Context) error {
+		before, err := s.DbService.GetOneById(*c, id)
+		if err != nil {
+			return err
+		}
+		result, err := s.DbService.UpdateOneById(*c, applyRateUpdate(c, before, body), id)
+		if err != nil {
+			return err
+		}
+		record, _ := result.(entity.ForexData)
+		return s.recordRateChanges(c, updated(before, record))
+	})
+	if errors.Is(err, errRateEvents) {
+		common.Log(*c).Errorf("Error in updating forex rate by id. Exception:%v", err)
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.InternalError, rateEventsFailure())
+	}
 	if err != nil {
 		e := &[]response.Error{
 			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
 		}
-		common.Logger.Errorf("Error in retriving and converting forex rate. Exception:%v", err)
+		common.Log(*c).Errorf("Error in retriving and converting forex rate. Exception:%v", err)
 		return common.GetSimpleResponse[response.ConversionResponse](nil, response.NotFound, e)
 	}
 
@@ -332,6 +569,43 @@ func (s *Fx_service) UpdateForexById(c *context.Context,
 	return common.GetSimpleResponse[response.ConversionResponse](nil, response.Success, nil)
 }
 
+// applyRateUpdate returns the stored record with the changes of an update, the optional
+// fields that are not given keep their stored value.
+func applyRateUpdate(c *context.Context, record entity.ForexData, body request.UpdateForexDataRequest) entity.ForexData {
+	record.BuyRate = body.BuyRate
+	record.SellRate = body.SellRate
+	if body.DirectIndirectFlag != "" {
+		record.DirectIndirectFlag = body.DirectIndirectFlag
+	}
+	if body.Multiplier != 0 {
+		record.Multiplier = float64(body.Multiplier)
+	}
+	if body.TolerancePercentage != 0 {
+		record.TolerancePercentage = int(body.TolerancePercentage)
+	}
+	if body.EffectiveDate != nil {
+		record.EffectiveDate = body.EffectiveDate
+	}
+	if body.ExpirationDate != nil {
+		record.ExpirationDate = body.ExpirationDate
+	}
+	if body.ContractRequirementThreshold != nil {
+		record.ContractRequirementThreshold = body.ContractRequirementThreshold
+	}
+	record.UpdatedDate = time.Now()
+	record.UpdatedBy = common.ActorFromContext(*c)
+	return record
+}
+
+func validateRateUpdate(body request.UpdateForexDataRequest) *[]response.Error {
+	if body.BuyRate <= 0 || body.SellRate <= 0 {
+		return &[]response.Error{
+			{Code: "INVALID_INPUT", Message: "Invalid rate", Details: "buyRate and sellRate must be greater than zero"},
+		}
+	}
+	return nil
+}
+
 func randomInt() int {
 	// Define the range for the random integer (1 million to 2 million)
 	minVal := 1000000
@@ -0,0 +1,67 @@
+package bal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/go-pg/pg/v10"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var meter = otel.Meter("github.com/PeerIslands/aci-fx-go/service/bal")
+
+var conversions, _ = meter.Int64Counter("fx.conversions",
+	metric.WithDescription("Conversions requested by tenant, currency pair, tier and status"))
+
+var conversionAmount, _ = meter.Float64Histogram("fx.conversion.amount",
+	metric.WithDescription("Amount of the successful conversions in the base currency"),
+	metric.WithExplicitBucketBoundaries(10, 100, 1000, 10000, 100000, 1000000, 10000000))
+
+var rateMisses, _ = meter.Int64Counter("fx.rate.lookup.misses",
+	metric.WithDescription("Conversions and quotes for which no rate could be found"))
+
+// recordConversion counts a conversion, with the base currency and tier it was resolved to
+// when it succeeded.
+func recordConversion(ctx context.Context, tenantId int, baseCurrency string, targetCurrency string, tier string,
+	amount float64, result response.ResponseWithSimpleData[response.ConversionResponse]) {
+	if result.Data != nil {
+		baseCurrency, tier = result.Data.BaseCurrency, result.Data.Tier
+	}
+	attrs := []attribute.KeyValue{
+		tenantAttribute(tenantId),
+		pairAttribute(baseCurrency, targetCurrency),
+		attribute.String("tier", tier),
+	}
+	conversions.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("status", string(result.Status)))...))
+	if result.Status == response.Success {
+		conversionAmount.Record(ctx, amount, metric.WithAttributes(attrs...))
+	}
+}
+
+func recordRateMiss(ctx context.Context, tenantId int, baseCurrency string, targetCurrency string, tier string, err error) {
+	reason := "error"
+	if errors.Is(err, dal.ErrNoRecord) || errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, pg.ErrNoRows) {
+		reason = "not_found"
+	}
+	rateMisses.Add(ctx, 1, metric.WithAttributes(
+		tenantAttribute(tenantId),
+		pairAttribute(baseCurrency, targetCurrency),
+		attribute.String("tier", tier),
+		attribute.String("reason", reason),
+	))
+}
+
+func tenantAttribute(tenantId int) attribute.KeyValue {
+	return attribute.String("tenant", strconv.Itoa(tenantId))
+}
+
+func pairAttribute(baseCurrency string, targetCurrency string) attribute.KeyValue {
+	return attribute.String("pair", strings.ToUpper(baseCurrency)+"/"+strings.ToUpper(targetCurrency))
+}
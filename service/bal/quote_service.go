@@ -0,0 +1,171 @@
+package bal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getQuoteDtoFromEntity(quote entity.Quote) *response.QuoteResponse {
+	return &response.QuoteResponse{
+		QuoteId:         quote.ID,
+		TenantId:        quote.TenantID,
+		BankId:          quote.BankID,
+		BaseCurrency:    quote.BaseCurrency,
+		TargetCurrency:  quote.TargetCurrency,
+		Tier:            quote.Tier,
+		Side:            quote.Side,
+		Amount:          quote.Amount,
+		Rate:            quote.Rate,
+		ConvertedAmount: quote.ConvertedAmount,
+		Status:          quote.Status,
+		ExpiresAt:       quote.ExpiresAt,
+		RedeemedAt:      quote.RedeemedAt,
+	}
+}
+
+// IssueQuote locks the current rate of a conversion for the quote time to live.
+func (s *Fx_service) IssueQuote(c *context.Context,
+	quoteRequest request.QuoteRequest) response.ResponseWithSimpleData[response.QuoteResponse] {
+	side := strings.ToUpper(quoteRequest.Side)
+	if side != entity.QuoteSideBuy && side != entity.QuoteSideSell {
+		e := &[]response.Error{
+			{Code: "INVALID_INPUT", Message: "side is invalid", Details: "side must be BUY or SELL"},
+		}
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.BadRequest, e)
+	}
+	if quoteRequest.Amount <= 0 {
+		e := &[]response.Error{
+			{Code: "INVALID_INPUT", Message: "amount is invalid", Details: "amount must be greater than zero"},
+		}
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.BadRequest, e)
+	}
+
+	tenantId, err := common.ResolveTenant(*c, quoteRequest.TenantId, quoteRequest.BankId)
+	if err != nil {
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.Forbidden, tenantForbiddenError(quoteRequest.TenantId, quoteRequest.BankId))
+	}
+	quoteRequest.TenantId = tenantId
+
+	convertRequest := request.FxDataRequest{
+		Amount:         quoteRequest.Amount,
+		TenantId:       quoteRequest.TenantId,
+		BankId:         quoteRequest.BankId,
+		BaseCurrency:   quoteRequest.BaseCurrency,
+		TargetCurrency: quoteRequest.TargetCurrency,
+		Tier:           quoteRequest.Tier,
+	}
+	tenant, e := s.applyTenantConfig(c, &convertRequest)
+	if e != nil {
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.BadRequest, e)
+	}
+	quoteRequest.BaseCurrency = convertRequest.BaseCurrency
+
+	rate, err := s.getConversionRate(c, convertRequest, tenant)
+	if err != nil {
+		recordRateMiss(*c, convertRequest.TenantId, convertRequest.BaseCurrency, convertRequest.TargetCurrency, convertRequest.Tier, err)
+		e := &[]response.Error{
+			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
+		}
+		common.Log(*c).Errorf("Error in retriving the rate of a quote. Exception:%v", err)
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.NotFound, e)
+	}
+
+	lockedRate := rate.buyRate
+	if side == entity.QuoteSideSell {
+		lockedRate = rate.sellRate
+	}
+	convertedAmount := roundAmount(tenant, quoteRequest.BankId, quoteRequest.Amount*lockedRate)
+	threshold := rate.record.ContractRequirementThreshold
+	contractRequired := exceedsThreshold(threshold, quoteRequest.Amount, convertedAmount, quoteRequest.TargetCurrency)
+	if contractRequired && s.ContractThresholdMode == ContractThresholdReject {
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.ContractRequired, contractRequiredError(threshold, quoteRequest.BaseCurrency))
+	}
+
+	now := time.Now()
+	quote := entity.Quote{
+		ID:              primitive.NewObjectID().Hex(),
+		TenantID:        quoteRequest.TenantId,
+		BankID:          quoteRequest.BankId,
+		BaseCurrency:    quoteRequest.BaseCurrency,
+		TargetCurrency:  quoteRequest.TargetCurrency,
+		Tier:            rate.record.Tier,
+		Side:            side,
+		Amount:          quoteRequest.Amount,
+		Rate:            lockedRate,
+		ConvertedAmount: convertedAmount,
+		Status:          entity.QuoteStatusIssued,
+		ExpiresAt:       now.Add(s.QuoteTTL),
+		CreatedDate:     now,
+		CreatedBy:       common.ActorFromContext(*c),
+	}
+
+	quote, err = s.QuoteService.CreateQuote(*c, quote)
+	if err != nil {
+		common.Log(*c).Errorf("Error in creating a quote. Exception:%v", err)
+		e := &[]response.Error{
+			{Code: "FAILURE", Message: "Unable to create quote", Details: "Unable to create quote due to some exception."},
+		}
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.InternalError, e)
+	}
+
+	resp := getQuoteDtoFromEntity(quote)
+	resp.ContractRequired = contractRequired
+	resp.AppliedRule = rate.appliedRule
+	return common.GetSimpleResponse[response.QuoteResponse](resp, response.Success, nil)
+}
+
+// GetQuote returns a quote by its id.
+func (s *Fx_service) GetQuote(c *context.Context, id string) response.ResponseWithSimpleData[response.QuoteResponse] {
+	quote, err := s.QuoteService.GetQuote(*c, id)
+	if err != nil {
+		common.Log(*c).Errorf("Error in retriving quote %s. Exception:%v", id, err)
+		e := &[]response.Error{
+			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
+		}
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.NotFound, e)
+	}
+	return common.GetSimpleResponse[response.QuoteResponse](getQuoteDtoFromEntity(quote), response.Success, nil)
+}
+
+// RedeemQuote executes a quote at its locked rate. A quote can be redeemed once and only
+// before it expires.
+func (s *Fx_service) RedeemQuote(c *context.Context, id string) response.ResponseWithSimpleData[response.QuoteResponse] {
+	now := time.Now()
+	quote, err := s.QuoteService.RedeemQuote(*c, id, common.ActorFromContext(*c), now)
+	if err == nil {
+		return common.GetSimpleResponse[response.QuoteResponse](getQuoteDtoFromEntity(quote), response.Success, nil)
+	}
+	if !errors.Is(err, dal.ErrNoRecord) {
+		common.Log(*c).Errorf("Error in redeeming quote %s. Exception:%v", id, err)
+		e := &[]response.Error{
+			{Code: "FAILURE", Message: "Unable to redeem quote", Details: "Unable to redeem quote due to some exception."},
+		}
+		return common.GetSimpleResponse[response.QuoteResponse](nil, response.InternalError, e)
+	}
+
+	// find out why the quote could not be redeemed
+	existing := s.GetQuote(c, id)
+	if existing.Status != response.Success {
+		return existing
+	}
+	e := &[]response.Error{
+		{Code: "QUOTE_EXPIRED", Message: "Quote has expired",
+			Details: fmt.Sprintf("The quote expired at %s", existing.Data.ExpiresAt.Format(time.RFC3339))},
+	}
+	if existing.Data.Status == entity.QuoteStatusRedeemed {
+		e = &[]response.Error{
+			{Code: "QUOTE_ALREADY_REDEEMED", Message: "Quote has already been redeemed", Details: "A quote can only be redeemed once"},
+		}
+	}
+	return common.GetSimpleResponse[response.QuoteResponse](nil, response.Conflict, e)
+}
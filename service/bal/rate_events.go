@@ -0,0 +1,130 @@
+package bal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// errRateEvents is returned when the events of a rate change cannot be stored, the change
+// is then rolled back.
+var errRateEvents = errors.New("rate events could not be recorded")
+
+func rateEventsFailure() *[]response.Error {
+	return &[]response.Error{
+		{Code: "FAILURE", Message: "Unable to save record", Details: "Unable to record the change of the rate."},
+	}
+}
+
+// rateChange is a change of a rate record, before is nil for a created record and after
+// is nil for a deleted one.
+type rateChange struct {
+	eventType string
+	before    *entity.ForexData
+	after     *entity.ForexData
+}
+
+func created(record entity.ForexData) rateChange {
+	return rateChange{eventType: entity.RateEventCreated, after: &record}
+}
+
+func updated(before entity.ForexData, after entity.ForexData) rateChange {
+	return rateChange{eventType: entity.RateEventUpdated, before: &before, after: &after}
+}
+
+// upserted is the change of an upsert, which creates the record when before is nil.
+func upserted(before *entity.ForexData, after entity.ForexData) rateChange {
+	if before == nil {
+		return created(after)
+	}
+	return updated(*before, after)
+}
+
+func deleted(record entity.ForexData) rateChange {
+	return rateChange{eventType: entity.RateEventDeleted, before: &record}
+}
+
+// recordRateChanges adds the events of rate changes to the outbox, from which the relay
+// publishes them. It is called in the transaction of the changes, a failure fails with
+// errRateEvents so the changes are rolled back.
+func (s *Fx_service) recordRateChanges(c *context.Context, changes ...rateChange) error {
+	if s.RateEvents == nil || len(changes) == 0 {
+		return nil
+	}
+	now := time.Now()
+	actor := common.ActorFromContext(*c)
+	var traceContext propagation.MapCarrier
+	if *c != nil {
+		traceContext = propagation.MapCarrier{}
+		otel.GetTextMapPropagator().Inject(*c, traceContext)
+	}
+	events := make([]entity.RateEvent, 0, len(changes))
+	for _, change := range changes {
+		record := change.after
+		if record == nil {
+			record = change.before
+		}
+		events = append(events, entity.RateEvent{
+			ID:             primitive.NewObjectID().Hex(),
+			Type:           change.eventType,
+			Version:        entity.RateEventVersion,
+			TenantID:       record.TenantID,
+			BankID:         record.BankID,
+			BaseCurrency:   record.BaseCurrency,
+			TargetCurrency: record.TargetCurrency,
+			Tier:           record.Tier,
+			DocVersion:     record.DocVersion,
+			Before:         entity.NewRateValues(change.before),
+			After:          entity.NewRateValues(change.after),
+			Actor:          actor,
+			OccurredAt:     now,
+			TraceContext:   traceContext,
+		})
+	}
+
+	if err := s.RateEvents.AddRateEvents(*c, events); err != nil {
+		return fmt.Errorf("%w: %v", errRateEvents, err)
+	}
+	return nil
+}
+
+// getRateBefore returns a rate as it is before a change, nil when it does not exist or
+// no events are recorded.
+func (s *Fx_service) getRateBefore(c *context.Context, filter request.FxDataRequest) (*entity.ForexData, error) {
+	if s.RateEvents == nil {
+		return nil, nil
+	}
+	record, err := s.DbService.GetOne(*c, filter)
+	if errors.Is(err, dal.ErrNoRecord) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// inTransaction runs fn with the context of a transaction, so a rate change and its events
+// are stored together or not at all.
+func (s *Fx_service) inTransaction(c *context.Context, fn func(c *context.Context) error) error {
+	if s.Transactions == nil || s.RateEvents == nil {
+		return fn(c)
+	}
+	ctx := *c
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return s.Transactions.RunInTransaction(ctx, func(txCtx context.Context) error {
+		return fn(&txCtx)
+	})
+}
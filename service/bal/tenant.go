@@ -0,0 +1,15 @@
+package bal
+
+import (
+	"fmt"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+)
+
+// tenantForbiddenError describes a request for a tenant or bank outside the scope of the caller.
+func tenantForbiddenError(tenantId int, bankId int) *[]response.Error {
+	return &[]response.Error{
+		{Code: "TENANT_FORBIDDEN", Message: "Access denied",
+			Details: fmt.Sprintf("Tenant %d bank %d is outside the scope of the caller", tenantId, bankId)},
+	}
+}
@@ -0,0 +1,388 @@
+package bal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func getTenantConfigDtoFromEntity(tenant entity.TenantConfig) *response.TenantConfigResponse {
+	return &response.TenantConfigResponse{
+		TenantId:      tenant.TenantID,
+		Name:          tenant.Name,
+		BaseCurrency:  tenant.BaseCurrency,
+		PivotCurrency: tenant.PivotCurrency,
+		DefaultTier:   tenant.DefaultTier,
+		AllowedPairs:  tenant.AllowedPairs,
+		Rounding:      tenant.Rounding,
+		Banks:         tenant.Banks,
+		DocVersion:    tenant.DocVersion,
+		CreatedBy:     tenant.CreatedBy,
+		UpdatedBy:     tenant.UpdatedBy,
+	}
+}
+
+// CreateTenantConfig onboards a tenant.
+func (s *Fx_service) CreateTenantConfig(c *context.Context,
+	tenantRequest request.TenantConfigRequest) response.ResponseWithSimpleData[response.TenantConfigResponse] {
+	if _, err := common.ResolveTenant(*c, tenantRequest.TenantId, 0); err != nil {
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.Forbidden, tenantForbiddenError(tenantRequest.TenantId, 0))
+	}
+	if e := validateTenantConfig(tenantRequest); e != nil {
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.BadRequest, e)
+	}
+
+	now := time.Now()
+	tenant := getTenantConfigFromRequest(tenantRequest)
+	tenant.CreatedDate = now
+	tenant.CreatedBy = common.ActorFromContext(*c)
+	tenant.UpdatedDate = now
+	tenant.UpdatedBy = common.ActorFromContext(*c)
+	tenant.DocVersion = 1
+
+	tenant, err := s.TenantService.CreateTenant(*c, tenant)
+	if errors.Is(err, dal.ErrDuplicateRecord) {
+		e := &[]response.Error{
+			{Code: "TENANT_EXISTS", Message: "Tenant already exists", Details: fmt.Sprintf("Tenant %d is already onboarded", tenant.TenantID)},
+		}
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.Conflict, e)
+	}
+	if err != nil {
+		common.Log(*c).Errorf("Error in creating tenant %d. Exception:%v", tenant.TenantID, err)
+		e := &[]response.Error{
+			{Code: "FAILURE", Message: "Unable to create record", Details: "Unable to create record due to some exception."},
+		}
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.InternalError, e)
+	}
+	return common.GetSimpleResponse[response.TenantConfigResponse](getTenantConfigDtoFromEntity(tenant), response.Success, nil)
+}
+
+// GetTenantConfig returns the configuration of a tenant.
+func (s *Fx_service) GetTenantConfig(c *context.Context, tenantId int) response.ResponseWithSimpleData[response.TenantConfigResponse] {
+	tenant, e := s.findTenantConfig(c, tenantId)
+	if e != nil {
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.NotFound, e)
+	}
+	return common.GetSimpleResponse[response.TenantConfigResponse](getTenantConfigDtoFromEntity(tenant), response.Success, nil)
+}
+
+// GetTenantConfigs returns the configuration of every tenant visible to the caller.
+func (s *Fx_service) GetTenantConfigs(c *context.Context) response.ResponseWithArrayData[response.TenantConfigResponse] {
+	tenants, err := s.TenantService.GetTenants(*c)
+	if err != nil {
+		common.Log(*c).Errorf("Error in retriving tenants. Exception:%v", err)
+		e := &[]response.Error{
+			{Code: "FAILURE", Message: "Unable to retrieve records", Details: "Unable to retrieve records due to some exception."},
+		}
+		return common.GetArrayResponse[response.TenantConfigResponse](nil, response.InternalError, e)
+	}
+
+	var data []response.TenantConfigResponse
+	for _, tenant := range tenants {
+		data = append(data, *getTenantConfigDtoFromEntity(tenant))
+	}
+	return common.GetArrayResponse[response.TenantConfigResponse](&data, response.Success, nil)
+}
+
+// UpdateTenantConfig replaces the configuration of a tenant, including its banks, at the
+// version of the request.
+func (s *Fx_service) UpdateTenantConfig(c *context.Context, tenantId int,
+	tenantRequest request.TenantConfigRequest) response.ResponseWithSimpleData[response.TenantConfigResponse] {
+	tenantRequest.TenantId = tenantId
+	if e := validateTenantConfig(tenantRequest); e != nil {
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.BadRequest, e)
+	}
+	return s.changeTenantConfig(c, tenantId, tenantRequest.DocVersion, func(tenant *entity.TenantConfig) {
+		updated := getTenantConfigFromRequest(tenantRequest)
+		updated.CreatedDate, updated.CreatedBy, updated.DocVersion = tenant.CreatedDate, tenant.CreatedBy, tenant.DocVersion
+		*tenant = updated
+	})
+}
+
+// UpsertBankConfig adds a bank to a tenant or replaces the configuration of the bank, at
+// the given version of the tenant.
+func (s *Fx_service) UpsertBankConfig(c *context.Context, tenantId int, bankId int, docVersion int,
+	bank entity.BankConfig) response.ResponseWithSimpleData[response.TenantConfigResponse] {
+	bank.BankID = bankId
+	if e := validateBankConfig(bank); e != nil {
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.BadRequest, e)
+	}
+	return s.changeTenantConfig(c, tenantId, docVersion, func(tenant *entity.TenantConfig) {
+		if existing := tenant.Bank(bankId); existing != nil {
+			*existing = bank
+			return
+		}
+		tenant.Banks = append(tenant.Banks, bank)
+	})
+}
+
+// DeleteBankConfig removes a bank from a tenant, at the given version of the tenant.
+func (s *Fx_service) DeleteBankConfig(c *context.Context, tenantId int, bankId int, docVersion int) response.ResponseWithSimpleData[response.TenantConfigResponse] {
+	return s.changeTenantConfig(c, tenantId, docVersion, func(tenant *entity.TenantConfig) {
+		var banks []entity.BankConfig
+		for _, bank := range tenant.Banks {
+			if bank.BankID != bankId {
+				banks = append(banks, bank)
+			}
+		}
+		tenant.Banks = banks
+	})
+}
+
+// DeleteTenantConfig removes the configuration of a tenant, its rates are kept.
+func (s *Fx_service) DeleteTenantConfig(c *context.Context, tenantId int) response.ResponseWithSimpleData[response.TenantConfigResponse] {
+	err := s.TenantService.DeleteTenant(*c, tenantId)
+	if err != nil {
+		common.Log(*c).Errorf("Error in deleting tenant %d. Exception:%v", tenantId, err)
+		e := &[]response.Error{
+			{Code: "DATA_NOT_FOUND", Message: "No record Deleted", Details: "No record Deleted"},
+		}
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.NotFound, e)
+	}
+	return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.Success, nil)
+}
+
+// changeTenantConfig applies a change to the configuration of a tenant at the version the
+// caller read. Changes made since by another caller are reported as a conflict.
+func (s *Fx_service) changeTenantConfig(c *context.Context, tenantId int, docVersion int,
+	change func(tenant *entity.TenantConfig)) response.ResponseWithSimpleData[response.TenantConfigResponse] {
+	if docVersion <= 0 {
+		e := &[]response.Error{invalidInput("docVersion of the tenant must be given")}
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.BadRequest, e)
+	}
+	tenant, e := s.findTenantConfig(c, tenantId)
+	if e != nil {
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.NotFound, e)
+	}
+	// the store only saves the change when it still has this version
+	tenant.DocVersion = docVersion
+	change(&tenant)
+	tenant.UpdatedDate = time.Now()
+	tenant.UpdatedBy = common.ActorFromContext(*c)
+
+	tenant, err := s.TenantService.UpdateTenant(*c, tenant)
+	if errors.Is(err, dal.ErrVersionConflict) {
+		e := &[]response.Error{
+			{Code: "VERSION_CONFLICT", Message: "Tenant was changed concurrently", Details: "Reload the tenant and apply the change again"},
+		}
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.Conflict, e)
+	}
+	if err != nil {
+		common.Log(*c).Errorf("Error in updating tenant %d. Exception:%v", tenantId, err)
+		e := &[]response.Error{
+			{Code: "FAILURE", Message: "Unable to save record", Details: "Unable to save record due to some exception."},
+		}
+		return common.GetSimpleResponse[response.TenantConfigResponse](nil, response.InternalError, e)
+	}
+	return common.GetSimpleResponse[response.TenantConfigResponse](getTenantConfigDtoFromEntity(tenant), response.Success, nil)
+}
+
+func (s *Fx_service) findTenantConfig(c *context.Context, tenantId int) (entity.TenantConfig, *[]response.Error) {
+	tenant, err := s.TenantService.GetTenant(*c, tenantId)
+	if err != nil {
+		common.Log(*c).Errorf("Error in retriving tenant %d. Exception:%v", tenantId, err)
+		return tenant, &[]response.Error{
+			{Code: "DATA_NOT_FOUND", Message: "No record found", Details: "No record found"},
+		}
+	}
+	return tenant, nil
+}
+
+// getTenantConfig returns the configuration the requests of a tenant are checked against,
+// nil when the tenant has not been onboarded.
+func (s *Fx_service) getTenantConfig(c *context.Context, tenantId int) *entity.TenantConfig {
+	if s.TenantService == nil {
+		return nil
+	}
+	tenant, err := s.TenantService.GetTenant(*c, tenantId)
+	if err != nil {
+		if !errors.Is(err, dal.ErrNoRecord) {
+			common.Log(*c).Warnf("Configuration of tenant %d is not applied. Exception:%v", tenantId, err)
+		}
+		return nil
+	}
+	return &tenant
+}
+
+// checkTenantConfig checks that the bank belongs to the tenant and trades the pair.
+func checkTenantConfig(tenant *entity.TenantConfig, bankId int, baseCurrency string, targetCurrency string) *[]response.Error {
+	if len(tenant.Banks) > 0 && tenant.Bank(bankId) == nil {
+		return &[]response.Error{
+			{Code: "BANK_NOT_CONFIGURED", Message: "Bank is not configured",
+				Details: fmt.Sprintf("Bank %d is not configured for tenant %d", bankId, tenant.TenantID)},
+		}
+	}
+	_, allowedPairs, _ := tenant.EffectiveSettings(bankId)
+	if !entity.PairAllowed(allowedPairs, baseCurrency, targetCurrency) {
+		return &[]response.Error{
+			{Code: "PAIR_NOT_ALLOWED", Message: "Currency pair is not allowed",
+				Details: fmt.Sprintf("%s is not traded by tenant %d bank %d", entity.Pair(baseCurrency, targetCurrency), tenant.TenantID, bankId)},
+		}
+	}
+	return nil
+}
+
+// validateRateData checks incoming rate data against the configuration of its tenant.
+func (s *Fx_service) validateRateData(c *context.Context, forexData request.CreateForexDataRequest) *[]response.Error {
+	tenant := s.getTenantConfig(c, forexData.TenantId)
+	if tenant == nil {
+		return nil
+	}
+	return checkTenantConfig(tenant, forexData.BankId, forexData.BaseCurrency, forexData.TargetCurrency)
+}
+
+// applyTenantConfig fills the base currency and tier of a conversion from the tenant
+// configuration when they are not given, and checks the conversion against it. The
+// configuration is nil for tenants that have not been onboarded.
+func (s *Fx_service) applyTenantConfig(c *context.Context, convertRequest *request.FxDataRequest) (*entity.TenantConfig, *[]response.Error) {
+	tenant := s.getTenantConfig(c, convertRequest.TenantId)
+	if tenant == nil {
+		return nil, nil
+	}
+	defaultTier, _, _ := tenant.EffectiveSettings(convertRequest.BankId)
+	if convertRequest.BaseCurrency == "" {
+		convertRequest.BaseCurrency = tenant.BaseCurrency
+	}
+	if convertRequest.Tier == "" && defaultTier != "" {
+		convertRequest.Tier = defaultTier
+	}
+	return tenant, checkTenantConfig(tenant, convertRequest.BankId, convertRequest.BaseCurrency, convertRequest.TargetCurrency)
+}
+
+// roundAmount rounds a converted amount by the rounding policy of the bank or tenant.
+func roundAmount(tenant *entity.TenantConfig, bankId int, amount float64) float64 {
+	if tenant == nil {
+		return amount
+	}
+	_, _, rounding := tenant.EffectiveSettings(bankId)
+	return rounding.Round(amount)
+}
+
+// getConversionRate returns the rate of a conversion, crossed through the pivot currency of
+// the tenant when there is no direct rate for the pair.
+func (s *Fx_service) getConversionRate(c *context.Context, convertRequest request.FxDataRequest, tenant *entity.TenantConfig) (conversionRate, error) {
+	rate, err := s.getRate(c, convertRequest)
+	if err == nil || tenant == nil {
+		return rate, err
+	}
+	pivot := tenant.PivotCurrency
+	if pivot == "" || strings.EqualFold(pivot, convertRequest.BaseCurrency) || strings.EqualFold(pivot, convertRequest.TargetCurrency) {
+		return rate, err
+	}
+
+	firstRequest := convertRequest
+	firstRequest.TargetCurrency = pivot
+	first, err := s.getRate(c, firstRequest)
+	if err != nil {
+		return first, err
+	}
+	secondRequest := convertRequest
+	secondRequest.BaseCurrency = pivot
+	secondRequest.Amount = convertRequest.Amount * first.buyRate
+	secondRequest.Tier = first.record.Tier
+	second, err := s.getRate(c, secondRequest)
+	if err != nil {
+		return second, err
+	}
+
+	record := first.record
+	record.TargetCurrency = convertRequest.TargetCurrency
+	return conversionRate{record: record, buyRate: first.buyRate * second.buyRate, sellRate: first.sellRate * second.sellRate}, nil
+}
+
+func getTenantConfigFromRequest(tenantRequest request.TenantConfigRequest) entity.TenantConfig {
+	return entity.TenantConfig{
+		TenantID:      tenantRequest.TenantId,
+		Name:          tenantRequest.Name,
+		BaseCurrency:  strings.ToUpper(tenantRequest.BaseCurrency),
+		PivotCurrency: strings.ToUpper(tenantRequest.PivotCurrency),
+		DefaultTier:   tenantRequest.DefaultTier,
+		AllowedPairs:  normalizePairs(tenantRequest.AllowedPairs),
+		Rounding:      tenantRequest.Rounding,
+		Banks:         tenantRequest.Banks,
+	}
+}
+
+func normalizePairs(pairs []string) []string {
+	var normalized []string
+	for _, pair := range pairs {
+		normalized = append(normalized, strings.ToUpper(pair))
+	}
+	return normalized
+}
+
+func validateTenantConfig(tenantRequest request.TenantConfigRequest) *[]response.Error {
+	var errs []response.Error
+	if tenantRequest.TenantId <= 0 {
+		errs = append(errs, invalidInput("tenantId must be greater than zero"))
+	}
+	if !currencyPattern.MatchString(strings.ToUpper(tenantRequest.BaseCurrency)) {
+		errs = append(errs, invalidInput("baseCurrency must be an ISO 4217 currency code"))
+	}
+	if tenantRequest.PivotCurrency != "" && !currencyPattern.MatchString(strings.ToUpper(tenantRequest.PivotCurrency)) {
+		errs = append(errs, invalidInput("pivotCurrency must be an ISO 4217 currency code"))
+	}
+	errs = append(errs, validatePairs(tenantRequest.AllowedPairs)...)
+	if tenantRequest.Rounding != nil {
+		if err := tenantRequest.Rounding.Validate(); err != nil {
+			errs = append(errs, invalidInput(err.Error()))
+		}
+	}
+	seen := map[int]bool{}
+	for _, bank := range tenantRequest.Banks {
+		if seen[bank.BankID] {
+			errs = append(errs, invalidInput(fmt.Sprintf("bank %d is configured more than once", bank.BankID)))
+		}
+		seen[bank.BankID] = true
+		if e := validateBankConfig(bank); e != nil {
+			errs = append(errs, *e...)
+		}
+	}
+	if len(errs) > 0 {
+		return &errs
+	}
+	return nil
+}
+
+func validateBankConfig(bank entity.BankConfig) *[]response.Error {
+	var errs []response.Error
+	if bank.BankID <= 0 {
+		errs = append(errs, invalidInput("bankId must be greater than zero"))
+	}
+	errs = append(errs, validatePairs(bank.AllowedPairs)...)
+	if bank.Rounding != nil {
+		if err := bank.Rounding.Validate(); err != nil {
+			errs = append(errs, invalidInput(err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return &errs
+	}
+	return nil
+}
+
+func validatePairs(pairs []string) []response.Error {
+	var errs []response.Error
+	for _, pair := range pairs {
+		currencies := strings.Split(strings.ToUpper(pair), "/")
+		if len(currencies) != 2 || !currencyPattern.MatchString(currencies[0]) || !currencyPattern.MatchString(currencies[1]) {
+			errs = append(errs, invalidInput(fmt.Sprintf("pair %q must be formatted as BASE/TARGET", pair)))
+		}
+	}
+	return errs
+}
+
+func invalidInput(details string) response.Error {
+	return response.Error{Code: "INVALID_INPUT", Message: "Invalid tenant configuration", Details: details}
+}
@@ -0,0 +1,114 @@
+package bal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+)
+
+const (
+	// AutoTier asks for the tier to be chosen by the amount band the conversion falls in
+	AutoTier = "auto"
+
+	ContractThresholdFlag   = "flag"
+	ContractThresholdReject = "reject"
+)
+
+// resolveTier picks the tier whose amount band contains the amount of the conversion. The
+// contract requirement threshold of a tier is the upper bound of its band and a tier without
+// a threshold is the open ended top band. Amounts above every band get the highest band.
+// The record of the pricing base tier is not a tier of its own and has no band.
+func (s *Fx_service) resolveTier(c *context.Context, convertRequest request.FxDataRequest) (string, error) {
+	convertRequest.Tier = ""
+	records, err := s.DbService.Get(*c, convertRequest)
+	if err != nil {
+		return "", err
+	}
+
+	type band struct {
+		tier  string
+		upper float64
+		open  bool
+	}
+	var bands []band
+	for _, record := range records {
+		if s.Pricing != nil && record.Tier == s.Pricing.BaseTier {
+			continue
+		}
+		threshold := record.ContractRequirementThreshold
+		if !threshold.IsSet() {
+			bands = append(bands, band{tier: record.Tier, open: true})
+			continue
+		}
+		upper, err := thresholdInBaseCurrency(threshold, record)
+		if err != nil {
+			return "", err
+		}
+		bands = append(bands, band{tier: record.Tier, upper: upper})
+	}
+	if len(bands) == 0 {
+		return "", errors.New("no tiers configured for the currency pair")
+	}
+
+	sort.SliceStable(bands, func(i, j int) bool {
+		if bands[i].open || bands[j].open {
+			return !bands[i].open && bands[j].open
+		}
+		return bands[i].upper < bands[j].upper
+	})
+	for _, band := range bands {
+		if band.open || convertRequest.Amount <= band.upper {
+			return band.tier, nil
+		}
+	}
+	return bands[len(bands)-1].tier, nil
+}
+
+// thresholdInBaseCurrency expresses a threshold in the base currency of the rate record. A
+// threshold in neither currency of the record cannot be converted and fails.
+func thresholdInBaseCurrency(threshold *entity.Threshold, record entity.ForexData) (float64, error) {
+	switch threshold.Currency {
+	case "", record.BaseCurrency:
+		return threshold.Amount, nil
+	case record.TargetCurrency:
+		if record.BuyRate <= 0 {
+			return 0, fmt.Errorf("tier %s has no rate to convert its threshold from %s", record.Tier, threshold.Currency)
+		}
+		return threshold.Amount / record.BuyRate, nil
+	}
+	return 0, fmt.Errorf("tier %s has a threshold in %s, which is neither %s nor %s",
+		record.Tier, threshold.Currency, record.BaseCurrency, record.TargetCurrency)
+}
+
+// exceedsThreshold compares a conversion with the threshold in the threshold currency. A
+// threshold in the target currency is compared with the converted amount, any other
+// threshold with the amount being converted.
+func exceedsThreshold(threshold *entity.Threshold, amount float64, convertedAmount float64, targetCurrency string) bool {
+	if !threshold.IsSet() {
+		return false
+	}
+	if threshold.Currency != "" && threshold.Currency == targetCurrency {
+		return convertedAmount > threshold.Amount
+	}
+	return amount > threshold.Amount
+}
+
+func contractRequiredError(threshold *entity.Threshold, baseCurrency string) *[]response.Error {
+	return &[]response.Error{
+		{Code: "CONTRACT_REQUIRED", Message: "A deal contract is required",
+			Details: fmt.Sprintf("The amount exceeds the contract requirement threshold of %v %s",
+				threshold.Amount, thresholdCurrency(threshold, baseCurrency))},
+	}
+}
+
+func thresholdCurrency(threshold *entity.Threshold, baseCurrency string) string {
+	if threshold.Currency == "" {
+		return baseCurrency
+	}
+	return threshold.Currency
+}
@@ -0,0 +1,23 @@
+package common
+
+import "context"
+
+type actorKey struct{}
+
+// SystemActor is recorded when a change is made without a known caller.
+const SystemActor = "system"
+
+// WithActor returns a context that attributes changes made with it to the given actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor changes should be attributed to.
+func ActorFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+			return actor
+		}
+	}
+	return SystemActor
+}
@@ -1,12 +1,106 @@
 package common
 
 import (
+	"context"
+	"fmt"
+	"sync"
+
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	LogFormatJson = "json"
+	LogFormatText = "text"
 )
 
 var Logger = logrus.New()
 
-func InitLog() {
-	Logger.SetFormatter(&logrus.JSONFormatter{})
-	Logger.SetLevel(logrus.TraceLevel)
+// InitLog sets the level of the logs, e.g. info or debug, and their format, json or text.
+func InitLog(level string, format string) error {
+	switch format {
+	case LogFormatJson:
+		Logger.SetFormatter(&logrus.JSONFormatter{})
+	case LogFormatText:
+		Logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown log format %q, use %s or %s", format, LogFormatJson, LogFormatText)
+	}
+	return SetLogLevel(level)
+}
+
+// SetLogLevel changes the level of the logs, it can be changed while running.
+func SetLogLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	Logger.SetLevel(parsed)
+	return nil
+}
+
+// LogLevel returns the level of the logs.
+func LogLevel() string {
+	return Logger.GetLevel().String()
+}
+
+// Log returns the logger of the work done with the context. Its lines carry the trace and
+// the span, the tenant and the route of the request when the context has them.
+func Log(ctx context.Context) *logrus.Entry {
+	if ctx == nil {
+		return logrus.NewEntry(Logger)
+	}
+	fields := logrus.Fields{}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields["trace_id"] = span.TraceID().String()
+		fields["span_id"] = span.SpanID().String()
+	}
+	if scope, ok := TenantScopeFromContext(ctx); ok {
+		fields["tenant"] = scope.TenantId
+	}
+	if route := routeFromContext(ctx); route != "" {
+		fields["route"] = route
+	}
+	return Logger.WithContext(ctx).WithFields(fields)
+}
+
+type routeKey struct{}
+
+// RequestRoute holds the route a request is served by, it is only known once the request
+// is matched by the router.
+type RequestRoute struct {
+	mu    sync.Mutex
+	route func() string
+}
+
+// NewRequestRoute returns the route of a request found by calling route until Freeze.
+func NewRequestRoute(route func() string) *RequestRoute {
+	return &RequestRoute{route: route}
+}
+
+func (r *RequestRoute) get() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.route()
+}
+
+// Freeze keeps the route of the request once it is served, as the request it is found in
+// is reused for the next ones.
+func (r *RequestRoute) Freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	route := r.route()
+	r.route = func() string { return route }
+}
+
+// WithRoute returns a context whose logs carry the route of the request.
+func WithRoute(ctx context.Context, route *RequestRoute) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+func routeFromContext(ctx context.Context) string {
+	if route, ok := ctx.Value(routeKey{}).(*RequestRoute); ok {
+		return route.get()
+	}
+	return ""
 }
@@ -0,0 +1,8 @@
+package common
+
+import "math"
+
+// RoundRate rounds a rate to the six decimals rates are stored with.
+func RoundRate(rate float64) float64 {
+	return math.Round(rate*1e6) / 1e6
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCrossTenant is returned when a caller addresses a tenant or bank outside its scope.
+var ErrCrossTenant = errors.New("access to another tenant or bank is not allowed")
+
+// TenantScope is the tenant, and optionally the banks of that tenant, a caller may access.
+// An empty BankIds allows every bank of the tenant.
+type TenantScope struct {
+	TenantId int
+	BankIds  []int
+}
+
+// AllowsBank reports whether the scope includes the bank.
+func (s TenantScope) AllowsBank(bankId int) bool {
+	if len(s.BankIds) == 0 {
+		return true
+	}
+	for _, allowed := range s.BankIds {
+		if allowed == bankId {
+			return true
+		}
+	}
+	return false
+}
+
+type tenantScopeKey struct{}
+
+// WithTenantScope returns a context restricted to the tenant scope.
+func WithTenantScope(ctx context.Context, scope TenantScope) context.Context {
+	return context.WithValue(ctx, tenantScopeKey{}, scope)
+}
+
+// TenantScopeFromContext returns the tenant scope of the context. It reports false for
+// unscoped contexts such as background jobs or deployments without authentication.
+func TenantScopeFromContext(ctx context.Context) (TenantScope, bool) {
+	if ctx == nil {
+		return TenantScope{}, false
+	}
+	scope, ok := ctx.Value(tenantScopeKey{}).(TenantScope)
+	return scope, ok
+}
+
+// ResolveTenant returns the tenant a request addressed to tenantId and bankId runs for.
+// A zero tenantId defaults to the tenant of the scope, any other tenant or a bank outside
+// the scope fails with ErrCrossTenant. Unscoped contexts keep the given tenant.
+func ResolveTenant(ctx context.Context, tenantId int, bankId int) (int, error) {
+	scope, ok := TenantScopeFromContext(ctx)
+	if !ok {
+		return tenantId, nil
+	}
+	if tenantId != 0 && tenantId != scope.TenantId {
+		return tenantId, ErrCrossTenant
+	}
+	if bankId != 0 && !scope.AllowsBank(bankId) {
+		return scope.TenantId, ErrCrossTenant
+	}
+	return scope.TenantId, nil
+}
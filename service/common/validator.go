@@ -118,6 +118,9 @@ func ParamValidationMiddlewareFiber[T any](validationRules []validation.Validati
 				})
 				continue
 			}
+			if paramValue == "" {
+				continue
+			}
 
 			if rule.ParamType == "int" {
 				_, err := strconv.Atoi(paramValue)
@@ -0,0 +1,38 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	connectionsMu sync.Mutex
+	connections   []func(ctx context.Context) error
+)
+
+// track keeps the close function of a connection opened by Init, or of a job using it, so
+// Close can close it.
+func track(close func(ctx context.Context) error) {
+	connectionsMu.Lock()
+	defer connectionsMu.Unlock()
+	connections = append(connections, close)
+}
+
+// Close closes every database connection opened so far, waiting for the operations in
+// use until the context is done. They are closed in the reverse order they were opened, so
+// the jobs using a connection stop before it is closed.
+func Close(ctx context.Context) error {
+	connectionsMu.Lock()
+	closing := connections
+	connections = nil
+	connectionsMu.Unlock()
+
+	var errs []error
+	for i := len(closing) - 1; i >= 0; i-- {
+		if err := closing[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
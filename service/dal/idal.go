@@ -1,21 +1,84 @@
 package dal
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/PeerIslands/aci-fx-go/config"
 	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/go-pg/pg/v10"
 	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrNoRecord is returned when no record matches the given id or filter.
+var ErrNoRecord = errors.New("no record found")
+
+// ErrDuplicateRecord is returned when a record with the same key already exists.
+var ErrDuplicateRecord = errors.New("record already exists")
+
+// ErrVersionConflict is returned when a record was changed since it was read.
+var ErrVersionConflict = errors.New("record was changed concurrently")
+
+// DBService stores forex records. Every method is restricted to the tenant scope of the
+// context: filters only match records of the scope and records outside it are rejected
+// with common.ErrCrossTenant.
 type DBService[T any] interface {
 	Init(credentials ...string)
-	GetOne(filter any) (T, error)
-	GetOneById(id int) (T, error)
-	Get(filter any) ([]T, error)
-	CreateOne(document T) (T, error)
-	BulkInsert(documents []T) (T, error)
-	UpdateOne(document any, filter any) (any, error)
-	UpdateOneById(id any) (any, error)
-	DeleteOne(filter any) (int64, error)
+	GetOne(ctx context.Context, filter any) (T, error)
+	GetOneById(ctx context.Context, id int) (T, error)
+	Get(ctx context.Context, filter any) ([]T, error)
+	CreateOne(ctx context.Context, document T) (T, error)
+	BulkInsert(ctx context.Context, documents []T) (T, error)
+	// UpdateOne writes the record document to the record matching the filter, bumps its
+	// docVersion and returns it as stored. Identity and creation details are kept.
+	UpdateOne(ctx context.Context, document any, filter any) (any, error)
+	// UpdateOneById is UpdateOne for the record found by GetOneById
+	UpdateOneById(ctx context.Context, document any, id any) (any, error)
+	UpsertOne(ctx context.Context, document T, filter any) (T, error)
+	// DeleteOne removes a record by id and returns it as it was before the delete
+	DeleteOne(ctx context.Context, filter any) (T, error)
+}
+
+// QuoteDBService stores rate quotes. RedeemQuote marks an issued quote as redeemed in a
+// single atomic step and returns ErrNoRecord when the quote is unknown, already redeemed
+// or expired at the given time. Quotes are restricted to the tenant scope of the context.
+type QuoteDBService interface {
+	CreateQuote(ctx context.Context, quote entity.Quote) (entity.Quote, error)
+	GetQuote(ctx context.Context, id string) (entity.Quote, error)
+	RedeemQuote(ctx context.Context, id string, redeemedBy string, now time.Time) (entity.Quote, error)
+}
+
+// TenantDBService stores the configuration of tenants and their banks. UpdateTenant
+// replaces a configuration only when its DocVersion is still the stored one and returns
+// ErrVersionConflict otherwise. Tenants outside the scope of the context are not visible.
+type TenantDBService interface {
+	CreateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error)
+	GetTenant(ctx context.Context, tenantId int) (entity.TenantConfig, error)
+	GetTenants(ctx context.Context) ([]entity.TenantConfig, error)
+	UpdateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error)
+	DeleteTenant(ctx context.Context, tenantId int) error
+}
+
+// RateLimitDBService keeps rate limit state shared by all instances of the service.
+// UpdateBucket applies update to the bucket of key atomically, a bucket that does not exist
+// yet is passed as its zero value. IncrementUsage counts a request against a limit and
+// reports false without counting it once the limit is reached.
+type RateLimitDBService interface {
+	UpdateBucket(ctx context.Context, key string, update func(entity.RateLimitBucket) entity.RateLimitBucket) (entity.RateLimitBucket, error)
+	IncrementUsage(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error)
+}
+
+// RateEventDBService is the outbox of rate events. Events are added along with the rate
+// change they describe and kept until the relay has published them.
+type RateEventDBService interface {
+	AddRateEvents(ctx context.Context, events []entity.RateEvent) error
+	// GetPendingRateEvents returns the oldest events that were not published yet
+	GetPendingRateEvents(ctx context.Context, limit int) ([]entity.RateEvent, error)
+	MarkRateEventPublished(ctx context.Context, id string, publishedAt time.Time) error
+	// DeletePublishedRateEvents removes the events published before the given time
+	DeletePublishedRateEvents(ctx context.Context, publishedBefore time.Time) (int64, error)
 }
 
 func GetDataAccess(config *config.Config) DBService[entity.ForexData] {
@@ -28,15 +91,119 @@ func GetDataAccess(config *config.Config) DBService[entity.ForexData] {
 	if config.Db.Mongo.Url != "" {
 		var db = MongoDbService[entity.ForexData]{}
 		db.Init(config.Db.Mongo.Url)
-		return &db
+		return measure[entity.ForexData](&db, systemMongo)
 	}
 
 	if config.Db.Yugabyte.Address != "" {
 		var ydb = YugaByteDbService[entity.ForexData]{}
 		ydb.Init(config.Db.Yugabyte.Username, config.Db.Yugabyte.Password, config.Db.Yugabyte.Dbname, config.Db.Yugabyte.Address, config.Db.Yugabyte.PoolSize)
-		return &ydb
+		return measure[entity.ForexData](&ydb, systemYugabyte)
+	}
+
+	log.Fatal("No database configuration found")
+	return nil
+}
+
+func GetQuoteAccess(config *config.Config) QuoteDBService {
+
+	if config == nil {
+		log.Fatal("No configuration found")
+		return nil
+	}
+
+	if config.Db.Mongo.Url != "" {
+		getMongoDatabase(config)
+		return &MongoQuoteService{}
+	}
+
+	if config.Db.Yugabyte.Address != "" {
+		return &YugaByteQuoteService{YbDB: getYugabyteDatabase(config)}
+	}
+
+	log.Fatal("No database configuration found")
+	return nil
+}
+
+func GetTenantAccess(config *config.Config) TenantDBService {
+
+	if config == nil {
+		log.Fatal("No configuration found")
+		return nil
+	}
+
+	if config.Db.Mongo.Url != "" {
+		getMongoDatabase(config)
+		return &MongoTenantService{}
+	}
+
+	if config.Db.Yugabyte.Address != "" {
+		return &YugaByteTenantService{YbDB: getYugabyteDatabase(config)}
+	}
+
+	log.Fatal("No database configuration found")
+	return nil
+}
+
+func GetRateLimitAccess(config *config.Config) RateLimitDBService {
+
+	if config == nil {
+		log.Fatal("No configuration found")
+		return nil
+	}
+
+	if config.Db.Mongo.Url != "" {
+		getMongoDatabase(config)
+		service := &MongoRateLimitService{}
+		if err := service.ensureExpiry(context.Background()); err != nil {
+			log.Fatal("Unable to create the rate limit expiry indexes:", err)
+		}
+		return service
+	}
+
+	if config.Db.Yugabyte.Address != "" {
+		service := &YugaByteRateLimitService{YbDB: getYugabyteDatabase(config)}
+		service.startCleanup(rateLimitCleanupInterval)
+		return service
 	}
 
 	log.Fatal("No database configuration found")
 	return nil
 }
+
+func GetRateEventAccess(config *config.Config) RateEventDBService {
+
+	if config == nil {
+		log.Fatal("No configuration found")
+		return nil
+	}
+
+	if config.Db.Mongo.Url != "" {
+		getMongoDatabase(config)
+		return &MongoRateEventService{}
+	}
+
+	if config.Db.Yugabyte.Address != "" {
+		return &YugaByteRateEventService{YbDB: getYugabyteDatabase(config)}
+	}
+
+	log.Fatal("No database configuration found")
+	return nil
+}
+
+// getMongoDatabase returns the shared mongo database, connecting on first use.
+func getMongoDatabase(config *config.Config) *mongo.Database {
+	if database == nil {
+		var db = MongoDbService[entity.ForexData]{}
+		db.Init(config.Db.Mongo.Url)
+	}
+	return database
+}
+
+// getYugabyteDatabase returns the shared yugabyte connection pool, connecting on first use.
+func getYugabyteDatabase(config *config.Config) *pg.DB {
+	if ybDB == nil {
+		var ydb = YugaByteDbService[entity.ForexData]{}
+		ydb.Init(config.Db.Yugabyte.Username, config.Db.Yugabyte.Password, config.Db.Yugabyte.Dbname, config.Db.Yugabyte.Address, config.Db.Yugabyte.PoolSize)
+	}
+	return ybDB
+}
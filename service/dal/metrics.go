@@ -0,0 +1,124 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/telemetry"
+	"github.com/go-pg/pg/v10"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	systemMongo    = "mongodb"
+	systemYugabyte = "postgresql"
+)
+
+var meter = otel.Meter(instrumentationName)
+
+var callDuration, _ = meter.Float64Histogram("fx.db.call.duration",
+	metric.WithDescription("Time taken by a call of the forex record store by method"),
+	metric.WithUnit("s"),
+	metric.WithExplicitBucketBoundaries(telemetry.DurationBoundaries...))
+
+// measuredDBService records the latency of every call of the store it wraps.
+type measuredDBService[T any] struct {
+	DBService[T]
+	system string
+}
+
+func measure[T any](db DBService[T], system string) DBService[T] {
+	return &measuredDBService[T]{DBService: db, system: system}
+}
+
+func (m *measuredDBService[T]) record(ctx context.Context, method string, started time.Time, err error) {
+	outcome := callOutcome(err)
+	callDuration.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(
+		attribute.String("db.system", m.system),
+		attribute.String("method", method),
+		attribute.String("outcome", outcome),
+	))
+	if outcome == "error" {
+		common.Log(ctx).Debugf("Error in %s of forex records on %s. Exception:%v", method, m.system, err)
+	}
+}
+
+// callOutcome tells records that were not found apart from failed calls.
+func callOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrNoRecord), errors.Is(err, mongo.ErrNoDocuments), errors.Is(err, pg.ErrNoRows):
+		return "not_found"
+	default:
+		return "error"
+	}
+}
+
+func (m *measuredDBService[T]) GetOne(ctx context.Context, filter any) (T, error) {
+	started := time.Now()
+	result, err := m.DBService.GetOne(ctx, filter)
+	m.record(ctx, "GetOne", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) GetOneById(ctx context.Context, id int) (T, error) {
+	started := time.Now()
+	result, err := m.DBService.GetOneById(ctx, id)
+	m.record(ctx, "GetOneById", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) Get(ctx context.Context, filter any) ([]T, error) {
+	started := time.Now()
+	result, err := m.DBService.Get(ctx, filter)
+	m.record(ctx, "Get", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) CreateOne(ctx context.Context, document T) (T, error) {
+	started := time.Now()
+	result, err := m.DBService.CreateOne(ctx, document)
+	m.record(ctx, "CreateOne", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) BulkInsert(ctx context.Context, documents []T) (T, error) {
+	started := time.Now()
+	result, err := m.DBService.BulkInsert(ctx, documents)
+	m.record(ctx, "BulkInsert", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) UpdateOne(ctx context.Context, document any, filter any) (any, error) {
+	started := time.Now()
+	result, err := m.DBService.UpdateOne(ctx, document, filter)
+	m.record(ctx, "UpdateOne", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) UpdateOneById(ctx context.Context, document any, id any) (any, error) {
+	started := time.Now()
+	result, err := m.DBService.UpdateOneById(ctx, document, id)
+	m.record(ctx, "UpdateOneById", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) UpsertOne(ctx context.Context, document T, filter any) (T, error) {
+	started := time.Now()
+	result, err := m.DBService.UpsertOne(ctx, document, filter)
+	m.record(ctx, "UpsertOne", started, err)
+	return result, err
+}
+
+func (m *measuredDBService[T]) DeleteOne(ctx context.Context, filter any) (T, error) {
+	started := time.Now()
+	result, err := m.DBService.DeleteOne(ctx, filter)
+	m.record(ctx, "DeleteOne", started, err)
+	return result, err
+}
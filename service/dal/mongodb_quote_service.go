@@ -0,0 +1,60 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const quoteCollectionName = "quotes"
+
+type MongoQuoteService struct {
+}
+
+func (db *MongoQuoteService) CreateQuote(ctx context.Context, quote entity.Quote) (entity.Quote, error) {
+	if err := checkTenant(ctx, quote); err != nil {
+		return quote, err
+	}
+	_, err := database.Collection(quoteCollectionName).InsertOne(ctx, quote)
+	if err != nil {
+		return quote, err
+	}
+	return quote, nil
+}
+
+func (db *MongoQuoteService) GetQuote(ctx context.Context, id string) (entity.Quote, error) {
+	var quote entity.Quote
+	err := database.Collection(quoteCollectionName).FindOne(ctx, scopeMongoFilter(ctx, bson.M{"_id": id})).Decode(&quote)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return quote, ErrNoRecord
+	}
+	return quote, err
+}
+
+func (db *MongoQuoteService) RedeemQuote(ctx context.Context, id string, redeemedBy string, now time.Time) (entity.Quote, error) {
+	filterBson := bson.M{
+		"_id":       id,
+		"status":    entity.QuoteStatusIssued,
+		"expiresAt": bson.M{"$gt": now},
+	}
+	updateBson := bson.M{
+		"$set": bson.M{
+			"status":     entity.QuoteStatusRedeemed,
+			"redeemedAt": now,
+			"redeemedBy": redeemedBy,
+		},
+	}
+	option := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var quote entity.Quote
+	err := database.Collection(quoteCollectionName).FindOneAndUpdate(ctx, scopeMongoFilter(ctx, filterBson), updateBson, option).Decode(&quote)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return quote, ErrNoRecord
+	}
+	return quote, err
+}
@@ -0,0 +1,61 @@
+package dal
+
+import (
+	"context"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const rateEventCollectionName = "rate_events"
+
+type MongoRateEventService struct {
+}
+
+func (db *MongoRateEventService) AddRateEvents(ctx context.Context, events []entity.RateEvent) error {
+	docs := make([]interface{}, len(events))
+	for i, event := range events {
+		if err := checkTenant(ctx, event); err != nil {
+			return err
+		}
+		docs[i] = event
+	}
+	_, err := database.Collection(rateEventCollectionName).InsertMany(ctx, docs)
+	return err
+}
+
+func (db *MongoRateEventService) GetPendingRateEvents(ctx context.Context, limit int) ([]entity.RateEvent, error) {
+	option := options.Find().SetSort(bson.D{{"occurredAt", 1}, {"_id", 1}}).SetLimit(int64(limit))
+	cursor, err := database.Collection(rateEventCollectionName).Find(ctx, scopeMongoFilter(ctx, bson.M{"publishedAt": nil}), option)
+	if err != nil {
+		return nil, err
+	}
+	var events []entity.RateEvent
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (db *MongoRateEventService) MarkRateEventPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	result, err := database.Collection(rateEventCollectionName).UpdateOne(ctx,
+		scopeMongoFilter(ctx, bson.M{"_id": id}), bson.M{"$set": bson.M{"publishedAt": publishedAt}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+func (db *MongoRateEventService) DeletePublishedRateEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	result, err := database.Collection(rateEventCollectionName).DeleteMany(ctx,
+		scopeMongoFilter(ctx, bson.M{"publishedAt": bson.M{"$lt": publishedBefore}}))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
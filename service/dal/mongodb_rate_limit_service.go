@@ -0,0 +1,94 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	bucketCollectionName = "rate_limit_buckets"
+	usageCollectionName  = "rate_limit_usage"
+	// bucketUpdateAttempts bounds the retries of a bucket update that raced another instance
+	bucketUpdateAttempts = 5
+)
+
+var errBucketContention = errors.New("rate limit bucket is updated concurrently")
+
+type MongoRateLimitService struct {
+}
+
+// ensureExpiry lets mongo remove buckets and usage counters once they expire.
+func (db *MongoRateLimitService) ensureExpiry(ctx context.Context) error {
+	for _, name := range []string{bucketCollectionName, usageCollectionName} {
+		_, err := database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{"expiresAt", 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateBucket replaces the bucket only when it was not changed since it was read and
+// retries otherwise.
+func (db *MongoRateLimitService) UpdateBucket(ctx context.Context, key string,
+	update func(entity.RateLimitBucket) entity.RateLimitBucket) (entity.RateLimitBucket, error) {
+	collection := database.Collection(bucketCollectionName)
+	for attempt := 0; attempt < bucketUpdateAttempts; attempt++ {
+		current := entity.RateLimitBucket{Key: key}
+		err := collection.FindOne(ctx, bson.M{"_id": key}).Decode(&current)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return current, err
+		}
+
+		bucket := update(current)
+		bucket.Key = key
+		if current.UpdatedAt.IsZero() {
+			_, err = collection.InsertOne(ctx, bucket)
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return bucket, err
+		}
+
+		result, err := collection.ReplaceOne(ctx, bson.M{"_id": key, "updatedAt": current.UpdatedAt}, bucket)
+		if err != nil {
+			return bucket, err
+		}
+		if result.MatchedCount == 1 {
+			return bucket, nil
+		}
+	}
+	return entity.RateLimitBucket{Key: key}, errBucketContention
+}
+
+func (db *MongoRateLimitService) IncrementUsage(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error) {
+	filterBson := bson.M{
+		"_id":   key,
+		"count": bson.M{"$lt": limit},
+	}
+	updateBson := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expiresAt": expiresAt},
+	}
+	option := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var usage entity.RateLimitUsage
+	err := database.Collection(usageCollectionName).FindOneAndUpdate(ctx, filterBson, updateBson, option).Decode(&usage)
+	// the counter exists but is at the limit, so the upsert collides with it
+	if mongo.IsDuplicateKeyError(err) {
+		return limit, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return usage.Count, true, nil
+}
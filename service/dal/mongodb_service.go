@@ -8,6 +8,7 @@ import (
 	"log"
 
 	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -28,7 +29,10 @@ func (db *MongoDbService[T]) Init(credentials ...string) {
 	loggerOptions := options.
 		Logger().
 		SetComponentLevel(options.LogComponentServerSelection, options.LogLevelDebug)
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(credentials[0]).SetLoggerOptions(loggerOptions))
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI(credentials[0]).
+		SetLoggerOptions(loggerOptions).
+		SetMonitor(CommandMonitor()))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -39,6 +43,7 @@ func (db *MongoDbService[T]) Init(credentials ...string) {
 	}
 
 	database = client.Database(dbName)
+	track(client.Disconnect)
 	return
 }
 
@@ -46,46 +51,57 @@ func (db *MongoDbService[T]) GetDatabase() *mongo.Database {
 	return database
 }
 
-func getContext() context.Context {
-	return context.Background()
-}
-
-func (db *MongoDbService[T]) GetOne(filter any) (T, error) {
-	var fxRequest = filter.(request.FxDataRequest)
-	filterBson := bson.D{
-		{"tenantId", fxRequest.TenantId},
-		{"bankId", fxRequest.BankId},
-		{"baseCurrency", fxRequest.BaseCurrency},
-		{"targetCurrency", fxRequest.TargetCurrency},
-		{"tier", fxRequest.Tier},
+func (db *MongoDbService[T]) GetOne(ctx context.Context, filter any) (T, error) {
+	if fxRequest, ok := filter.(request.FxDataRequest); ok {
+		filter = bson.D{
+			{"tenantId", fxRequest.TenantId},
+			{"bankId", fxRequest.BankId},
+			{"baseCurrency", fxRequest.BaseCurrency},
+			{"targetCurrency", fxRequest.TargetCurrency},
+			{"tier", fxRequest.Tier},
+		}
 	}
 
-	result := database.Collection(collectionName).FindOne(getContext(), filterBson)
+	result := database.Collection(collectionName).FindOne(ctx, scopeMongoFilter(ctx, filter))
 	var data T
 	err := result.Decode(&data)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return data, ErrNoRecord
+	}
 	if err != nil {
 		return data, err
 	}
 	return data, nil
 }
 
-func (db *MongoDbService[T]) GetOneById(id int) (T, error) {
-
-	var data T
-
-	return data, nil
+// GetOneById finds the record updated by UpdateOneById, mongo records are numbered by
+// their docVersion.
+func (db *MongoDbService[T]) GetOneById(ctx context.Context, id int) (T, error) {
+	return db.GetOne(ctx, bson.D{{"docVersion", id}})
 }
 
-func (db *MongoDbService[T]) Get(filter any) ([]T, error) {
-	cursor, _ := database.Collection(collectionName).Find(getContext(), filter)
+func (db *MongoDbService[T]) Get(ctx context.Context, filter any) ([]T, error) {
+	if fxRequest, ok := filter.(request.FxDataRequest); ok {
+		filterBson := bson.M{
+			"tenantId":       fxRequest.TenantId,
+			"bankId":         fxRequest.BankId,
+			"baseCurrency":   fxRequest.BaseCurrency,
+			"targetCurrency": fxRequest.TargetCurrency,
+		}
+		if fxRequest.Tier != "" {
+			filterBson["tier"] = fxRequest.Tier
+		}
+		filter = filterBson
+	}
+	cursor, _ := database.Collection(collectionName).Find(ctx, scopeMongoFilter(ctx, filter))
 	defer func(cursor *mongo.Cursor, ctx context.Context) {
 		err := cursor.Close(ctx)
 		if err != nil {
 
 		}
-	}(cursor, context.Background())
+	}(cursor, ctx)
 	var data []T
-	for cursor.Next(context.Background()) {
+	for cursor.Next(ctx) {
 		var result T
 		if err := cursor.Decode(&result); err != nil {
 			fmt.Println("Error decoding document:", err)
@@ -95,8 +111,11 @@ func (db *MongoDbService[T]) Get(filter any) ([]T, error) {
 	return data, nil
 }
 
-func (db *MongoDbService[T]) CreateOne(document T) (T, error) {
-	_, err := database.Collection(collectionName).InsertOne(getContext(), document)
+func (db *MongoDbService[T]) CreateOne(ctx context.Context, document T) (T, error) {
+	if err := checkTenant(ctx, document); err != nil {
+		return document, err
+	}
+	_, err := database.Collection(collectionName).InsertOne(ctx, document)
 
 	if err != nil {
 		return document, err
@@ -104,73 +123,153 @@ func (db *MongoDbService[T]) CreateOne(document T) (T, error) {
 	return document, nil
 }
 
-func (db *MongoDbService[T]) UpdateOne(document any, filter any) (any, error) {
-	var fxRequest = filter.(request.FxDataRequest)
-	filterBson := bson.D{
-		{"tenantId", fxRequest.TenantId},
-		{"bankId", fxRequest.BankId},
-		{"baseCurrency", fxRequest.BaseCurrency},
-		{"targetCurrency", fxRequest.TargetCurrency},
-		{"tier", fxRequest.Tier},
-	}
-	option := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	updateBson := bson.D{
-		{"$inc", bson.D{
-			{"buyRate", 0.01},
-		}},
+func (db *MongoDbService[T]) UpdateOne(ctx context.Context, document any, filter any) (any, error) {
+	if fxRequest, ok := filter.(request.FxDataRequest); ok {
+		filter = bson.D{
+			{"tenantId", fxRequest.TenantId},
+			{"bankId", fxRequest.BankId},
+			{"baseCurrency", fxRequest.BaseCurrency},
+			{"targetCurrency", fxRequest.TargetCurrency},
+			{"tier", fxRequest.Tier},
+		}
 	}
+	return db.update(ctx, document, filter)
+}
 
-	result := database.Collection(collectionName).FindOneAndUpdate(getContext(), filterBson, updateBson, option)
+func (db *MongoDbService[T]) UpdateOneById(ctx context.Context, document any, id any) (any, error) {
+	return db.update(ctx, document, bson.D{{"docVersion", id.(int)}})
+}
 
+// update sets the fields of the record on the one matching the filter and bumps its
+// docVersion.
+func (db *MongoDbService[T]) update(ctx context.Context, document any, filter any) (T, error) {
 	var data T
-	err := result.Decode(&data)
+	if err := checkTenant(ctx, document); err != nil {
+		return data, err
+	}
+	fields, err := recordFields(document)
+	if err != nil {
+		return data, err
+	}
+	delete(fields, "_id")
+	delete(fields, "createdDate")
+	delete(fields, "createdBy")
+	delete(fields, "docVersion")
+	updateBson := bson.M{
+		"$set": fields,
+		"$inc": bson.M{"docVersion": 1},
+	}
+	option := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	result := database.Collection(collectionName).FindOneAndUpdate(ctx, scopeMongoFilter(ctx, filter), updateBson, option)
+
+	err = result.Decode(&data)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return data, ErrNoRecord
+	}
 	if err != nil {
 		return data, err
 	}
 	return data, nil
 }
 
-func (db *MongoDbService[T]) UpdateOneById(id any) (any, error) {
-	var docVersion = id.(int)
-	filterBson := bson.D{
-		{"docVersion", docVersion},
+// recordFields returns the fields of a record as they are stored.
+func recordFields(document any) (bson.M, error) {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	fields := bson.M{}
+	if err = bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (db *MongoDbService[T]) UpsertOne(ctx context.Context, document T, filter any) (T, error) {
+	if err := checkTenant(ctx, document); err != nil {
+		return document, err
 	}
-	updateBson := bson.D{
-		{"$inc", bson.D{
-			{"buyRate", 0.01},
-		}},
+	var fxRequest = filter.(request.FxDataRequest)
+	filterBson := bson.M{
+		"tenantId":       fxRequest.TenantId,
+		"bankId":         fxRequest.BankId,
+		"baseCurrency":   fxRequest.BaseCurrency,
+		"targetCurrency": fxRequest.TargetCurrency,
+		"tier":           fxRequest.Tier,
+	}
+
+	fields, err := recordFields(document)
+	if err != nil {
+		return document, err
+	}
+	// identity and creation details are only written when the record is new,
+	// docVersion is bumped on every write and a missing threshold keeps the stored one
+	if keepsThreshold(document) {
+		delete(fields, "contractRequirementThreshold")
+	}
+	insertOnly := bson.M{"_id": fields["_id"], "createdDate": fields["createdDate"], "createdBy": fields["createdBy"]}
+	delete(fields, "_id")
+	delete(fields, "createdDate")
+	delete(fields, "createdBy")
+	delete(fields, "docVersion")
+
+	updateBson := bson.M{
+		"$set":         fields,
+		"$setOnInsert": insertOnly,
+		"$inc":         bson.M{"docVersion": 1},
 	}
+	option := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
 
-	result, err := database.Collection(collectionName).UpdateOne(getContext(), filterBson, updateBson)
+	result := database.Collection(collectionName).FindOneAndUpdate(ctx, scopeMongoFilter(ctx, filterBson), updateBson, option)
 
-	if err != nil || result.ModifiedCount == 0 {
-		return false, err
+	var data T
+	err = result.Decode(&data)
+	if err != nil {
+		return data, err
 	}
-	return result.ModifiedCount, nil
+	return data, nil
+}
+
+// thresholdOwner is implemented by records with a contract requirement threshold.
+type thresholdOwner interface {
+	GetContractRequirementThreshold() *entity.Threshold
+}
+
+// keepsThreshold reports whether an upsert of the record leaves the stored threshold as it
+// is, which is the case when the record has none.
+func keepsThreshold(record any) bool {
+	owner, ok := record.(thresholdOwner)
+	return ok && owner.GetContractRequirementThreshold() == nil
 }
 
-func (db *MongoDbService[T]) DeleteOne(id any) (int64, error) {
+func (db *MongoDbService[T]) DeleteOne(ctx context.Context, id any) (T, error) {
 	objectId, _ := primitive.ObjectIDFromHex(id.(string))
 	filter := bson.D{{"_id", objectId}}
-	result, err := database.Collection(collectionName).DeleteOne(getContext(), filter)
+	result := database.Collection(collectionName).FindOneAndDelete(ctx, scopeMongoFilter(ctx, filter))
 
-	if err != nil {
-		return 0, err
+	var data T
+	err := result.Decode(&data)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return data, ErrNoRecord
 	}
-	if result.DeletedCount == 0 {
-		return 0, errors.New("no record found")
+	if err != nil {
+		return data, err
 	}
-	return result.DeletedCount, nil
+	return data, nil
 }
 
-func (db *MongoDbService[T]) BulkInsert(documents []T) (T, error) {
+func (db *MongoDbService[T]) BulkInsert(ctx context.Context, documents []T) (T, error) {
 
 	docs := make([]interface{}, len(documents))
 	for i, v := range documents {
+		if err := checkTenant(ctx, v); err != nil {
+			return v, err
+		}
 		docs[i] = v
 	}
 
-	result, err := database.Collection(collectionName).InsertMany(getContext(), docs, options.InsertMany())
+	result, err := database.Collection(collectionName).InsertMany(ctx, docs, options.InsertMany())
 
 	if err != nil {
 		return documents[0], err
@@ -0,0 +1,91 @@
+package dal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const tenantCollectionName = "tenants"
+
+type MongoTenantService struct {
+}
+
+func (db *MongoTenantService) CreateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error) {
+	if err := checkTenant(ctx, tenant); err != nil {
+		return tenant, err
+	}
+	_, err := database.Collection(tenantCollectionName).InsertOne(ctx, tenant)
+	if mongo.IsDuplicateKeyError(err) {
+		return tenant, ErrDuplicateRecord
+	}
+	if err != nil {
+		return tenant, err
+	}
+	return tenant, nil
+}
+
+func (db *MongoTenantService) GetTenant(ctx context.Context, tenantId int) (entity.TenantConfig, error) {
+	var tenant entity.TenantConfig
+	if !tenantInScope(ctx, tenantId) {
+		return tenant, ErrNoRecord
+	}
+	err := database.Collection(tenantCollectionName).FindOne(ctx, bson.M{"_id": tenantId}).Decode(&tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return tenant, ErrNoRecord
+	}
+	return tenant, err
+}
+
+func (db *MongoTenantService) GetTenants(ctx context.Context) ([]entity.TenantConfig, error) {
+	filterBson := bson.M{}
+	if scope, ok := common.TenantScopeFromContext(ctx); ok {
+		filterBson["_id"] = scope.TenantId
+	}
+	cursor, err := database.Collection(tenantCollectionName).Find(ctx, filterBson)
+	if err != nil {
+		return nil, err
+	}
+	var tenants []entity.TenantConfig
+	if err = cursor.All(ctx, &tenants); err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func (db *MongoTenantService) UpdateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error) {
+	if err := checkTenant(ctx, tenant); err != nil {
+		return tenant, err
+	}
+	filterBson := bson.M{"_id": tenant.TenantID, "docVersion": tenant.DocVersion}
+	tenant.DocVersion++
+	result, err := database.Collection(tenantCollectionName).ReplaceOne(ctx, filterBson, tenant)
+	if err != nil {
+		return tenant, err
+	}
+	if result.MatchedCount == 0 {
+		if _, err = db.GetTenant(ctx, tenant.TenantID); err != nil {
+			return tenant, err
+		}
+		return tenant, ErrVersionConflict
+	}
+	return tenant, nil
+}
+
+func (db *MongoTenantService) DeleteTenant(ctx context.Context, tenantId int) error {
+	if !tenantInScope(ctx, tenantId) {
+		return ErrNoRecord
+	}
+	result, err := database.Collection(tenantCollectionName).DeleteOne(ctx, bson.M{"_id": tenantId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package dal
+
+import (
+	"context"
+
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// tenantOwned is implemented by records that belong to a tenant and bank.
+type tenantOwned interface {
+	GetTenantId() int
+	GetBankId() int
+}
+
+// checkTenant rejects a record outside the tenant scope of the context.
+func checkTenant(ctx context.Context, record any) error {
+	scope, ok := common.TenantScopeFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	owned, ok := record.(tenantOwned)
+	if !ok {
+		return nil
+	}
+	if owned.GetTenantId() != scope.TenantId || !scope.AllowsBank(owned.GetBankId()) {
+		return common.ErrCrossTenant
+	}
+	return nil
+}
+
+// tenantInScope reports whether the tenant is visible in the scope of the context.
+func tenantInScope(ctx context.Context, tenantId int) bool {
+	scope, ok := common.TenantScopeFromContext(ctx)
+	return !ok || scope.TenantId == tenantId
+}
+
+// scopeMongoFilter narrows a mongo filter to the tenant scope of the context.
+func scopeMongoFilter(ctx context.Context, filter any) any {
+	scope, ok := common.TenantScopeFromContext(ctx)
+	if !ok {
+		return filter
+	}
+	tenantFilter := bson.M{"tenantId": scope.TenantId}
+	if len(scope.BankIds) > 0 {
+		tenantFilter["bankId"] = bson.M{"$in": scope.BankIds}
+	}
+	return bson.M{"$and": bson.A{filter, tenantFilter}}
+}
+
+// scopeQuery narrows a yugabyte query to the tenant scope of the context.
+func scopeQuery(ctx context.Context, query *orm.Query) *orm.Query {
+	scope, ok := common.TenantScopeFromContext(ctx)
+	if !ok {
+		return query
+	}
+	query = query.Where("tenant_id = ?", scope.TenantId)
+	if len(scope.BankIds) > 0 {
+		query = query.Where("bank_id IN (?)", pg.In(scope.BankIds))
+	}
+	return query
+}
+
+// scopeTenantQuery narrows a yugabyte query of tenant configurations to the tenant of the
+// scope of the context, they are not owned by a bank.
+func scopeTenantQuery(ctx context.Context, query *orm.Query) *orm.Query {
+	if scope, ok := common.TenantScopeFromContext(ctx); ok {
+		query = query.Where("tenant_id = ?", scope.TenantId)
+	}
+	return query
+}
@@ -0,0 +1,207 @@
+package dal
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer and the meter of the database clients.
+const instrumentationName = "github.com/PeerIslands/aci-fx-go/service/dal"
+
+const (
+	rowsAffectedKey = attribute.Key("db.rows_affected")
+	rowsReturnedKey = attribute.Key("db.rows_returned")
+)
+
+// hiddenMongoFields are the fields the driver adds to every command, they say nothing of
+// the statement.
+var hiddenMongoFields = map[string]bool{
+	"lsid": true, "$clusterTime": true, "$db": true, "txnNumber": true, "$readPreference": true,
+}
+
+type mongoCommand struct {
+	connection string
+	request    int64
+}
+
+// CommandMonitor returns a command monitor of the Mongo client running every command
+// within a client span, parented to the span of the context the command was sent with.
+func CommandMonitor() *event.CommandMonitor {
+	var spans sync.Map
+	finish := func(connection string, request int64) trace.Span {
+		span, ok := spans.LoadAndDelete(mongoCommand{connection, request})
+		if !ok {
+			return nil
+		}
+		return span.(trace.Span)
+	}
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBName(evt.DatabaseName),
+				semconv.DBOperation(evt.CommandName),
+				semconv.DBStatement(mongoStatement(evt.Command)),
+			}
+			name := evt.CommandName + " " + evt.DatabaseName
+			if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+				attrs = append(attrs, semconv.DBMongoDBCollection(collection))
+				name += "." + collection
+			}
+			_, span := otel.Tracer(instrumentationName).Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			spans.Store(mongoCommand{evt.ConnectionID, evt.RequestID}, span)
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			span := finish(evt.ConnectionID, evt.RequestID)
+			if span == nil {
+				return
+			}
+			span.SetAttributes(mongoRows(evt.Reply)...)
+			span.End()
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			span := finish(evt.ConnectionID, evt.RequestID)
+			if span == nil {
+				return
+			}
+			span.SetStatus(codes.Error, evt.Failure)
+			span.End()
+		},
+	}
+}
+
+// mongoStatement returns the command as extended JSON with the values replaced by ?, so
+// no data of the tenants ends up in the traces. The name of the collection is kept.
+func mongoStatement(command bson.Raw) string {
+	statement, err := bson.MarshalExtJSON(sanitizeMongoDocument(command, true), false, false)
+	if err != nil {
+		return ""
+	}
+	return string(statement)
+}
+
+func sanitizeMongoDocument(document bson.Raw, command bool) bson.D {
+	elements, _ := document.Elements()
+	sanitized := make(bson.D, 0, len(elements))
+	for i, element := range elements {
+		key := element.Key()
+		if hiddenMongoFields[key] {
+			continue
+		}
+		if command && i == 0 {
+			sanitized = append(sanitized, bson.E{Key: key, Value: element.Value()})
+			continue
+		}
+		sanitized = append(sanitized, bson.E{Key: key, Value: sanitizeMongoValue(element.Value())})
+	}
+	return sanitized
+}
+
+// sanitizeMongoValue keeps the shape of documents, arrays are collapsed to their first
+// value as inserts and $in filters can hold any number of them.
+func sanitizeMongoValue(value bson.RawValue) any {
+	switch value.Type {
+	case bson.TypeEmbeddedDocument:
+		return sanitizeMongoDocument(value.Document(), false)
+	case bson.TypeArray:
+		values, _ := value.Array().Values()
+		if len(values) == 0 {
+			return bson.A{}
+		}
+		return bson.A{sanitizeMongoValue(values[0])}
+	default:
+		return "?"
+	}
+}
+
+// mongoRows returns the number of documents written or returned by a command, from its
+// reply.
+func mongoRows(reply bson.Raw) []attribute.KeyValue {
+	if n, ok := reply.Lookup("n").AsInt64OK(); ok {
+		return []attribute.KeyValue{rowsAffectedKey.Int64(n)}
+	}
+	if n, ok := reply.Lookup("lastErrorObject", "n").AsInt64OK(); ok {
+		return []attribute.KeyValue{rowsAffectedKey.Int64(n)}
+	}
+	if batch, ok := reply.Lookup("cursor", "firstBatch").ArrayOK(); ok {
+		values, _ := batch.Values()
+		return []attribute.KeyValue{rowsReturnedKey.Int(len(values))}
+	}
+	return nil
+}
+
+// QueryHook runs every query of the go-pg client within a client span, parented to the
+// span of the context the query was made with.
+type QueryHook struct {
+	// Database is the name of the database the client is connected to
+	Database string
+}
+
+var _ pg.QueryHook = QueryHook{}
+
+func (h QueryHook) BeforeQuery(ctx context.Context, evt *pg.QueryEvent) (context.Context, error) {
+	// the unformatted query keeps the placeholders instead of the values
+	query, _ := evt.UnformattedQuery()
+	operation, table := queryTarget(evt.Query, string(query))
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemPostgreSQL,
+		semconv.DBName(h.Database),
+		semconv.DBOperation(operation),
+		semconv.DBStatement(string(query)),
+	}
+	name := operation
+	if table != "" {
+		attrs = append(attrs, semconv.DBSQLTable(table))
+		name += " " + table
+	}
+	if name == "" {
+		name = h.Database
+	}
+	ctx, _ = otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, nil
+}
+
+func (h QueryHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) error {
+	span := trace.SpanFromContext(ctx)
+	if evt.Err != nil {
+		span.RecordError(evt.Err)
+		span.SetStatus(codes.Error, evt.Err.Error())
+	} else if evt.Result != nil {
+		query, _ := evt.UnformattedQuery()
+		if operation, _ := queryTarget(evt.Query, string(query)); operation == string(orm.SelectOp) {
+			span.SetAttributes(rowsReturnedKey.Int(evt.Result.RowsReturned()))
+		} else {
+			span.SetAttributes(rowsAffectedKey.Int(evt.Result.RowsAffected()))
+		}
+	}
+	span.End()
+	return nil
+}
+
+// queryTarget returns the operation of a query and the table it is made on, the table
+// is only known for queries built with the orm.
+func queryTarget(query any, statement string) (string, string) {
+	command, ok := query.(orm.QueryCommand)
+	if !ok {
+		operation, _, _ := strings.Cut(strings.TrimSpace(statement), " ")
+		return strings.ToUpper(operation), ""
+	}
+	var table string
+	if model := command.Query().TableModel(); model != nil {
+		table = strings.Trim(string(model.Table().SQLName), `"`)
+	}
+	return string(command.Operation()), table
+}
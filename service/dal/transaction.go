@@ -0,0 +1,85 @@
+package dal
+
+import (
+	"context"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/gofiber/fiber/v2/log"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs work in a single database transaction. The services called with the
+// context passed to fn take part in the transaction, which is committed when fn returns
+// nil and rolled back otherwise. fn may be run again when the transaction is retried.
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+func GetTransactor(config *config.Config) Transactor {
+
+	if config == nil {
+		log.Fatal("No configuration found")
+		return nil
+	}
+
+	if config.Db.Mongo.Url != "" {
+		getMongoDatabase(config)
+		return &MongoTransactor{}
+	}
+
+	if config.Db.Yugabyte.Address != "" {
+		return &YugaByteTransactor{YbDB: getYugabyteDatabase(config)}
+	}
+
+	log.Fatal("No database configuration found")
+	return nil
+}
+
+// MongoTransactor runs transactions in a mongo session, which needs a replica set.
+type MongoTransactor struct {
+}
+
+func (m *MongoTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	return database.Client().UseSession(ctx, func(session mongo.SessionContext) error {
+		_, err := session.WithTransaction(session, func(txCtx mongo.SessionContext) (any, error) {
+			return nil, fn(txCtx)
+		})
+		return err
+	})
+}
+
+type YugaByteTransactor struct {
+	YbDB *pg.DB
+}
+
+type txKey struct{}
+
+func (y *YugaByteTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*pg.Tx); ok {
+		return fn(ctx)
+	}
+	return y.YbDB.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction of the context, or db outside a transaction.
+func conn(ctx context.Context, db *pg.DB) orm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*pg.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// runInTx runs fn in the transaction of the context, or in a new one of db.
+func runInTx(ctx context.Context, db *pg.DB, fn func(tx *pg.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*pg.Tx); ok {
+		return fn(tx)
+	}
+	return db.RunInTransaction(ctx, fn)
+}
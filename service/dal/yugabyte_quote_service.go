@@ -0,0 +1,55 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/go-pg/pg/v10"
+)
+
+type YugaByteQuoteService struct {
+	YbDB *pg.DB
+}
+
+func (y *YugaByteQuoteService) CreateQuote(ctx context.Context, quote entity.Quote) (entity.Quote, error) {
+	if err := checkTenant(ctx, quote); err != nil {
+		return quote, err
+	}
+	_, err := y.YbDB.ModelContext(ctx, &quote).Insert()
+	if err != nil {
+		return quote, err
+	}
+	return quote, nil
+}
+
+func (y *YugaByteQuoteService) GetQuote(ctx context.Context, id string) (entity.Quote, error) {
+	var quote entity.Quote
+	err := scopeQuery(ctx, y.YbDB.ModelContext(ctx, &quote).Where("id = ?", id)).First()
+	if errors.Is(err, pg.ErrNoRows) {
+		return quote, ErrNoRecord
+	}
+	return quote, err
+}
+
+func (y *YugaByteQuoteService) RedeemQuote(ctx context.Context, id string, redeemedBy string, now time.Time) (entity.Quote, error) {
+	var quote entity.Quote
+	query := scopeQuery(ctx, y.YbDB.ModelContext(ctx, &quote))
+	result, err := query.
+		Set("status = ?", entity.QuoteStatusRedeemed).
+		Set("redeemed_at = ?", now).
+		Set("redeemed_by = ?", redeemedBy).
+		Where("id = ?", id).
+		Where("status = ?", entity.QuoteStatusIssued).
+		Where("expires_at > ?", now).
+		Returning("*").
+		Update()
+	if err != nil {
+		return quote, err
+	}
+	if result.RowsAffected() == 0 {
+		return quote, ErrNoRecord
+	}
+	return quote, nil
+}
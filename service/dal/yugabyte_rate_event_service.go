@@ -0,0 +1,62 @@
+package dal
+
+import (
+	"context"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/go-pg/pg/v10"
+)
+
+type YugaByteRateEventService struct {
+	YbDB *pg.DB
+}
+
+func (y *YugaByteRateEventService) AddRateEvents(ctx context.Context, events []entity.RateEvent) error {
+	for _, event := range events {
+		if err := checkTenant(ctx, event); err != nil {
+			return err
+		}
+	}
+	_, err := conn(ctx, y.YbDB).ModelContext(ctx, &events).Insert()
+	return err
+}
+
+func (y *YugaByteRateEventService) GetPendingRateEvents(ctx context.Context, limit int) ([]entity.RateEvent, error) {
+	var events []entity.RateEvent
+	err := scopeQuery(ctx, y.YbDB.ModelContext(ctx, &events)).
+		Where("published_at IS NULL").
+		Order("occurred_at", "id").
+		Limit(limit).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (y *YugaByteRateEventService) MarkRateEventPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	var event entity.RateEvent
+	result, err := scopeQuery(ctx, y.YbDB.ModelContext(ctx, &event)).
+		Set("published_at = ?", publishedAt).
+		Where("id = ?", id).
+		Update()
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+func (y *YugaByteRateEventService) DeletePublishedRateEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	var event entity.RateEvent
+	result, err := scopeQuery(ctx, y.YbDB.ModelContext(ctx, &event)).
+		Where("published_at < ?", publishedBefore).
+		Delete()
+	if err != nil {
+		return 0, err
+	}
+	return int64(result.RowsAffected()), nil
+}
@@ -0,0 +1,106 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/go-pg/pg/v10"
+)
+
+// rateLimitCleanupInterval is how often expired rate limit state is deleted from yugabyte
+const rateLimitCleanupInterval = 10 * time.Minute
+
+type YugaByteRateLimitService struct {
+	YbDB *pg.DB
+}
+
+// DeleteExpired removes the buckets and usage counters that expired before now.
+func (y *YugaByteRateLimitService) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	for _, model := range []any{(*entity.RateLimitBucket)(nil), (*entity.RateLimitUsage)(nil)} {
+		result, err := y.YbDB.ModelContext(ctx, model).Where("expires_at < ?", now).Delete()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += int64(result.RowsAffected())
+	}
+	return deleted, nil
+}
+
+// startCleanup deletes the expired rate limit state every interval until the connections
+// are closed.
+func (y *YugaByteRateLimitService) startCleanup(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if _, err := y.DeleteExpired(ctx, now); err != nil && ctx.Err() == nil {
+					common.Logger.Errorf("Error in deleting expired rate limits. Exception:%v", err)
+				}
+			}
+		}
+	}()
+	track(func(closeCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-closeCtx.Done():
+			return closeCtx.Err()
+		}
+	})
+}
+
+// UpdateBucket locks the bucket row for the duration of the update.
+func (y *YugaByteRateLimitService) UpdateBucket(ctx context.Context, key string,
+	update func(entity.RateLimitBucket) entity.RateLimitBucket) (entity.RateLimitBucket, error) {
+	var bucket entity.RateLimitBucket
+	err := y.YbDB.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		current := entity.RateLimitBucket{Key: key}
+		err := tx.ModelContext(ctx, &current).Where("key = ?", key).For("UPDATE").Select()
+		if err != nil && !errors.Is(err, pg.ErrNoRows) {
+			return err
+		}
+
+		bucket = update(current)
+		bucket.Key = key
+		_, err = tx.ModelContext(ctx, &bucket).
+			OnConflict("(key) DO UPDATE").
+			Set("tokens = EXCLUDED.tokens").
+			Set("updated_at = EXCLUDED.updated_at").
+			Set("expires_at = EXCLUDED.expires_at").
+			Insert()
+		return err
+	})
+	if err != nil {
+		return bucket, err
+	}
+	return bucket, nil
+}
+
+func (y *YugaByteRateLimitService) IncrementUsage(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error) {
+	usage := entity.RateLimitUsage{Key: key, Count: 1, ExpiresAt: expiresAt}
+	result, err := y.YbDB.ModelContext(ctx, &usage).
+		OnConflict("(key) DO UPDATE").
+		Set("count = ?TableAlias.count + 1").
+		Where("?TableAlias.count < ?", limit).
+		Returning("count").
+		Insert()
+	if errors.Is(err, pg.ErrNoRows) || (err == nil && result.RowsAffected() == 0) {
+		return limit, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return usage.Count, true, nil
+}
@@ -3,8 +3,11 @@ package dal
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/service/common"
 	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
 	"log"
 	"strconv"
 )
@@ -27,111 +30,186 @@ func (y *YugaByteDbService[T]) Init(credentials ...string) {
 		//	InsecureSkipVerify: true,
 		//},
 	})
+	ybDB.AddQueryHook(QueryHook{Database: credentials[2]})
 
 	// Check the connection
 	ctx := context.Background()
 	if err := ybDB.Ping(ctx); err != nil {
 		log.Fatal("Unable to connect to database:", err)
 	}
-	log.Println("Connected to database", credentials[2], "on", credentials[3], "with pool size", poolSize)
+	common.Logger.Infof("Connected to database %s on %s with pool size %d", credentials[2], credentials[3], poolSize)
 	y.YbDB = ybDB
+	db := ybDB
+	track(func(context.Context) error { return db.Close() })
 }
 
 func (y *YugaByteDbService[T]) getDatabase() *pg.DB {
 	return ybDB
 }
 
-func (y *YugaByteDbService[T]) GetOne(filter any) (T, error) {
+func (y *YugaByteDbService[T]) GetOne(ctx context.Context, filter any) (T, error) {
 	var fxRequest = filter.(request.FxDataRequest)
 	var data T
-	err := y.YbDB.Model(&data).
+	err := scopeQuery(ctx, conn(ctx, y.YbDB).ModelContext(ctx, &data)).
 		Where("tenant_id = ?", fxRequest.TenantId).
 		Where("bank_id = ?", fxRequest.BankId).
 		Where("base_currency = ?", fxRequest.BaseCurrency).
 		Where("target_currency = ?", fxRequest.TargetCurrency).
 		Where("tier = ?", fxRequest.Tier).First()
+	if errors.Is(err, pg.ErrNoRows) {
+		return data, ErrNoRecord
+	}
 	if err != nil {
 		return data, err
 	}
 	return data, nil
 }
-func (y *YugaByteDbService[T]) GetOneById(id int) (T, error) {
+func (y *YugaByteDbService[T]) GetOneById(ctx context.Context, id int) (T, error) {
 
 	var data T
-	err := y.YbDB.Model(&data).
+	err := scopeQuery(ctx, conn(ctx, y.YbDB).ModelContext(ctx, &data)).
 		Where("id = ?", id).
 		First()
+	if errors.Is(err, pg.ErrNoRows) {
+		return data, ErrNoRecord
+	}
 	if err != nil {
 		return data, err
 	}
 	return data, nil
 }
 
-func (y *YugaByteDbService[T]) CreateOne(record T) (T, error) {
-	_, err := y.YbDB.Model(&record).Insert()
+func (y *YugaByteDbService[T]) CreateOne(ctx context.Context, record T) (T, error) {
+	if err := checkTenant(ctx, record); err != nil {
+		return record, err
+	}
+	_, err := conn(ctx, y.YbDB).ModelContext(ctx, &record).Insert()
 	if err != nil {
 		return record, err
 	}
 	return record, nil
 }
 
-func (y *YugaByteDbService[T]) UpdateOne(record any, filter any) (any, error) {
+func (y *YugaByteDbService[T]) UpdateOne(ctx context.Context, document any, filter any) (any, error) {
 	var fxRequest = filter.(request.FxDataRequest)
+	return y.update(ctx, document, func(query *orm.Query) *orm.Query {
+		return query.
+			Where("tenant_id = ?", fxRequest.TenantId).
+			Where("bank_id = ?", fxRequest.BankId).
+			Where("base_currency = ?", fxRequest.BaseCurrency).
+			Where("target_currency = ?", fxRequest.TargetCurrency).
+			Where("tier = ?", fxRequest.Tier)
+	})
+}
 
-	var rec T
-	_, err := y.YbDB.Model(&rec).
-		Set("buy_rate = buy_rate + ?", 0.001).
-		Where("tenant_id = ?", fxRequest.TenantId).
-		Where("bank_id = ?", fxRequest.BankId).
-		Where("base_currency = ?", fxRequest.BaseCurrency).
-		Where("target_currency = ?", fxRequest.TargetCurrency).
-		Where("tier = ?", fxRequest.Tier).
+func (y *YugaByteDbService[T]) UpdateOneById(ctx context.Context, document any, id any) (any, error) {
+	var rowId = id.(int)
+	return y.update(ctx, document, func(query *orm.Query) *orm.Query {
+		return query.Where("id = ?", rowId)
+	})
+}
+
+// update writes the record to the row selected by where and bumps its doc_version.
+func (y *YugaByteDbService[T]) update(ctx context.Context, document any, where func(query *orm.Query) *orm.Query) (T, error) {
+	record, ok := document.(T)
+	if !ok {
+		return record, fmt.Errorf("cannot update %T records with %T", record, document)
+	}
+	if err := checkTenant(ctx, record); err != nil {
+		return record, err
+	}
+	result, err := where(scopeQuery(ctx, conn(ctx, y.YbDB).ModelContext(ctx, &record))).
+		ExcludeColumn("id", "created_date", "created_by").
+		Value("doc_version", "doc_version + 1").
 		Returning("*").
 		Update()
 	if err != nil {
 		return record, err
 	}
+	if result.RowsAffected() == 0 {
+		return record, ErrNoRecord
+	}
 	return record, nil
 }
 
-func (y *YugaByteDbService[T]) UpdateOneById(id any) (any, error) {
-	var rowId = id.(int)
+func (y *YugaByteDbService[T]) UpsertOne(ctx context.Context, record T, filter any) (T, error) {
+	if err := checkTenant(ctx, record); err != nil {
+		return record, err
+	}
+	var fxRequest = filter.(request.FxDataRequest)
 
-	var rec T
-	_, err := y.YbDB.Model(&rec).
-		Set("buy_rate = buy_rate + ?", 0.001).
-		Where("id = ?", rowId).
-		Returning("*").
-		Update()
+	// a missing threshold keeps the stored one
+	excluded := []string{"id", "created_date", "created_by"}
+	if keepsThreshold(record) {
+		excluded = append(excluded, "contract_requirement_threshold")
+	}
+	err := runInTx(ctx, y.YbDB, func(tx *pg.Tx) error {
+		result, err := scopeQuery(ctx, tx.ModelContext(ctx, &record)).
+			ExcludeColumn(excluded...).
+			Value("doc_version", "doc_version + 1").
+			Where("tenant_id = ?", fxRequest.TenantId).
+			Where("bank_id = ?", fxRequest.BankId).
+			Where("base_currency = ?", fxRequest.BaseCurrency).
+			Where("target_currency = ?", fxRequest.TargetCurrency).
+			Where("tier = ?", fxRequest.Tier).
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() > 0 {
+			return nil
+		}
+		_, err = tx.ModelContext(ctx, &record).Insert()
+		return err
+	})
 	if err != nil {
-		return rec, err
+		return record, err
 	}
-	return rec, nil
+	return record, nil
 }
 
-func (y *YugaByteDbService[T]) DeleteOne(id any) (int64, error) {
-	var data []T
-	result, err := y.YbDB.Model(&data).Where("id = ?", id.(string)).Delete()
+func (y *YugaByteDbService[T]) DeleteOne(ctx context.Context, id any) (T, error) {
+	var data T
+	result, err := scopeQuery(ctx, conn(ctx, y.YbDB).ModelContext(ctx, &data)).Where("id = ?", id.(string)).Returning("*").Delete()
 	if err != nil {
-		return 0, err
+		return data, err
 	}
 	if result.RowsAffected() == 0 {
-		return 0, errors.New("no record found")
+		return data, ErrNoRecord
 	}
-	return 1, nil
+	return data, nil
 }
 
-func (y *YugaByteDbService[T]) Get(filter any) ([]T, error) {
+func (y *YugaByteDbService[T]) Get(ctx context.Context, filter any) ([]T, error) {
 	var data []T
-	err := y.YbDB.Model(&data).Where(filter.(string)).Select()
+	query := scopeQuery(ctx, y.YbDB.ModelContext(ctx, &data))
+	if fxRequest, ok := filter.(request.FxDataRequest); ok {
+		query = query.
+			Where("tenant_id = ?", fxRequest.TenantId).
+			Where("bank_id = ?", fxRequest.BankId).
+			Where("base_currency = ?", fxRequest.BaseCurrency).
+			Where("target_currency = ?", fxRequest.TargetCurrency)
+		if fxRequest.Tier != "" {
+			query = query.Where("tier = ?", fxRequest.Tier)
+		}
+	} else {
+		query = query.Where(filter.(string))
+	}
+	err := query.Select()
 	if err != nil {
 		return data, err
 	}
 	return data, nil
 }
 
-func (y *YugaByteDbService[T]) BulkInsert(documents []T) (T, error) {
-	_, err := y.YbDB.Model(&documents).Insert()
+func (y *YugaByteDbService[T]) BulkInsert(ctx context.Context, documents []T) (T, error) {
+	for _, document := range documents {
+		if err := checkTenant(ctx, document); err != nil {
+			return document, err
+		}
+	}
+	_, err := conn(ctx, y.YbDB).ModelContext(ctx, &documents).Insert()
 	if err != nil {
 		return documents[0], err
 	}
@@ -0,0 +1,80 @@
+package dal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/go-pg/pg/v10"
+)
+
+type YugaByteTenantService struct {
+	YbDB *pg.DB
+}
+
+func (y *YugaByteTenantService) CreateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error) {
+	if err := checkTenant(ctx, tenant); err != nil {
+		return tenant, err
+	}
+	result, err := y.YbDB.ModelContext(ctx, &tenant).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		return tenant, err
+	}
+	if result.RowsAffected() == 0 {
+		return tenant, ErrDuplicateRecord
+	}
+	return tenant, nil
+}
+
+func (y *YugaByteTenantService) GetTenant(ctx context.Context, tenantId int) (entity.TenantConfig, error) {
+	var tenant entity.TenantConfig
+	err := scopeTenantQuery(ctx, y.YbDB.ModelContext(ctx, &tenant)).Where("tenant_id = ?", tenantId).First()
+	if errors.Is(err, pg.ErrNoRows) {
+		return tenant, ErrNoRecord
+	}
+	return tenant, err
+}
+
+func (y *YugaByteTenantService) GetTenants(ctx context.Context) ([]entity.TenantConfig, error) {
+	var tenants []entity.TenantConfig
+	err := scopeTenantQuery(ctx, y.YbDB.ModelContext(ctx, &tenants)).Order("tenant_id").Select()
+	if err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func (y *YugaByteTenantService) UpdateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error) {
+	if err := checkTenant(ctx, tenant); err != nil {
+		return tenant, err
+	}
+	version := tenant.DocVersion
+	tenant.DocVersion++
+	result, err := y.YbDB.ModelContext(ctx, &tenant).
+		ExcludeColumn("created_date", "created_by").
+		Where("tenant_id = ?tenant_id").
+		Where("doc_version = ?", version).
+		Update()
+	if err != nil {
+		return tenant, err
+	}
+	if result.RowsAffected() == 0 {
+		if _, err = y.GetTenant(ctx, tenant.TenantID); err != nil {
+			return tenant, err
+		}
+		return tenant, ErrVersionConflict
+	}
+	return tenant, nil
+}
+
+func (y *YugaByteTenantService) DeleteTenant(ctx context.Context, tenantId int) error {
+	var tenant entity.TenantConfig
+	result, err := scopeTenantQuery(ctx, y.YbDB.ModelContext(ctx, &tenant)).Where("tenant_id = ?", tenantId).Delete()
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const ecbBaseCurrency = "EUR"
+
+// ecbEnvelope mirrors the eurofxref feeds published by the ECB. The daily, 90 day and
+// historical files share the same layout and only differ in the number of days.
+type ecbEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Cube    struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// ParseECB parses an ECB eurofxref style feed and returns the most recent day it contains.
+func ParseECB(r io.Reader) (*ReferenceRates, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	var latest *ReferenceRates
+	for _, day := range envelope.Cube.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return nil, err
+		}
+		if latest != nil && !date.After(latest.Date) {
+			continue
+		}
+		rates := make(map[string]float64, len(day.Rates))
+		for _, rate := range day.Rates {
+			if rate.Currency == "" || rate.Rate <= 0 {
+				continue
+			}
+			rates[strings.ToUpper(rate.Currency)] = rate.Rate
+		}
+		latest = &ReferenceRates{BaseCurrency: ecbBaseCurrency, Date: date, Rates: rates}
+	}
+
+	if latest == nil || len(latest.Rates) == 0 {
+		return nil, errors.New("feed does not contain any reference rates")
+	}
+	return latest, nil
+}
@@ -0,0 +1,96 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+)
+
+// Ingester periodically loads a reference rate feed and upserts the derived rates.
+type Ingester struct {
+	FxService *bal.Fx_service
+	Source    string
+	Parser    Parser
+	Targets   []Target
+	Interval  time.Duration
+}
+
+// NewIngester builds an ingester from the ingest section of the configuration.
+func NewIngester(fxConfig *config.Config, fxService *bal.Fx_service) (*Ingester, error) {
+	parser, err := GetParser(fxConfig.Ingest.Format)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := time.ParseDuration(fxConfig.Ingest.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ingest interval: %w", err)
+	}
+	targets, err := LoadTargets(fxConfig.Ingest.TargetsFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load ingest targets: %w", err)
+	}
+	return &Ingester{
+		FxService: fxService,
+		Source:    fxConfig.Ingest.Source,
+		Parser:    parser,
+		Targets:   targets,
+		Interval:  interval,
+	}, nil
+}
+
+// RunOnce fetches the feed a single time and writes the rates of every target.
+func (i *Ingester) RunOnce(ctx context.Context) error {
+	reader, err := OpenSource(ctx, i.Source)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	rates, err := i.Parser(reader)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", i.Source, err)
+	}
+
+	ctx = common.WithActor(ctx, "ingest:"+i.Source)
+	failed := 0
+	requests := ToForexRequests(rates, i.Targets)
+	for _, forexRequest := range requests {
+		if result := i.FxService.UpsertForexData(&ctx, forexRequest); result.Status != response.Success {
+			failed++
+		}
+	}
+	common.Logger.Infof("Ingested %d reference rates of %s from %s, %d failed",
+		len(requests)-failed, rates.Date.Format(time.DateOnly), i.Source, failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rates could not be saved", failed, len(requests))
+	}
+	return nil
+}
+
+// Start runs the ingestion immediately and then on every interval until the context is done.
+// The returned channel is closed once the ingestion has stopped.
+func (i *Ingester) Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(i.Interval)
+		defer ticker.Stop()
+		for {
+			if err := i.RunOnce(ctx); err != nil {
+				common.Logger.Errorf("Reference rate ingestion failed. Exception:%v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
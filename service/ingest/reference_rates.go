@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReferenceRates is a single day of central bank reference rates quoted against one base currency.
+type ReferenceRates struct {
+	BaseCurrency string
+	Date         time.Time
+	// Rates holds the number of units of each currency per one unit of BaseCurrency
+	Rates map[string]float64
+}
+
+// Parser reads a reference rate feed in a specific format.
+type Parser func(r io.Reader) (*ReferenceRates, error)
+
+var parsers = map[string]Parser{
+	"ecb": ParseECB,
+}
+
+// GetParser returns the parser registered for the given feed format.
+func GetParser(format string) (Parser, error) {
+	parser, ok := parsers[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference rate format %q", format)
+	}
+	return parser, nil
+}
+
+// OpenSource opens a feed from a local file path or an http(s) URL.
+func OpenSource(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("fetching %s returned %s", source, resp.Status)
+	}
+	return &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,95 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+)
+
+// Target describes which tenant and bank receive the reference rates and how each tier is priced.
+type Target struct {
+	TenantId int `json:"tenantId"`
+	BankId   int `json:"bankId"`
+	// Currencies restricts the feed to the listed currencies, all currencies are used when empty
+	Currencies []string `json:"currencies,omitempty"`
+	// IncludeInverse also stores the inverted pair, e.g. USD/EUR next to EUR/USD
+	IncludeInverse bool         `json:"includeInverse"`
+	Tiers          []TierMarkup `json:"tiers"`
+}
+
+// TierMarkup is the markup applied on both sides of the reference rate for a tier.
+type TierMarkup struct {
+	Tier             string  `json:"tier"`
+	MarkupPercentage float64 `json:"markupPercentage"`
+}
+
+// LoadTargets reads the list of ingestion targets from a JSON file.
+func LoadTargets(path string) ([]Target, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	if err := json.Unmarshal(content, &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// ToForexRequests maps reference rates to the forex records of every target and tier.
+func ToForexRequests(rates *ReferenceRates, targets []Target) []request.CreateForexDataRequest {
+	var requests []request.CreateForexDataRequest
+	effectiveDate := rates.Date
+
+	for _, target := range targets {
+		for _, currency := range target.currencies(rates) {
+			mid := rates.Rates[currency]
+			for _, tier := range target.Tiers {
+				requests = append(requests, newForexRequest(target, tier, rates.BaseCurrency, currency, mid, &effectiveDate))
+				if target.IncludeInverse {
+					requests = append(requests, newForexRequest(target, tier, currency, rates.BaseCurrency, 1/mid, &effectiveDate))
+				}
+			}
+		}
+	}
+	return requests
+}
+
+func (t Target) currencies(rates *ReferenceRates) []string {
+	var currencies []string
+	if len(t.Currencies) == 0 {
+		for currency := range rates.Rates {
+			currencies = append(currencies, currency)
+		}
+	} else {
+		for _, currency := range t.Currencies {
+			currency = strings.ToUpper(currency)
+			if _, ok := rates.Rates[currency]; ok {
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+func newForexRequest(target Target, tier TierMarkup, baseCurrency string, targetCurrency string, mid float64,
+	effectiveDate *time.Time) request.CreateForexDataRequest {
+	markup := tier.MarkupPercentage / 100
+	return request.CreateForexDataRequest{
+		TenantId:       target.TenantId,
+		BankId:         target.BankId,
+		BaseCurrency:   baseCurrency,
+		TargetCurrency: targetCurrency,
+		Tier:           tier.Tier,
+		Multiplier:     1,
+		BuyRate:        common.RoundRate(mid * (1 - markup)),
+		SellRate:       common.RoundRate(mid * (1 + markup)),
+		EffectiveDate:  effectiveDate,
+	}
+}
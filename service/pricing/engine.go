@@ -0,0 +1,134 @@
+package pricing
+
+import (
+	"log"
+	"sync"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+)
+
+// Price is the result of applying a rule to a base rate.
+type Price struct {
+	RuleId    string
+	MidRate   float64
+	SpreadBps float64
+	BuyRate   float64
+	SellRate  float64
+	// Band is the amount band that decided the spread, nil when the rule spread was used
+	Band *Band
+}
+
+// Engine holds the active pricing rules of each tenant, the rules of tenant 0 are global
+// and apply to every tenant. Tier rates are derived from the record stored under BaseTier,
+// whose buy and sell rates are averaged to the mid rate.
+type Engine struct {
+	BaseTier string
+
+	mutex sync.RWMutex
+	rules map[int][]Rule
+}
+
+// NewEngine creates an engine with a validated set of rules, each rule belongs to the
+// rules of its tenant.
+func NewEngine(baseTier string, rules []Rule) (*Engine, error) {
+	byTenant := map[int][]Rule{}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		byTenant[rule.TenantId] = append(byTenant[rule.TenantId], rule)
+	}
+	return &Engine{BaseTier: baseTier, rules: byTenant}, nil
+}
+
+// GetEngine creates the engine from the pricing section of the configuration. Without a rules
+// file the engine starts empty and the stored tier rates are used until rules are set.
+func GetEngine(fxConfig *config.Config) *Engine {
+	var rules []Rule
+	if fxConfig.Pricing.RulesFile != "" {
+		var err error
+		if rules, err = LoadRules(fxConfig.Pricing.RulesFile); err != nil {
+			log.Fatal("Unable to load pricing rules:", err)
+		}
+	}
+	engine, err := NewEngine(fxConfig.Pricing.BaseTier, rules)
+	if err != nil {
+		log.Fatal("Invalid pricing rules:", err)
+	}
+	return engine
+}
+
+// Rules returns a copy of the active rules of the tenant, tenant 0 for the global rules.
+func (e *Engine) Rules(tenantId int) []Rule {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return append([]Rule(nil), e.rules[tenantId]...)
+}
+
+// SetRules validates and replaces the active rules of the tenant, tenant 0 for the global
+// rules. Rules without a tenant are stored for the tenant, rules naming another tenant fail
+// with common.ErrCrossTenant.
+func (e *Engine) SetRules(tenantId int, rules []Rule) error {
+	tenantRules := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if rule.TenantId != 0 && rule.TenantId != tenantId {
+			return common.ErrCrossTenant
+		}
+		rule.TenantId = tenantId
+		tenantRules = append(tenantRules, rule)
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(tenantRules) == 0 {
+		delete(e.rules, tenantId)
+	} else {
+		e.rules[tenantId] = tenantRules
+	}
+	return nil
+}
+
+// Match returns the most specific rule for the conversion among the global rules and the
+// rules of the tenant. Among equally specific rules the one listed first wins, global rules
+// are listed before the rules of the tenant.
+func (e *Engine) Match(tenantId int, bankId int, baseCurrency string, targetCurrency string, tier string) (Rule, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	candidates := e.rules[0]
+	if tenantId != 0 {
+		candidates = append(append([]Rule(nil), candidates...), e.rules[tenantId]...)
+	}
+	var best Rule
+	found := false
+	for _, rule := range candidates {
+		if !rule.matches(tenantId, bankId, baseCurrency, targetCurrency, tier) {
+			continue
+		}
+		if !found || rule.specificity() > best.specificity() {
+			best = rule
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Apply prices an amount with the rule, using the band containing the amount if any.
+func (e *Engine) Apply(rule Rule, midRate float64, amount float64) Price {
+	price := Price{RuleId: rule.Id, MidRate: midRate, SpreadBps: spreadBps(rule.SpreadBps, rule.SpreadPercent)}
+	for i := range rule.Bands {
+		if rule.Bands[i].contains(amount) {
+			band := rule.Bands[i]
+			price.Band = &band
+			price.SpreadBps = spreadBps(band.SpreadBps, band.SpreadPercent)
+			break
+		}
+	}
+	spread := price.SpreadBps / 10000
+	price.BuyRate = common.RoundRate(midRate * (1 - spread))
+	price.SellRate = common.RoundRate(midRate * (1 + spread))
+	return price
+}
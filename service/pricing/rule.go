@@ -0,0 +1,102 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rule derives the customer rates of a tier from the base rate. Zero values of TenantId and
+// BankId and empty currencies or tier act as wildcards.
+type Rule struct {
+	Id             string  `json:"id"`
+	TenantId       int     `json:"tenantId,omitempty"`
+	BankId         int     `json:"bankId,omitempty"`
+	BaseCurrency   string  `json:"baseCurrency,omitempty"`
+	TargetCurrency string  `json:"targetCurrency,omitempty"`
+	Tier           string  `json:"tier,omitempty"`
+	SpreadBps      float64 `json:"spreadBps,omitempty"`
+	SpreadPercent  float64 `json:"spreadPercent,omitempty"`
+	// Bands override the spread of the rule for conversions within an amount range
+	Bands []Band `json:"bands,omitempty"`
+}
+
+// Band is a spread that applies to amounts from MinAmount up to, but excluding, MaxAmount.
+// A MaxAmount of zero leaves the band open ended.
+type Band struct {
+	MinAmount     float64 `json:"minAmount"`
+	MaxAmount     float64 `json:"maxAmount,omitempty"`
+	SpreadBps     float64 `json:"spreadBps,omitempty"`
+	SpreadPercent float64 `json:"spreadPercent,omitempty"`
+}
+
+// LoadRules reads a list of rules from a JSON file.
+func LoadRules(path string) ([]Rule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(content, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Validate checks that the rule has an id and its spreads and bands are usable.
+func (r Rule) Validate() error {
+	if r.Id == "" {
+		return fmt.Errorf("pricing rule must have an id")
+	}
+	if spread := spreadBps(r.SpreadBps, r.SpreadPercent); spread < 0 || spread >= 10000 {
+		return fmt.Errorf("pricing rule %s has an invalid spread", r.Id)
+	}
+	for _, band := range r.Bands {
+		if band.MinAmount < 0 || (band.MaxAmount != 0 && band.MaxAmount <= band.MinAmount) {
+			return fmt.Errorf("pricing rule %s has an invalid band %v-%v", r.Id, band.MinAmount, band.MaxAmount)
+		}
+		if spread := spreadBps(band.SpreadBps, band.SpreadPercent); spread < 0 || spread >= 10000 {
+			return fmt.Errorf("pricing rule %s has an invalid band spread", r.Id)
+		}
+	}
+	return nil
+}
+
+func (r Rule) matches(tenantId int, bankId int, baseCurrency string, targetCurrency string, tier string) bool {
+	return (r.TenantId == 0 || r.TenantId == tenantId) &&
+		(r.BankId == 0 || r.BankId == bankId) &&
+		(r.BaseCurrency == "" || strings.EqualFold(r.BaseCurrency, baseCurrency)) &&
+		(r.TargetCurrency == "" || strings.EqualFold(r.TargetCurrency, targetCurrency)) &&
+		(r.Tier == "" || r.Tier == tier)
+}
+
+// specificity ranks rules so that a tenant specific rule always wins over a bank or pair
+// specific one, and so on down to the tier.
+func (r Rule) specificity() int {
+	score := 0
+	if r.TenantId != 0 {
+		score += 16
+	}
+	if r.BankId != 0 {
+		score += 8
+	}
+	if r.BaseCurrency != "" {
+		score += 4
+	}
+	if r.TargetCurrency != "" {
+		score += 2
+	}
+	if r.Tier != "" {
+		score++
+	}
+	return score
+}
+
+func (b Band) contains(amount float64) bool {
+	return amount >= b.MinAmount && (b.MaxAmount == 0 || amount < b.MaxAmount)
+}
+
+func spreadBps(bps float64, percent float64) float64 {
+	return bps + percent*100
+}
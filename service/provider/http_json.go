@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPJSONProvider reads rates from a JSON http feed. Each distinct url is fetched once per
+// refresh and the rate of every pair is looked up with a dotted path such as "rates.{target}".
+type HTTPJSONProvider struct {
+	ProviderName  string
+	Url           string
+	RatePath      string
+	TimestampPath string
+	Headers       map[string]string
+	Client        *http.Client
+}
+
+func (p *HTTPJSONProvider) Name() string {
+	return p.ProviderName
+}
+
+func (p *HTTPJSONProvider) FetchRates(ctx context.Context, pairs []Pair) ([]Rate, error) {
+	documents := map[string]any{}
+	var rates []Rate
+	for _, pair := range pairs {
+		url := expand(p.Url, pair)
+		document, ok := documents[url]
+		if !ok {
+			var err error
+			if document, err = p.fetch(ctx, url); err != nil {
+				return rates, err
+			}
+			documents[url] = document
+		}
+
+		value, ok := lookup(document, expand(p.RatePath, pair))
+		if !ok {
+			continue
+		}
+		mid, err := toFloat(value)
+		if err != nil || mid <= 0 {
+			continue
+		}
+		timestamp := time.Now()
+		if p.TimestampPath != "" {
+			if value, ok := lookup(document, expand(p.TimestampPath, pair)); ok {
+				timestamp = toTime(value, timestamp)
+			}
+		}
+		rates = append(rates, Rate{Pair: pair, Mid: mid, Timestamp: timestamp})
+	}
+	return rates, nil
+}
+
+func (p *HTTPJSONProvider) fetch(ctx context.Context, url string) (any, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider %s returned %s", p.ProviderName, resp.Status)
+	}
+	var document any
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func expand(template string, pair Pair) string {
+	return strings.NewReplacer("{base}", pair.BaseCurrency, "{target}", pair.TargetCurrency).Replace(template)
+}
+
+func lookup(document any, path string) (any, bool) {
+	current := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func toFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("rate %v is not a number", value)
+}
+
+func toTime(value any, fallback time.Time) time.Time {
+	switch v := value.(type) {
+	case float64:
+		return time.Unix(int64(v), 0)
+	case string:
+		if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Pair is a currency pair quoted by a provider.
+type Pair struct {
+	BaseCurrency   string `json:"baseCurrency"`
+	TargetCurrency string `json:"targetCurrency"`
+}
+
+func (p Pair) String() string {
+	return p.BaseCurrency + "/" + p.TargetCurrency
+}
+
+// Rate is a market mid rate for a pair as reported by a provider.
+type Rate struct {
+	Pair
+	Mid       float64
+	Timestamp time.Time
+}
+
+// RateProvider fetches market rates for a set of currency pairs. Pairs the provider
+// has no quote for are left out of the result.
+type RateProvider interface {
+	Name() string
+	FetchRates(ctx context.Context, pairs []Pair) ([]Rate, error)
+}
+
+// Settings is the content of the providers file.
+type Settings struct {
+	Interval  string       `json:"interval"`
+	Providers []Definition `json:"providers"`
+}
+
+// Definition configures one provider, the pairs it refreshes and where the rates are written.
+type Definition struct {
+	Name string `json:"name"`
+	// Type is either "http-json" or "file"
+	Type string `json:"type"`
+	// Url and RatePath may contain {base} and {target} placeholders
+	Url           string            `json:"url,omitempty"`
+	RatePath      string            `json:"ratePath,omitempty"`
+	TimestampPath string            `json:"timestampPath,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Path          string            `json:"path,omitempty"`
+	StaleAfter    string            `json:"staleAfter,omitempty"`
+	Pairs         []Pair            `json:"pairs"`
+	Targets       []Target          `json:"targets"`
+}
+
+// Target is a tenant and bank that receives the rates of a provider.
+type Target struct {
+	TenantId int          `json:"tenantId"`
+	BankId   int          `json:"bankId"`
+	Tiers    []TierSpread `json:"tiers"`
+}
+
+// TierSpread is the spread in basis points applied on each side of the mid rate for a tier.
+type TierSpread struct {
+	Tier      string  `json:"tier"`
+	SpreadBps float64 `json:"spreadBps"`
+}
+
+// LoadSettings reads the provider settings from a JSON file.
+func LoadSettings(path string) (*Settings, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var settings Settings
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// NewProvider creates the adapter matching the type of the definition.
+func NewProvider(definition Definition) (RateProvider, error) {
+	switch definition.Type {
+	case "http-json":
+		return &HTTPJSONProvider{
+			ProviderName:  definition.Name,
+			Url:           definition.Url,
+			RatePath:      definition.RatePath,
+			TimestampPath: definition.TimestampPath,
+			Headers:       definition.Headers,
+		}, nil
+	case "file":
+		return &FileProvider{ProviderName: definition.Name, Path: definition.Path}, nil
+	}
+	return nil, fmt.Errorf("unsupported provider type %q for provider %s", definition.Type, definition.Name)
+}
@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+)
+
+const defaultStaleAfter = 15 * time.Minute
+
+// Status is the refresh state of a single provider.
+type Status struct {
+	Provider     string
+	LastAttempt  time.Time
+	LastSuccess  time.Time
+	LastError    string
+	RatesUpdated int
+	StaleAfter   time.Duration
+}
+
+// Stale reports whether the provider has not delivered rates within its staleness window.
+func (s Status) Stale(now time.Time) bool {
+	return s.LastSuccess.IsZero() || now.Sub(s.LastSuccess) > s.StaleAfter
+}
+
+type source struct {
+	provider RateProvider
+	pairs    []Pair
+	targets  []Target
+	status   Status
+}
+
+// Refresher pulls rates from the configured providers on an interval and writes the
+// tier rates derived from them through the forex service.
+type Refresher struct {
+	FxService *bal.Fx_service
+	Interval  time.Duration
+
+	mutex   sync.RWMutex
+	sources []*source
+}
+
+var (
+	activeRefresher *Refresher
+	activeMutex     sync.RWMutex
+)
+
+// NewRefresher creates the providers described by the settings.
+func NewRefresher(settings *Settings, fxService *bal.Fx_service) (*Refresher, error) {
+	interval, err := time.ParseDuration(settings.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider refresh interval: %w", err)
+	}
+	refresher := &Refresher{FxService: fxService, Interval: interval}
+	for _, definition := range settings.Providers {
+		rateProvider, err := NewProvider(definition)
+		if err != nil {
+			return nil, err
+		}
+		staleAfter := defaultStaleAfter
+		if definition.StaleAfter != "" {
+			if staleAfter, err = time.ParseDuration(definition.StaleAfter); err != nil {
+				return nil, fmt.Errorf("invalid staleAfter for provider %s: %w", definition.Name, err)
+			}
+		}
+		refresher.sources = append(refresher.sources, &source{
+			provider: rateProvider,
+			pairs:    definition.Pairs,
+			targets:  definition.Targets,
+			status:   Status{Provider: rateProvider.Name(), StaleAfter: staleAfter},
+		})
+	}
+	return refresher, nil
+}
+
+// RefreshOnce refreshes every provider a single time.
+func (r *Refresher) RefreshOnce(ctx context.Context) {
+	for _, src := range r.sources {
+		updated, err := r.refresh(ctx, src)
+
+		r.mutex.Lock()
+		src.status.LastAttempt = time.Now()
+		if err != nil {
+			src.status.LastError = err.Error()
+		} else {
+			src.status.LastError = ""
+			src.status.LastSuccess = src.status.LastAttempt
+			src.status.RatesUpdated = updated
+		}
+		status := src.status
+		r.mutex.Unlock()
+
+		if err != nil {
+			common.Logger.Errorf("Refreshing rates from provider %s failed. Exception:%v", status.Provider, err)
+		}
+		if status.Stale(time.Now()) {
+			common.Logger.Warnf("Rates from provider %s are stale, last success at %v", status.Provider, status.LastSuccess)
+		}
+	}
+}
+
+func (r *Refresher) refresh(ctx context.Context, src *source) (int, error) {
+	rates, err := src.provider.FetchRates(ctx, src.pairs)
+	if err != nil {
+		return 0, err
+	}
+	if len(rates) < len(src.pairs) {
+		common.Logger.Warnf("Provider %s returned %d of %d pairs", src.provider.Name(), len(rates), len(src.pairs))
+	}
+
+	ctx = common.WithActor(ctx, "provider:"+src.provider.Name())
+	updated, failed := 0, 0
+	for _, rate := range rates {
+		for _, forexRequest := range toForexRequests(rate, src.targets) {
+			if result := r.FxService.UpsertForexData(&ctx, forexRequest); result.Status != response.Success {
+				failed++
+				continue
+			}
+			updated++
+		}
+	}
+	if failed > 0 {
+		return updated, fmt.Errorf("%d rates could not be saved", failed)
+	}
+	return updated, nil
+}
+
+func toForexRequests(rate Rate, targets []Target) []request.CreateForexDataRequest {
+	var requests []request.CreateForexDataRequest
+	effectiveDate := rate.Timestamp
+	for _, target := range targets {
+		for _, tier := range target.Tiers {
+			spread := tier.SpreadBps / 10000
+			requests = append(requests, request.CreateForexDataRequest{
+				TenantId:       target.TenantId,
+				BankId:         target.BankId,
+				BaseCurrency:   rate.BaseCurrency,
+				TargetCurrency: rate.TargetCurrency,
+				Tier:           tier.Tier,
+				Multiplier:     1,
+				BuyRate:        common.RoundRate(rate.Mid * (1 - spread)),
+				SellRate:       common.RoundRate(rate.Mid * (1 + spread)),
+				EffectiveDate:  &effectiveDate,
+			})
+		}
+	}
+	return requests
+}
+
+// Start refreshes immediately and then on every interval until the context is done.
+// The refresher becomes the one reported by Statuses. The returned channel is closed once
+// the refresher has stopped.
+func (r *Refresher) Start(ctx context.Context) <-chan struct{} {
+	activeMutex.Lock()
+	activeRefresher = r
+	activeMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		for {
+			r.RefreshOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// Statuses returns the state of every provider of the refresher.
+func (r *Refresher) Statuses() []Status {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	statuses := make([]Status, 0, len(r.sources))
+	for _, src := range r.sources {
+		statuses = append(statuses, src.status)
+	}
+	return statuses
+}
+
+// Statuses returns the provider states of the running refresher, if any.
+func Statuses() []Status {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+	if activeRefresher == nil {
+		return nil
+	}
+	return activeRefresher.Statuses()
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/auth"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	StoreMemory   = "memory"
+	StoreDatabase = "database"
+)
+
+// Decision is the outcome of a request against its limit.
+type Decision struct {
+	Allowed bool
+	// QuotaExceeded is set when the request was denied by the daily quota
+	QuotaExceeded bool
+	Limit         Limit
+	Remaining     int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a denied request can be retried
+	RetryAfter time.Duration
+}
+
+// Limiter enforces the rate limits and daily quotas of the settings. Buckets are kept per
+// tenant, caller and route, quotas per tenant and route.
+type Limiter struct {
+	Settings Settings
+	Store    dal.RateLimitDBService
+}
+
+func NewLimiter(settings Settings, store dal.RateLimitDBService) *Limiter {
+	return &Limiter{Settings: settings, Store: store}
+}
+
+// Allow takes a request of the caller from its bucket and counts it against the daily
+// quota of the tenant.
+func (l *Limiter) Allow(ctx context.Context, tenantId int, caller string, route string, now time.Time) (Decision, error) {
+	limit := l.Settings.LimitFor(tenantId, route)
+	decision := Decision{Allowed: true, Limit: limit}
+
+	if limit.RequestsPerMinute > 0 {
+		key := fmt.Sprintf("%d|%s|%s", tenantId, caller, route)
+		var allowed bool
+		bucket, err := l.Store.UpdateBucket(ctx, key, func(bucket entity.RateLimitBucket) entity.RateLimitBucket {
+			bucket, allowed = take(bucket, limit, now)
+			return bucket
+		})
+		if err != nil {
+			return decision, err
+		}
+		decision.Allowed = allowed
+		decision.Remaining = int(bucket.Tokens)
+		decision.Reset = limit.durationFor(limit.capacity() - bucket.Tokens)
+		if !allowed {
+			decision.RetryAfter = limit.durationFor(1 - bucket.Tokens)
+			recordDecision(ctx, tenantId, route, outcomeLimited)
+			return decision, nil
+		}
+	}
+
+	if limit.DailyQuota > 0 {
+		day := now.UTC().Truncate(24 * time.Hour)
+		key := fmt.Sprintf("%d|%s|%s", tenantId, route, day.Format(time.DateOnly))
+		used, allowed, err := l.Store.IncrementUsage(ctx, key, limit.DailyQuota, day.Add(48*time.Hour))
+		if err != nil {
+			return decision, err
+		}
+		recordQuotaUsage(tenantId, route, day, used, limit.DailyQuota)
+		if !allowed {
+			decision.Allowed = false
+			decision.QuotaExceeded = true
+			decision.RetryAfter = day.Add(24 * time.Hour).Sub(now)
+			recordDecision(ctx, tenantId, route, outcomeQuotaExceeded)
+			return decision, nil
+		}
+	}
+
+	recordDecision(ctx, tenantId, route, outcomeAllowed)
+	return decision, nil
+}
+
+// take refills the bucket for the time passed since its last update and takes a token
+// from it when one is available.
+func take(bucket entity.RateLimitBucket, limit Limit, now time.Time) (entity.RateLimitBucket, bool) {
+	capacity := limit.capacity()
+	if bucket.UpdatedAt.IsZero() {
+		bucket.Tokens = capacity
+	} else if elapsed := now.Sub(bucket.UpdatedAt).Seconds(); elapsed > 0 {
+		bucket.Tokens = math.Min(capacity, bucket.Tokens+elapsed*limit.ratePerSecond())
+	}
+	allowed := bucket.Tokens >= 1
+	if allowed {
+		bucket.Tokens--
+	}
+	bucket.UpdatedAt = now
+	bucket.ExpiresAt = now.Add(limit.durationFor(capacity - bucket.Tokens))
+	return bucket, allowed
+}
+
+// Handler limits the route it is attached to. The RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers describe the bucket of the caller, denied requests get a 429 with
+// Retry-After. Requests are let through when the state store fails.
+func (l *Limiter) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tenantId, caller := identify(c)
+		endpoint := c.Method() + " " + c.Route().Path
+		decision, err := l.Allow(c.UserContext(), tenantId, caller, c.Route().Path, time.Now())
+		if err != nil {
+			common.Log(c.UserContext()).Warnf("Rate limit of %s for tenant %d is not enforced. Exception:%v", endpoint, tenantId, err)
+			return c.Next()
+		}
+
+		if decision.Limit.RequestsPerMinute > 0 {
+			c.Set("RateLimit-Limit", strconv.Itoa(int(decision.Limit.capacity())))
+			c.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			c.Set("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset)))
+		}
+		if decision.Allowed {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds(decision.RetryAfter)))
+		e := &[]response.Error{
+			{Code: "RATE_LIMITED", Message: "Too many requests", Details: fmt.Sprintf("The rate limit of %s was exceeded", endpoint)},
+		}
+		if decision.QuotaExceeded {
+			e = &[]response.Error{
+				{Code: "QUOTA_EXCEEDED", Message: "Daily quota exceeded", Details: fmt.Sprintf("The daily quota of %d requests to %s was used up", decision.Limit.DailyQuota, endpoint)},
+			}
+		}
+		return c.Status(fiber.StatusTooManyRequests).JSON(common.GetSimpleResponse[response.ForexDataResponse](nil, response.TooManyRequests, e))
+	}
+}
+
+// identify returns the tenant and caller a request is accounted to. Unauthenticated
+// requests are accounted to their address.
+func identify(c *fiber.Ctx) (int, string) {
+	principal := auth.GetPrincipal(c)
+	if principal == nil {
+		return 0, c.IP()
+	}
+	tenantId := principal.TenantId
+	if scope, ok := common.TenantScopeFromContext(c.UserContext()); ok {
+		tenantId = scope.TenantId
+	}
+	return tenantId, principal.Method + ":" + principal.Subject
+}
+
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
+
+// GetLimiter creates the limiter of the rate limits file of the configuration. Without a
+// file no request is limited.
+func GetLimiter(fxConfig *config.Config) *Limiter {
+	var settings Settings
+	if fxConfig.RateLimit.File != "" {
+		var err error
+		if settings, err = LoadSettings(fxConfig.RateLimit.File); err != nil {
+			log.Fatal("Unable to load rate limits:", err)
+		}
+	}
+
+	switch fxConfig.RateLimit.Store {
+	case "", StoreMemory:
+		return NewLimiter(settings, NewMemoryStore())
+	case StoreDatabase:
+		return NewLimiter(settings, dal.GetRateLimitAccess(fxConfig))
+	}
+	log.Fatalf("Unsupported rate limit store %q", fxConfig.RateLimit.Store)
+	return nil
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+)
+
+// MemoryStore keeps the rate limit state of a single instance.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	buckets map[string]entity.RateLimitBucket
+	usage   map[string]entity.RateLimitUsage
+	evicted time.Time
+}
+
+// evictionInterval is how often expired state is dropped
+const evictionInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]entity.RateLimitBucket{},
+		usage:   map[string]entity.RateLimitUsage{},
+	}
+}
+
+func (m *MemoryStore) UpdateBucket(ctx context.Context, key string,
+	update func(entity.RateLimitBucket) entity.RateLimitBucket) (entity.RateLimitBucket, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	current, ok := m.buckets[key]
+	if !ok {
+		current = entity.RateLimitBucket{Key: key}
+	}
+	bucket := update(current)
+	bucket.Key = key
+	m.buckets[key] = bucket
+	m.evict(bucket.UpdatedAt)
+	return bucket, nil
+}
+
+func (m *MemoryStore) IncrementUsage(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	usage, ok := m.usage[key]
+	if !ok {
+		usage = entity.RateLimitUsage{Key: key, ExpiresAt: expiresAt}
+	}
+	if usage.Count >= limit {
+		return usage.Count, false, nil
+	}
+	usage.Count++
+	m.usage[key] = usage
+	return usage.Count, true, nil
+}
+
+// evict drops buckets that are full again and counters of past quota periods, so idle
+// callers do not hold memory.
+func (m *MemoryStore) evict(now time.Time) {
+	if now.Sub(m.evicted) < evictionInterval {
+		return
+	}
+	m.evicted = now
+	for key, bucket := range m.buckets {
+		if bucket.ExpiresAt.Before(now) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, usage := range m.usage {
+		if usage.ExpiresAt.Before(now) {
+			delete(m.usage, key)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	outcomeAllowed       = "allowed"
+	outcomeLimited       = "limited"
+	outcomeQuotaExceeded = "quota_exceeded"
+)
+
+var meter = otel.Meter("github.com/PeerIslands/aci-fx-go/service/ratelimit")
+
+var decisions, _ = meter.Int64Counter("fx.ratelimit.decisions",
+	metric.WithDescription("Requests checked against rate limits and quotas by outcome"))
+
+// quotaUsage holds the latest usage of every quota of the current day for the gauges.
+var quotaUsage = struct {
+	sync.Mutex
+	entries map[string]quotaEntry
+}{entries: map[string]quotaEntry{}}
+
+type quotaEntry struct {
+	tenantId int
+	route    string
+	day      time.Time
+	used     int64
+	limit    int64
+}
+
+func init() {
+	used, _ := meter.Int64ObservableGauge("fx.quota.daily.used",
+		metric.WithDescription("Requests counted against the daily quota of a tenant and route"))
+	limit, _ := meter.Int64ObservableGauge("fx.quota.daily.limit",
+		metric.WithDescription("Daily quota of a tenant and route"))
+	_, _ = meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		quotaUsage.Lock()
+		defer quotaUsage.Unlock()
+		for key, entry := range quotaUsage.entries {
+			if entry.day.Before(today) {
+				delete(quotaUsage.entries, key)
+				continue
+			}
+			attributes := metric.WithAttributes(tenantAttribute(entry.tenantId), attribute.String("route", entry.route))
+			observer.ObserveInt64(used, entry.used, attributes)
+			observer.ObserveInt64(limit, entry.limit, attributes)
+		}
+		return nil
+	}, used, limit)
+}
+
+func recordDecision(ctx context.Context, tenantId int, route string, outcome string) {
+	decisions.Add(ctx, 1, metric.WithAttributes(
+		tenantAttribute(tenantId),
+		attribute.String("route", route),
+		attribute.String("outcome", outcome),
+	))
+}
+
+func recordQuotaUsage(tenantId int, route string, day time.Time, used int64, limit int64) {
+	quotaUsage.Lock()
+	defer quotaUsage.Unlock()
+	key := strconv.Itoa(tenantId) + "|" + route
+	if entry, ok := quotaUsage.entries[key]; ok && entry.day.Equal(day) && entry.used > used {
+		return
+	}
+	quotaUsage.entries[key] = quotaEntry{tenantId: tenantId, route: route, day: day, used: used, limit: limit}
+}
+
+func tenantAttribute(tenantId int) attribute.KeyValue {
+	return attribute.String("tenant", strconv.Itoa(tenantId))
+}
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Limit is a token bucket refilled at RequestsPerMinute that holds up to Burst requests,
+// along with the number of requests allowed per UTC day. Zero values leave the rate or
+// the day unlimited, in a rule they are taken from the less specific rules.
+type Limit struct {
+	RequestsPerMinute float64 `json:"requestsPerMinute,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+	DailyQuota        int64   `json:"dailyQuota,omitempty"`
+}
+
+// Rule overrides the default limit for a tenant, a route or both. Zero TenantId and an
+// empty Route act as wildcards. Routes are the templates they are registered with, such
+// as /api/quotes/:id.
+type Rule struct {
+	TenantId int    `json:"tenantId,omitempty"`
+	Route    string `json:"route,omitempty"`
+	Limit
+}
+
+// Settings is the content of the rate limits file.
+type Settings struct {
+	Default Limit  `json:"default"`
+	Rules   []Rule `json:"rules,omitempty"`
+}
+
+// LoadSettings reads the rate limits from a JSON file.
+func LoadSettings(path string) (Settings, error) {
+	var settings Settings
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return settings, err
+	}
+	if err = json.Unmarshal(content, &settings); err != nil {
+		return settings, err
+	}
+	return settings, settings.Validate()
+}
+
+// Validate checks that no limit is negative.
+func (s Settings) Validate() error {
+	if err := s.Default.validate(); err != nil {
+		return fmt.Errorf("default limit: %w", err)
+	}
+	for _, rule := range s.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("limit of tenant %d route %q: %w", rule.TenantId, rule.Route, err)
+		}
+	}
+	return nil
+}
+
+func (l Limit) validate() error {
+	if l.RequestsPerMinute < 0 || l.Burst < 0 || l.DailyQuota < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+// LimitFor returns the limit of the rules matching the tenant and route. A rule for both
+// wins over a rule for the route, which wins over a rule for the tenant, and the fields a
+// rule leaves unset are taken from the less specific rules and then the default. Among
+// equally specific rules the one listed first wins.
+func (s Settings) LimitFor(tenantId int, route string) Limit {
+	var matching []Rule
+	for _, rule := range s.Rules {
+		if (rule.TenantId != 0 && rule.TenantId != tenantId) || (rule.Route != "" && rule.Route != route) {
+			continue
+		}
+		matching = append(matching, rule)
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].specificity() > matching[j].specificity()
+	})
+
+	var limit Limit
+	for _, rule := range matching {
+		limit = limit.orElse(rule.Limit)
+	}
+	return limit.orElse(s.Default)
+}
+
+func (r Rule) specificity() int {
+	specificity := 1
+	if r.Route != "" {
+		specificity += 2
+	}
+	if r.TenantId != 0 {
+		specificity++
+	}
+	return specificity
+}
+
+// orElse fills the unset fields of the limit from another one.
+func (l Limit) orElse(other Limit) Limit {
+	if l.RequestsPerMinute == 0 {
+		l.RequestsPerMinute = other.RequestsPerMinute
+	}
+	if l.Burst == 0 {
+		l.Burst = other.Burst
+	}
+	if l.DailyQuota == 0 {
+		l.DailyQuota = other.DailyQuota
+	}
+	return l
+}
+
+// ratePerSecond is the refill rate of the bucket.
+func (l Limit) ratePerSecond() float64 {
+	return l.RequestsPerMinute / 60
+}
+
+// capacity is the burst of the limit, a second worth of requests when it is not set.
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.ratePerSecond()))
+}
+
+// durationFor is the time it takes to refill the given number of tokens.
+func (l Limit) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.ratePerSecond() * float64(time.Second))
+}
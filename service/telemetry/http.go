@@ -0,0 +1,151 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the scope of the spans and metrics of the package, the service
+// they come from is told by the resource of the providers.
+const instrumentationName = "github.com/PeerIslands/aci-fx-go/service/telemetry"
+
+var meter = otel.Meter(instrumentationName)
+
+// DurationBoundaries are the bucket boundaries of the histograms of durations, which are
+// recorded in seconds.
+var DurationBoundaries = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// requestDuration counts requests and their errors along with their duration, by route.
+var requestDuration, _ = meter.Float64Histogram("http.server.request.duration",
+	metric.WithDescription("Duration of the requests served by route and outcome"),
+	metric.WithUnit("s"),
+	metric.WithExplicitBucketBoundaries(DurationBoundaries...))
+
+// requestCarrier reads the W3C trace context and baggage from the request headers.
+type requestCarrier struct {
+	c *fiber.Ctx
+}
+
+func (r requestCarrier) Get(key string) string {
+	return utils.CopyString(r.c.Get(key))
+}
+
+func (r requestCarrier) Set(key string, value string) {
+	r.c.Request().Header.Set(key, value)
+}
+
+func (r requestCarrier) Keys() []string {
+	var keys []string
+	r.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// Middleware runs every request within a server span, continuing the trace of the caller
+// found in the request headers. The span is named after the route template, e.g.
+// GET /api/quotes/:id, and handlers find it in c.UserContext().
+//
+// Errors returned by the handlers are recorded on the span and handed to the error
+// handler of the app, so the span holds the status code actually sent.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), requestCarrier{c})
+		// the strings of the request are reused once it is done, the span outlives it
+		method := utils.CopyString(c.Method())
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(utils.CopyString(c.Path())),
+				semconv.URLScheme(utils.CopyString(c.Protocol())),
+				semconv.ServerAddress(utils.CopyString(c.Hostname())),
+				semconv.ClientAddress(utils.CopyString(c.IP())),
+				semconv.UserAgentOriginal(utils.CopyString(c.Get(fiber.HeaderUserAgent))),
+				semconv.HTTPRequestBodySize(len(c.Request().Body())),
+			))
+		defer span.End()
+		requestRoute := common.NewRequestRoute(func() string { return utils.CopyString(Route(c)) })
+		c.SetUserContext(common.WithRoute(ctx, requestRoute))
+		// the request is released even when a handler panics
+		defer requestRoute.Freeze()
+
+		err := c.Next()
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		route := Route(c)
+		if route != "" {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(
+			semconv.HTTPResponseStatusCode(status),
+			semconv.HTTPResponseBodySize(len(c.Response().Body())),
+		)
+		// the api answers 200 and carries the outcome in the status of the envelope
+		envelope := envelopeStatus(c)
+		if envelope != "" {
+			span.SetAttributes(attribute.String("fx.response.status", string(envelope)))
+		}
+		if err == nil && (status >= fiber.StatusInternalServerError || envelope == response.InternalError) {
+			span.SetStatus(codes.Error, string(envelope))
+		}
+
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+			attribute.String("fx.response.status", string(envelope)),
+		}
+		if err != nil {
+			attrs = append(attrs, attribute.String("error.type", fmt.Sprintf("%T", err)))
+		}
+		requestDuration.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(attrs...))
+		return nil
+	}
+}
+
+func envelopeStatus(c *fiber.Ctx) response.StatusCode {
+	if !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return ""
+	}
+	var envelope struct {
+		Status response.StatusCode `json:"status"`
+	}
+	if err := json.Unmarshal(c.Response().Body(), &envelope); err != nil {
+		return ""
+	}
+	return envelope.Status
+}
+
+// Route returns the template of the route that handled the request, empty when no route
+// matched it.
+func Route(c *fiber.Ctx) string {
+	route := c.Route()
+	// middlewares match every path with their prefix, / when added by app.Use
+	if route == nil || (route.Path == "/" && c.Path() != "/") {
+		return ""
+	}
+	return route.Path
+}
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log"
+)
+
+var severities = map[logrus.Level]log.Severity{
+	logrus.PanicLevel: log.SeverityFatal2,
+	logrus.FatalLevel: log.SeverityFatal,
+	logrus.ErrorLevel: log.SeverityError,
+	logrus.WarnLevel:  log.SeverityWarn,
+	logrus.InfoLevel:  log.SeverityInfo,
+	logrus.DebugLevel: log.SeverityDebug,
+	logrus.TraceLevel: log.SeverityTrace,
+}
+
+// logHook sends the logs through the OTel logs pipeline along with the traces, each log
+// is correlated with the span of the context it is logged with.
+type logHook struct {
+	logger log.Logger
+}
+
+// LogHook returns the hook of the logger sending its logs to the provider.
+func LogHook(provider log.LoggerProvider) logrus.Hook {
+	return &logHook{logger: provider.Logger(instrumentationName)}
+}
+
+func (h *logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logHook) Fire(entry *logrus.Entry) error {
+	var record log.Record
+	record.SetTimestamp(entry.Time)
+	record.SetSeverity(severities[entry.Level])
+	record.SetSeverityText(entry.Level.String())
+	record.SetBody(log.StringValue(entry.Message))
+	for key, value := range entry.Data {
+		// the record has the trace and the span of the context
+		if key == "trace_id" || key == "span_id" {
+			continue
+		}
+		record.AddAttributes(log.KeyValue{Key: key, Value: logValue(value)})
+	}
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h.logger.Emit(ctx, record)
+	return nil
+}
+
+// ErrorHandler logs the errors of the telemetry pipelines with the output, format and
+// level of logger but without its hooks, as a log export that fails would otherwise be
+// exported again.
+func ErrorHandler(logger *logrus.Logger) otel.ErrorHandler {
+	return otel.ErrorHandlerFunc(func(err error) {
+		unhooked := &logrus.Logger{
+			Out:       logger.Out,
+			Formatter: logger.Formatter,
+			Hooks:     logrus.LevelHooks{},
+			Level:     logger.GetLevel(),
+			ExitFunc:  logger.ExitFunc,
+		}
+		unhooked.Errorf("Error in exporting telemetry. Exception:%v", err)
+	})
+}
+
+func logValue(value any) log.Value {
+	switch value := value.(type) {
+	case string:
+		return log.StringValue(value)
+	case int:
+		return log.IntValue(value)
+	case int64:
+		return log.Int64Value(value)
+	case float64:
+		return log.Float64Value(value)
+	case bool:
+		return log.BoolValue(value)
+	default:
+		return log.StringValue(fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,260 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	ExporterOtlpGrpc = "otlp-grpc"
+	ExporterOtlpHttp = "otlp-http"
+	ExporterStdout   = "stdout"
+	ExporterNone     = "none"
+)
+
+// exporterSettings are the settings of the otlp exporters, they fall back to the
+// OTEL_EXPORTER_OTLP_* variables for what is not configured.
+type exporterSettings struct {
+	endpoint string
+	insecure bool
+	tls      *tls.Config
+	headers  map[string]string
+}
+
+func newExporterSettings(fxConfig *config.Config) (exporterSettings, error) {
+	settings := exporterSettings{endpoint: fxConfig.Telemetry.Endpoint}
+	insecure, err := strconv.ParseBool(fxConfig.Telemetry.Insecure)
+	if err != nil {
+		return settings, fmt.Errorf("telemetry insecure %q: %w", fxConfig.Telemetry.Insecure, err)
+	}
+	settings.insecure = insecure
+	if fxConfig.Telemetry.CaFile != "" {
+		pem, err := os.ReadFile(fxConfig.Telemetry.CaFile)
+		if err != nil {
+			return settings, fmt.Errorf("telemetry ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return settings, fmt.Errorf("telemetry ca file %s holds no certificate", fxConfig.Telemetry.CaFile)
+		}
+		settings.tls = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	if fxConfig.Telemetry.Headers != "" {
+		settings.headers = map[string]string{}
+		for _, header := range strings.Split(fxConfig.Telemetry.Headers, ",") {
+			key, value, ok := strings.Cut(header, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return settings, fmt.Errorf("telemetry header %q is not key=value", header)
+			}
+			settings.headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return settings, nil
+}
+
+// exporterOptions returns the options of an otlp exporter for the settings, built with
+// the option functions of its package.
+func exporterOptions[O any](settings exporterSettings, endpoint func(string) O, insecure func() O,
+	tlsConfig func(*tls.Config) O, headers func(map[string]string) O) []O {
+	var options []O
+	if settings.endpoint != "" {
+		options = append(options, endpoint(settings.endpoint))
+	}
+	if settings.insecure {
+		options = append(options, insecure())
+	} else if settings.tls != nil {
+		options = append(options, tlsConfig(settings.tls))
+	}
+	if settings.headers != nil {
+		options = append(options, headers(settings.headers))
+	}
+	return options
+}
+
+// grpcTLS adapts the tls option of an otlp grpc exporter to a tls config.
+func grpcTLS[O any](withCredentials func(credentials.TransportCredentials) O) func(*tls.Config) O {
+	return func(config *tls.Config) O {
+		return withCredentials(credentials.NewTLS(config))
+	}
+}
+
+// newExporter creates the exporter of a signal, traces, metrics or logs, with the function
+// of the configured exporter. ok is false with the none exporter.
+func newExporter[E any](fxConfig *config.Config, signal string, otlpGrpc, otlpHttp, stdout func() (E, error)) (exporter E, ok bool, err error) {
+	var create func() (E, error)
+	switch fxConfig.Telemetry.Exporter {
+	case ExporterOtlpGrpc:
+		create = otlpGrpc
+	case ExporterOtlpHttp:
+		create = otlpHttp
+	case ExporterStdout:
+		create = stdout
+	case ExporterNone:
+		return exporter, false, nil
+	default:
+		return exporter, false, fmt.Errorf("unknown telemetry exporter %q, use %s, %s, %s or %s",
+			fxConfig.Telemetry.Exporter, ExporterOtlpGrpc, ExporterOtlpHttp, ExporterStdout, ExporterNone)
+	}
+	if exporter, err = create(); err != nil {
+		return exporter, false, fmt.Errorf("%s %s exporter: %w", fxConfig.Telemetry.Exporter, signal, err)
+	}
+	return exporter, true, nil
+}
+
+// NewResource describes the service the traces and metrics come from. The attributes of
+// OTEL_RESOURCE_ATTRIBUTES are kept unless configured.
+func NewResource(fxConfig *config.Config) (*sdkresource.Resource, error) {
+	attrs := []sdkresource.Option{
+		sdkresource.WithTelemetrySDK(),
+		sdkresource.WithOS(),
+		sdkresource.WithProcess(),
+		sdkresource.WithContainer(),
+		sdkresource.WithHost(),
+		sdkresource.WithFromEnv(),
+		sdkresource.WithAttributes(semconv.ServiceName(fxConfig.Telemetry.ServiceName)),
+	}
+	if fxConfig.Telemetry.ServiceVersion != "" {
+		attrs = append(attrs, sdkresource.WithAttributes(semconv.ServiceVersion(fxConfig.Telemetry.ServiceVersion)))
+	}
+	if fxConfig.Telemetry.Environment != "" {
+		attrs = append(attrs, sdkresource.WithAttributes(semconv.DeploymentEnvironment(fxConfig.Telemetry.Environment)))
+	}
+	resource, err := sdkresource.New(context.Background(), attrs...)
+	// the detectors that fail leave their attributes out
+	if errors.Is(err, sdkresource.ErrPartialResource) {
+		err = nil
+	}
+	return resource, err
+}
+
+// NewTracerProvider returns the tracer provider sampling and exporting the spans as
+// configured. No span leaves the process with the none exporter.
+func NewTracerProvider(fxConfig *config.Config, resource *sdkresource.Resource) (*sdktrace.TracerProvider, error) {
+	ratio, err := strconv.ParseFloat(fxConfig.Telemetry.SampleRatio, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("telemetry sample ratio %q is not between 0 and 1", fxConfig.Telemetry.SampleRatio)
+	}
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource),
+	}
+
+	settings, err := newExporterSettings(fxConfig)
+	if err != nil {
+		return nil, err
+	}
+	exporter, ok, err := newExporter(fxConfig, "trace",
+		func() (sdktrace.SpanExporter, error) {
+			return otlptracegrpc.New(context.Background(), exporterOptions(settings, otlptracegrpc.WithEndpoint,
+				otlptracegrpc.WithInsecure, grpcTLS(otlptracegrpc.WithTLSCredentials), otlptracegrpc.WithHeaders)...)
+		},
+		func() (sdktrace.SpanExporter, error) {
+			return otlptracehttp.New(context.Background(), exporterOptions(settings, otlptracehttp.WithEndpoint,
+				otlptracehttp.WithInsecure, otlptracehttp.WithTLSClientConfig, otlptracehttp.WithHeaders)...)
+		},
+		func() (sdktrace.SpanExporter, error) { return stdouttrace.New() })
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(options...), nil
+}
+
+// NewMeterProvider returns the meter provider exporting the metrics as configured and,
+// when a prometheus address is configured, the server exposing them for scraping.
+func NewMeterProvider(fxConfig *config.Config, resource *sdkresource.Resource) (*sdkmetric.MeterProvider, *http.Server, error) {
+	options := []sdkmetric.Option{sdkmetric.WithResource(resource)}
+
+	settings, err := newExporterSettings(fxConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	exporter, ok, err := newExporter(fxConfig, "metric",
+		func() (sdkmetric.Exporter, error) {
+			return otlpmetricgrpc.New(context.Background(), exporterOptions(settings, otlpmetricgrpc.WithEndpoint,
+				otlpmetricgrpc.WithInsecure, grpcTLS(otlpmetricgrpc.WithTLSCredentials), otlpmetricgrpc.WithHeaders)...)
+		},
+		func() (sdkmetric.Exporter, error) {
+			return otlpmetrichttp.New(context.Background(), exporterOptions(settings, otlpmetrichttp.WithEndpoint,
+				otlpmetrichttp.WithInsecure, otlpmetrichttp.WithTLSClientConfig, otlpmetrichttp.WithHeaders)...)
+		},
+		func() (sdkmetric.Exporter, error) { return stdoutmetric.New() })
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+	}
+
+	var server *http.Server
+	if fxConfig.Metrics.PrometheusAddress != "" {
+		registry := prometheus.NewRegistry()
+		reader, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, nil, fmt.Errorf("prometheus exporter: %w", err)
+		}
+		options = append(options, sdkmetric.WithReader(reader))
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		server = &http.Server{Addr: fxConfig.Metrics.PrometheusAddress, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	}
+	return sdkmetric.NewMeterProvider(options...), server, nil
+}
+
+// NewLoggerProvider returns the logger provider exporting the logs like the traces, the
+// logs are dropped with the none exporter.
+func NewLoggerProvider(fxConfig *config.Config, resource *sdkresource.Resource) (*sdklog.LoggerProvider, error) {
+	options := []sdklog.LoggerProviderOption{sdklog.WithResource(resource)}
+
+	settings, err := newExporterSettings(fxConfig)
+	if err != nil {
+		return nil, err
+	}
+	exporter, ok, err := newExporter(fxConfig, "log",
+		func() (sdklog.Exporter, error) {
+			return otlploggrpc.New(context.Background(), exporterOptions(settings, otlploggrpc.WithEndpoint,
+				otlploggrpc.WithInsecure, grpcTLS(otlploggrpc.WithTLSCredentials), otlploggrpc.WithHeaders)...)
+		},
+		func() (sdklog.Exporter, error) {
+			return otlploghttp.New(context.Background(), exporterOptions(settings, otlploghttp.WithEndpoint,
+				otlploghttp.WithInsecure, otlploghttp.WithTLSClientConfig, otlploghttp.WithHeaders)...)
+		},
+		func() (sdklog.Exporter, error) { return stdoutlog.New() })
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		options = append(options, sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+	}
+	return sdklog.NewLoggerProvider(options...), nil
+}
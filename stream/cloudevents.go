@@ -0,0 +1,217 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Stream messages are CloudEvents 1.0, in structured mode the whole event is the message
+// body, in binary mode the attributes are ce- headers and the body is the event data.
+const (
+	SpecVersion = "1.0"
+
+	ModeStructured = "structured"
+	ModeBinary     = "binary"
+
+	ContentTypeCloudEvents = "application/cloudevents+json"
+	ContentTypeJSON        = "application/json"
+
+	headerContentType = "Content-Type"
+	headerPrefix      = "ce-"
+
+	defaultEventSource = "/aci-fx-go"
+)
+
+// Event types of the stream, the version of their data is part of the dataschema.
+const (
+	// EventRequest asks for a conversion, a quote or the redemption of a quote
+	EventRequest = "fx.request"
+	// EventReply carries the response envelope of a request
+	EventReply = "fx.reply"
+	// EventRateChanged records the creation, update or deletion of a rate
+	EventRateChanged = "fx.rate.changed"
+)
+
+// schemaPrefix starts the dataschema of an event, followed by its type and version,
+// e.g. urn:aci-fx:schema:fx.request:v1. The schemas are served by the api.
+const schemaPrefix = "urn:aci-fx:schema:"
+
+// Event is a CloudEvent. CorrelationID is an extension attribute, the reply to a request
+// carries the id of the request.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewEvent returns an event of the given type and schema version carrying data.
+func NewEvent(eventType string, version int, source string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	if source == "" {
+		source = defaultEventSource
+	}
+	now := time.Now().UTC()
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              primitive.NewObjectID().Hex(),
+		Source:          source,
+		Type:            eventType,
+		Time:            &now,
+		DataContentType: ContentTypeJSON,
+		DataSchema:      SchemaURI(eventType, version),
+		Data:            raw,
+	}, nil
+}
+
+// SchemaURI is the dataschema of the given event type and version.
+func SchemaURI(eventType string, version int) string {
+	return schemaPrefix + eventType + ":v" + strconv.Itoa(version)
+}
+
+// Version is the schema version of the event data. Messages sent before events were
+// introduced, and events without a dataschema, are version 1.
+func (e Event) Version() (int, error) {
+	if e.DataSchema == "" {
+		return 1, nil
+	}
+	i := strings.LastIndex(e.DataSchema, ":v")
+	if !strings.HasPrefix(e.DataSchema, schemaPrefix) || i < 0 {
+		return 0, fmt.Errorf("dataschema %s is unknown", e.DataSchema)
+	}
+	version, err := strconv.Atoi(e.DataSchema[i+2:])
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("dataschema %s has no valid version", e.DataSchema)
+	}
+	return version, nil
+}
+
+// Legacy reports whether the message was bare JSON rather than an event.
+func (e Event) Legacy() bool {
+	return e.SpecVersion == ""
+}
+
+// EncodeEvent writes the event to the message in the given content mode, structured
+// when the mode is empty.
+func EncodeEvent(msg *Msg, event Event, mode string) error {
+	if msg.Header == nil {
+		msg.Header = Header{}
+	}
+	switch mode {
+	case "", ModeStructured:
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		msg.Header.Set(headerContentType, ContentTypeCloudEvents)
+		msg.Data = data
+	case ModeBinary:
+		attributes := map[string]string{
+			"specversion":   event.SpecVersion,
+			"id":            event.ID,
+			"source":        event.Source,
+			"type":          event.Type,
+			"subject":       event.Subject,
+			"dataschema":    event.DataSchema,
+			"correlationid": event.CorrelationID,
+		}
+		if event.Time != nil {
+			attributes["time"] = event.Time.Format(time.RFC3339Nano)
+		}
+		for name, value := range attributes {
+			if value != "" {
+				msg.Header.Set(headerPrefix+name, value)
+			}
+		}
+		if event.DataContentType != "" {
+			msg.Header.Set(headerContentType, event.DataContentType)
+		}
+		msg.Data = event.Data
+	default:
+		return checkEventMode(mode)
+	}
+	return nil
+}
+
+func checkEventMode(mode string) error {
+	if mode != "" && mode != ModeStructured && mode != ModeBinary {
+		return fmt.Errorf("event mode %q must be %s or %s", mode, ModeStructured, ModeBinary)
+	}
+	return nil
+}
+
+// DecodeEvent reads an event in either content mode from the headers and body of a
+// message. A body that is not an event is returned as the data of a legacy event.
+func DecodeEvent(header Header, data []byte) (Event, error) {
+	if header.Get(headerPrefix+"specversion") != "" {
+		return decodeBinary(header, data)
+	}
+
+	var event Event
+	if strings.HasPrefix(header.Get(headerContentType), ContentTypeCloudEvents) {
+		if err := json.Unmarshal(data, &event); err != nil {
+			return event, err
+		}
+		return event, validateEvent(event)
+	}
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return event, err
+	}
+	if probe.SpecVersion == "" {
+		return Event{Data: data}, nil
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return event, err
+	}
+	return event, validateEvent(event)
+}
+
+func decodeBinary(header Header, data []byte) (Event, error) {
+	event := Event{
+		SpecVersion:     header.Get(headerPrefix + "specversion"),
+		ID:              header.Get(headerPrefix + "id"),
+		Source:          header.Get(headerPrefix + "source"),
+		Type:            header.Get(headerPrefix + "type"),
+		Subject:         header.Get(headerPrefix + "subject"),
+		DataContentType: header.Get(headerContentType),
+		DataSchema:      header.Get(headerPrefix + "dataschema"),
+		CorrelationID:   header.Get(headerPrefix + "correlationid"),
+		Data:            data,
+	}
+	if value := header.Get(headerPrefix + "time"); value != "" {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return event, fmt.Errorf("event time: %w", err)
+		}
+		event.Time = &t
+	}
+	return event, validateEvent(event)
+}
+
+func validateEvent(event Event) error {
+	if event.SpecVersion != SpecVersion {
+		return fmt.Errorf("event specversion %s is not supported", event.SpecVersion)
+	}
+	if event.ID == "" || event.Source == "" || event.Type == "" {
+		return errors.New("event id, source and type must be provided")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/segmentio/kafka-go"
+)
+
+// Replayer publishes dead letters back to the subject they were received on.
+type Replayer interface {
+	Replay(ctx context.Context, limit int) (int, error)
+}
+
+// DeadLetters replays the messages kept on the dead letter stream.
+type DeadLetters struct {
+	Url     string
+	Stream  string
+	Subject string
+}
+
+// GetDeadLetters returns the dead letters of the configured broker, nil when no dead
+// letters are kept.
+func GetDeadLetters(fxConfig *config.Config) Replayer {
+	destinations := destinationsOf(fxConfig)
+	if !Configured(fxConfig) || destinations.deadLetter == "" {
+		return nil
+	}
+	if fxConfig.Stream.Transport == TransportKafka {
+		return &KafkaDeadLetters{
+			Config: fxConfig,
+			Group:  destinations.consumer + "-replay",
+		}
+	}
+	if fxConfig.Nats.DeadLetterStream == "" {
+		return nil
+	}
+	return &DeadLetters{
+		Url:     fxConfig.Nats.Url,
+		Stream:  fxConfig.Nats.DeadLetterStream,
+		Subject: fxConfig.Nats.DeadLetterSubject,
+	}
+}
+
+// replayHeaders are the headers of a replayed dead letter, those describing the failure
+// are dropped.
+func replayHeaders(header Header) Header {
+	replay := Header{}
+	for name, values := range header {
+		if !strings.HasPrefix(name, "Fx-") {
+			replay[name] = values
+		}
+	}
+	return replay
+}
+
+// Replay publishes up to limit dead letters back to the subject they were received on,
+// oldest first. Every replayed dead letter is removed from the dead letter stream.
+func (d *DeadLetters) Replay(ctx context.Context, limit int) (int, error) {
+	nc, err := nats.Connect(d.Url)
+	if err != nil {
+		return 0, fmt.Errorf("NATS connection: %w", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return 0, fmt.Errorf("JetStream context: %w", err)
+	}
+	stream, err := js.Stream(ctx, d.Stream)
+	if err != nil {
+		return 0, fmt.Errorf("JetStream dead letter stream %s: %w", d.Stream, err)
+	}
+	cons, err := stream.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{})
+	if err != nil {
+		return 0, fmt.Errorf("JetStream dead letter consumer: %w", err)
+	}
+
+	batch, err := cons.Fetch(limit, jetstream.FetchMaxWait(time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("fetching dead letters: %w", err)
+	}
+	replayed := 0
+	for msg := range batch.Messages() {
+		subject := msg.Headers().Get(HeaderOriginalSubject)
+		if subject == "" {
+			return replayed, fmt.Errorf("dead letter on %s has no %s header", msg.Subject(), HeaderOriginalSubject)
+		}
+		replay := &nats.Msg{Subject: subject, Header: nats.Header(replayHeaders(Header(msg.Headers()))), Data: msg.Data()}
+		if _, err = js.PublishMsg(ctx, replay); err != nil {
+			return replayed, fmt.Errorf("replaying a dead letter to %s: %w", subject, err)
+		}
+		metadata, err := msg.Metadata()
+		if err != nil {
+			return replayed, err
+		}
+		if err = stream.DeleteMsg(ctx, metadata.Sequence.Stream); err != nil {
+			return replayed, fmt.Errorf("removing a replayed dead letter: %w", err)
+		}
+		replayed++
+	}
+	if err = batch.Error(); err != nil && !errors.Is(err, jetstream.ErrNoMessages) && !errors.Is(err, nats.ErrTimeout) {
+		return replayed, err
+	}
+	return replayed, nil
+}
+
+// kafkaReplayWait is how long a replay waits for dead letters, joining the group
+// takes a few seconds.
+const kafkaReplayWait = 5 * time.Second
+
+// KafkaDeadLetters replays the messages kept on the dead letter topic. The replay has
+// its own consumer group, a dead letter is committed once it is replayed.
+type KafkaDeadLetters struct {
+	Config *config.Config
+	Group  string
+}
+
+// Replay publishes up to limit dead letters back to the subject they were received on,
+// oldest first.
+func (d *KafkaDeadLetters) Replay(ctx context.Context, limit int) (int, error) {
+	transport, err := openKafka(d.Config, false)
+	if err != nil {
+		return 0, err
+	}
+	defer transport.Close()
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     strings.Split(d.Config.Kafka.Brokers, ","),
+		GroupID:     d.Group,
+		Topic:       d.Config.Kafka.DeadLetterTopic,
+		StartOffset: kafka.FirstOffset,
+	})
+	defer reader.Close()
+
+	replayed := 0
+	for replayed < limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, kafkaReplayWait)
+		km, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("fetching dead letters: %w", err)
+		}
+		header := kafkaHeader(km)
+		subject := header.Get(HeaderOriginalSubject)
+		if subject == "" {
+			return replayed, fmt.Errorf("dead letter at offset %d has no %s header", km.Offset, HeaderOriginalSubject)
+		}
+		replay := &Msg{Subject: subject, Header: replayHeaders(header), Data: km.Value}
+		if err = transport.Publish(ctx, replay); err != nil {
+			return replayed, fmt.Errorf("replaying a dead letter to %s: %w", subject, err)
+		}
+		if err = reader.CommitMessages(ctx, km); err != nil {
+			return replayed, fmt.Errorf("committing a replayed dead letter: %w", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
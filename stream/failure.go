@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers of a dead letter, describing the message and why it could not be handled.
+const (
+	HeaderOriginalSubject = "Fx-Original-Subject"
+	HeaderStreamSequence  = "Fx-Stream-Sequence"
+	HeaderDeliveries      = "Fx-Deliveries"
+	HeaderErrorClass      = "Fx-Error-Class"
+	HeaderError           = "Fx-Error"
+	HeaderFailedAt        = "Fx-Failed-At"
+)
+
+const (
+	ErrorClassPoison    = "poison"
+	ErrorClassTransient = "transient"
+)
+
+const (
+	defaultMaxDeliver      = 5
+	defaultRetryBackoff    = time.Second
+	defaultMaxRetryBackoff = time.Minute
+)
+
+var failures, _ = meter.Int64Counter("fx.stream.failures",
+	metric.WithDescription("Messages that could not be handled by error class and outcome"))
+
+// poisonError marks a message that can never be handled, retrying it would fail the same way.
+type poisonError struct {
+	err error
+}
+
+func (e *poisonError) Error() string {
+	return e.err.Error()
+}
+
+func (e *poisonError) Unwrap() error {
+	return e.err
+}
+
+func poison(err error) error {
+	return &poisonError{err: err}
+}
+
+// errorClass tells poison errors apart from transient ones, which are any other error.
+func errorClass(err error) string {
+	var p *poisonError
+	if errors.As(err, &p) {
+		return ErrorClassPoison
+	}
+	return ErrorClassTransient
+}
+
+// replyOrRetry returns the reply of a request, unless the service failed on something
+// that may succeed later, like the database being unavailable.
+func replyOrRetry(reply any, status response.StatusCode, errs *[]response.Error) (any, error) {
+	if status != response.InternalError {
+		return reply, nil
+	}
+	var details []string
+	if errs != nil {
+		for _, e := range *errs {
+			details = append(details, e.Code+": "+e.Details)
+		}
+	}
+	return nil, fmt.Errorf("service failed, %s", strings.Join(details, "; "))
+}
+
+// fail handles a message that could not be handled. Transient errors are retried with
+// an exponential backoff until the message was delivered MaxDeliver times, poison
+// messages and messages out of retries are published to the dead letter subject.
+func (h *Handler) fail(ctx context.Context, msg Message, err error) {
+	class := errorClass(err)
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("fx.error.class", class))
+	endWithError(span, err)
+	deliveries := msg.Deliveries()
+	common.Log(ctx).Errorf("Error in handling a message on %s, delivery %d, %s error. Exception:%v", msg.Subject(), deliveries, class, err)
+
+	if class == ErrorClassTransient && deliveries < h.maxDeliver() {
+		recordFailure(class, "retried")
+		if nakErr := msg.Retry(h.backoff(deliveries)); nakErr != nil {
+			common.Log(ctx).Errorf("Error in retrying a message on %s. Exception:%v", msg.Subject(), nakErr)
+		}
+		return
+	}
+
+	if dlErr := h.deadLetter(ctx, msg, class, err, deliveries); dlErr != nil {
+		// keep the message on the stream rather than losing it
+		common.Log(ctx).Errorf("Error in dead lettering a message on %s. Exception:%v", msg.Subject(), dlErr)
+		recordFailure(class, "retried")
+		if nakErr := msg.Retry(h.backoff(deliveries)); nakErr != nil {
+			common.Log(ctx).Errorf("Error in retrying a message on %s. Exception:%v", msg.Subject(), nakErr)
+		}
+		return
+	}
+	recordFailure(class, "dead_lettered")
+	if termErr := msg.Term(); termErr != nil {
+		common.Log(ctx).Errorf("Error in terminating a message on %s. Exception:%v", msg.Subject(), termErr)
+	}
+}
+
+func (h *Handler) deadLetter(ctx context.Context, msg Message, class string, err error, deliveries int) error {
+	if h.DeadLetterSubject == "" {
+		common.Log(ctx).Warnf("Dropping a message on %s, no dead letter subject is configured", msg.Subject())
+		return nil
+	}
+	deadLetter := NewMsg(h.DeadLetterSubject)
+	deadLetter.Data = msg.Data()
+	// keep the event attributes of binary events, the trace context is replaced by the
+	// one of the dead letter
+	for name, values := range msg.Headers() {
+		deadLetter.Header[name] = values
+	}
+	deadLetter.Header.Set(HeaderOriginalSubject, msg.Subject())
+	deadLetter.Header.Set(HeaderDeliveries, strconv.Itoa(deliveries))
+	deadLetter.Header.Set(HeaderErrorClass, class)
+	deadLetter.Header.Set(HeaderError, err.Error())
+	deadLetter.Header.Set(HeaderFailedAt, time.Now().UTC().Format(time.RFC3339))
+	deadLetter.Header.Set(HeaderStreamSequence, strconv.FormatUint(msg.Sequence(), 10))
+	return publishTraced(ctx, h.Publisher, deadLetter)
+}
+
+func (h *Handler) maxDeliver() int {
+	if h.MaxDeliver <= 0 {
+		return defaultMaxDeliver
+	}
+	return h.MaxDeliver
+}
+
+// backoff is the delay before the next delivery of a message delivered the given times.
+func (h *Handler) backoff(deliveries int) time.Duration {
+	delay, limit := h.RetryBackoff, h.MaxRetryBackoff
+	if delay <= 0 {
+		delay = defaultRetryBackoff
+	}
+	if limit <= 0 {
+		limit = defaultMaxRetryBackoff
+	}
+	for i := 1; i < deliveries && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+func recordFailure(class string, outcome string) {
+	failures.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("class", class),
+		attribute.String("outcome", outcome),
+	))
+}
@@ -0,0 +1,59 @@
+package stream
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var meter = otel.Meter(instrumentationName)
+
+var inFlight, _ = meter.Int64UpDownCounter("fx.stream.inflight",
+	metric.WithDescription("Messages being handled by the worker pool"))
+
+var handlerDuration, _ = meter.Float64Histogram("fx.stream.handler.duration",
+	metric.WithDescription("Time taken to handle a message, including publishing its reply"),
+	metric.WithUnit("s"),
+	metric.WithExplicitBucketBoundaries(telemetry.DurationBoundaries...))
+
+// lagBoundaries reach further than the durations, a message waits on the stream for as
+// long as the consumers fall behind.
+var lagBoundaries = append(append([]float64(nil), telemetry.DurationBoundaries...), 30, 60, 300, 900, 3600)
+
+var lag, _ = meter.Float64Histogram("fx.stream.lag",
+	metric.WithDescription("Time a message waited on the stream before it was handled"),
+	metric.WithUnit("s"),
+	metric.WithExplicitBucketBoundaries(lagBoundaries...))
+
+var fetched, _ = meter.Int64Histogram("fx.stream.fetch.size",
+	metric.WithDescription("Messages received by a single fetch"))
+
+// pending is the number of messages waiting on the consumer, as reported by the
+// metadata of the latest message received.
+var pending atomic.Int64
+
+func init() {
+	queueDepth, _ := meter.Int64ObservableGauge("fx.stream.pending",
+		metric.WithDescription("Messages waiting on the consumer to be delivered"))
+	_, _ = meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		observer.ObserveInt64(queueDepth, pending.Load())
+		return nil
+	}, queueDepth)
+}
+
+func recordLag(ctx context.Context, msg Message, started time.Time) {
+	if stored := msg.Timestamp(); !stored.IsZero() {
+		lag.Record(ctx, started.Sub(stored).Seconds(),
+			metric.WithAttributes(attribute.String("subject", msg.Subject())))
+	}
+}
+
+func recordHandled(ctx context.Context, subject string, started time.Time) {
+	handlerDuration.Record(ctx, time.Since(started).Seconds(),
+		metric.WithAttributes(attribute.String("subject", subject)))
+}
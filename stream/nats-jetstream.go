@@ -2,106 +2,277 @@ package stream
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"github.com/PeerIslands/aci-fx-go/config"
 	"github.com/PeerIslands/aci-fx-go/model/dto/request"
 	"github.com/PeerIslands/aci-fx-go/model/dto/response"
-	"github.com/PeerIslands/aci-fx-go/model/entity"
 	"github.com/PeerIslands/aci-fx-go/service/bal"
-	"github.com/PeerIslands/aci-fx-go/service/dal"
-	"github.com/nats-io/nats.go"
-	"github.com/nats-io/nats.go/jetstream"
-	"log"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 	"os"
-	"os/signal"
-	"syscall"
+	"strconv"
+	"strings"
 	"time"
 )
 
-var dbService = &dal.MongoDbService[entity.ForexData]{}
+// Handler runs the conversions and quotes requested on the stream and publishes the
+// result, or a structured error, of every request to the reply subject.
+type Handler struct {
+	FxService *bal.Fx_service
+	Publisher Publisher
+	Subject   string
+	HostName  string
+	// Source is the source of the reply events and EventMode their content mode,
+	// structured or binary
+	Source    string
+	EventMode string
+	// DeadLetterSubject receives the messages that could not be handled, they are
+	// dropped when empty
+	DeadLetterSubject string
+	// MaxDeliver is how many times a message is tried before it is dead lettered
+	MaxDeliver int
+	// RetryBackoff is the delay before the first retry, it doubles with every retry
+	// up to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+}
 
-var fxService = &bal.Fx_service{
-	DbService: dbService,
+// Consumer is a running stream consumer.
+type Consumer struct {
+	transport Transport
+	cancel    context.CancelFunc
+	done      chan struct{}
 }
 
-func Connect() {
-	nc, cerr := nats.Connect(os.Getenv("NATS_URI"))
+// Start connects to the configured broker and consumes the conversion requests of the
+// listen subject until the consumer is stopped.
+func Start(fxConfig *config.Config, fxService *bal.Fx_service) (*Consumer, error) {
+	workers, err := strconv.Atoi(fxConfig.Nats.Workers)
+	if err != nil || workers <= 0 {
+		return nil, fmt.Errorf("NATS workers %q must be a positive number", fxConfig.Nats.Workers)
+	}
+	ackWait, err := time.ParseDuration(fxConfig.Nats.AckWait)
+	if err != nil {
+		return nil, fmt.Errorf("NATS ack wait: %w", err)
+	}
+	fetchMaxWait, err := time.ParseDuration(fxConfig.Nats.FetchMaxWait)
+	if err != nil {
+		return nil, fmt.Errorf("NATS fetch max wait: %w", err)
+	}
+	if fetchMaxWait <= 0 {
+		return nil, fmt.Errorf("NATS fetch max wait %q must be positive", fxConfig.Nats.FetchMaxWait)
+	}
+	maxDeliver, err := strconv.Atoi(fxConfig.Nats.MaxDeliver)
+	if err != nil || maxDeliver <= 0 {
+		return nil, fmt.Errorf("NATS max deliver %q must be a positive number", fxConfig.Nats.MaxDeliver)
+	}
+	retryBackoff, err := time.ParseDuration(fxConfig.Nats.RetryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("NATS retry backoff: %w", err)
+	}
+	maxRetryBackoff, err := time.ParseDuration(fxConfig.Nats.MaxRetryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("NATS max retry backoff: %w", err)
+	}
+	if err = checkEventMode(fxConfig.Nats.EventMode); err != nil {
+		return nil, err
+	}
+
+	transport, err := OpenTransport(fxConfig, true)
+	if err != nil {
+		return nil, err
+	}
+
+	destinations := destinationsOf(fxConfig)
+	hn, _ := os.Hostname()
+	pool := &Pool{
+		Handler: &Handler{
+			FxService: fxService,
+			Publisher: transport,
+			Subject:   destinations.publish,
+			HostName:  hn,
+			Source:    fxConfig.Nats.EventSource,
+			EventMode: fxConfig.Nats.EventMode,
 
-	if cerr != nil {
-		log.Fatal("cerr")
+			DeadLetterSubject: destinations.deadLetter,
+			MaxDeliver:        maxDeliver,
+			RetryBackoff:      retryBackoff,
+			MaxRetryBackoff:   maxRetryBackoff,
+		},
+		Workers:      workers,
+		FetchMaxWait: fetchMaxWait,
+		// report progress well before the server would redeliver the message
+		Heartbeat: ackWait / 3,
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer := &Consumer{transport: transport, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(consumer.done)
+		pool.Run(ctx, transport)
+	}()
+	common.Logger.Infof("Consuming %s with %d workers over %s", destinations.listen, workers, transportName(fxConfig))
+	return consumer, nil
+}
 
-	js, jerr := jetstream.New(nc)
+// Stop stops fetching messages, waits for the messages in flight to be handled and
+// closes the transport, so the replies being published are flushed before it is closed.
+func (c *Consumer) Stop() {
+	_ = c.Shutdown(context.Background())
+	c.Close()
+}
 
-	if jerr != nil {
-		log.Fatal("jerr")
+// Shutdown stops fetching messages and waits for the messages in flight to be handled
+// until the context is done. Messages still in flight then are redelivered once their
+// ack wait is over.
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	c.cancel()
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		common.Logger.Warn("Stopped consuming with messages still in flight")
+		return ctx.Err()
 	}
-	stream, serr := js.Stream(context.Background(), os.Getenv("STREAM"))
+}
 
-	if serr != nil {
-		log.Fatal("serr")
+// Close closes the transport, once the consumer is shut down.
+func (c *Consumer) Close() {
+	if err := c.transport.Close(); err != nil {
+		common.Logger.Errorf("Error in closing the stream transport. Exception:%v", err)
 	}
+}
 
-	cons, _ := stream.CreateOrUpdateConsumer(context.Background(), jetstream.ConsumerConfig{
-		Durable:       os.Getenv("CONSUMER"),
-		AckPolicy:     jetstream.AckExplicitPolicy,
-		FilterSubject: os.Getenv("LISTEN_SUBJECT"),
-		MaxWaiting:    0,
-	})
+// Handle processes a single message. The message is acknowledged once its reply is
+// published. Failures are retried or dead lettered, see fail.
+func (h *Handler) Handle(msg Message) {
+	ctx, span := startProcessSpan(msg.Subject(), msg.Headers(), len(msg.Data()))
+	defer span.End()
 
-	cc, err := cons.Consume(func(msg jetstream.Msg) {
-		go func() {
-			handle(msg, js)
-		}()
-	})
-	if err != nil {
-		print("error")
+	deliveries := msg.Deliveries()
+	if deliveries > h.maxDeliver() {
+		// earlier deliveries ended without an outcome, e.g. the handler crashed or timed out
+		h.fail(ctx, msg, poison(fmt.Errorf("message was delivered %d times without being handled", deliveries-1)))
+		return
 	}
-	defer cc.Stop()
-
-	signalCh := make(chan os.Signal, 1)
-	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
 
-	<-signalCh
-	fmt.Println("Shutting down gracefully...")
+	reply, requestEvent, err := h.process(ctx, msg)
+	if reply != nil {
+		if publishErr := h.publish(ctx, reply, requestEvent); publishErr != nil && err == nil {
+			err = publishErr
+		}
+	}
+	if err != nil {
+		h.fail(ctx, msg, err)
+		return
+	}
+	if ackErr := msg.Ack(); ackErr != nil {
+		common.Log(ctx).Errorf("Error in acknowledging a message on %s. Exception:%v", msg.Subject(), ackErr)
+	}
 }
 
-func handle(msg jetstream.Msg, js jetstream.JetStream) {
+// process runs the requested action and returns the reply to publish along with the
+// request event. Requests that cannot be read are poison, the requester still gets an
+// error reply.
+func (h *Handler) process(ctx context.Context, msg Message) (any, Event, error) {
 	var receivedTime = time.Now().UnixMilli()
-	hn, _ := os.Hostname()
+	requestEvent, err := DecodeEvent(msg.Headers(), msg.Data())
 	var message request.NatConvertRequest
-	unmarshalErr := json.Unmarshal(msg.Data(), &message)
-	if unmarshalErr != nil {
-		return
+	if err == nil {
+		message, err = decodeRequest(requestEvent)
+	}
+	if err != nil {
+		e := &[]response.Error{
+			{Code: "INVALID_MESSAGE", Message: "Message is invalid", Details: err.Error()},
+		}
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e), requestEvent, poison(err)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		semconv.MessagingMessageID(requestEvent.ID),
+		semconv.MessagingMessageConversationID(requestEvent.CorrelationID),
+	)
+	reply, err := h.run(ctx, message, receivedTime)
+	return reply, requestEvent, err
+}
+
+func (h *Handler) run(ctx context.Context, message request.NatConvertRequest, receivedTime int64) (any, error) {
+
+	switch message.Action {
+	case "", request.NatActionConvert:
+		conversionResponse := h.convert(&ctx, message, receivedTime)
+		return replyOrRetry(conversionResponse, conversionResponse.Status, conversionResponse.Errors)
+	case request.NatActionQuote:
+		quoteResponse := h.FxService.IssueQuote(&ctx, request.QuoteRequest{
+			TenantId:       message.TenantID,
+			BankId:         message.BankID,
+			BaseCurrency:   message.BaseCurrency,
+			TargetCurrency: message.TargetCurrency,
+			Tier:           message.Tier,
+			Side:           message.Side,
+			Amount:         message.Amount,
+		})
+		return replyOrRetry(quoteResponse, quoteResponse.Status, quoteResponse.Errors)
+	case request.NatActionRedeem:
+		quoteResponse := h.FxService.RedeemQuote(&ctx, message.QuoteId)
+		return replyOrRetry(quoteResponse, quoteResponse.Status, quoteResponse.Errors)
+	default:
+		e := &[]response.Error{
+			{Code: "INVALID_INPUT", Message: "action is invalid",
+				Details: fmt.Sprintf("action must be one of %s, %s or %s", request.NatActionConvert, request.NatActionQuote, request.NatActionRedeem)},
+		}
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e), nil
+	}
+}
+
+// requireFields returns an error when a required field of a request is missing, as the
+// parameter validation of the api does.
+func requireFields(fields map[string]bool) *[]response.Error {
+	var missing []string
+	for _, name := range []string{"tenantId", "bankId", "baseCurrency", "targetCurrency", "side", "amount"} {
+		if present, ok := fields[name]; ok && !present {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
 	}
-	/*conversionResponse := fxService.GetConvertedRate(message.TenantID, message.BankID, message.Amount, message.BaseCurrency, message.TargetCurrency, message.Tier)
-	if conversionResponse.Status == "Success" {
+	return &[]response.Error{
+		{Code: "INVALID_INPUT", Message: "Required fields are missing", Details: strings.Join(missing, ", ") + " must be provided"},
+	}
+}
+
+func (h *Handler) convert(c *context.Context, message request.NatConvertRequest, receivedTime int64) response.ResponseWithSimpleData[response.ConversionResponse] {
+	if e := requireFields(map[string]bool{"bankId": message.BankID > 0, "targetCurrency": message.TargetCurrency != ""}); e != nil {
+		return common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e)
+	}
+
+	conversionResponse := h.FxService.GetConvertedRate(c, message.TenantID, message.BankID, message.Amount, message.BaseCurrency, message.TargetCurrency, message.Tier)
+	if conversionResponse.Status == response.Success {
 		conversionResponse.Data.InitiatedOn = message.InitiatedOn
 		conversionResponse.Data.TimeTaken = time.Now().UnixMilli() - message.InitiatedOn
 		conversionResponse.Data.ReceivedTime = receivedTime - message.InitiatedOn
-	}*/
-
-	conversionResponse := response.ConversionResponse{
-		Amount:          0,
-		ConvertedAmount: 0,
-		BaseCurrency:    "",
-		TargetCurrency:  "",
-		InitiatedOn:     message.InitiatedOn,
-		TimeTaken:       time.Now().UnixMilli() - message.InitiatedOn,
-		ReceivedTime:    receivedTime - message.InitiatedOn,
-		Rate:            0,
-		HostName:        hn,
-	}
-	processedData, _ := json.Marshal(conversionResponse)
-	_, err := js.PublishAsync(os.Getenv("PUBLISH_SUBJECT"), processedData)
-	if err != nil {
-		return
+		conversionResponse.Data.HostName = h.HostName
 	}
+	return conversionResponse
+}
 
-	ackErr := msg.Ack()
-	log.Println("completed")
-	if ackErr != nil {
-		return
+// publish publishes the reply to a request as a reply event correlated with the request.
+func (h *Handler) publish(ctx context.Context, reply any, requestEvent Event) error {
+	event, err := NewEvent(EventReply, ReplyVersion, h.Source, reply)
+	if err != nil {
+		return poison(fmt.Errorf("encoding the reply: %w", err))
 	}
-
+	event.Subject = requestEvent.Subject
+	event.CorrelationID = requestEvent.CorrelationID
+	if event.CorrelationID == "" {
+		event.CorrelationID = requestEvent.ID
+	}
+	msg := NewMsg(h.Subject)
+	if err = EncodeEvent(msg, event, h.EventMode); err != nil {
+		return poison(fmt.Errorf("encoding the reply: %w", err))
+	}
+	if err = publishTraced(ctx, h.Publisher, msg); err != nil {
+		return fmt.Errorf("publishing the reply to %s: %w", h.Subject, err)
+	}
+	return nil
 }
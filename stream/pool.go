@@ -0,0 +1,126 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/common"
+)
+
+// Pool handles messages with a fixed number of workers. Messages are only pulled from
+// the consumer when a worker is free, so at most Workers messages are in flight and
+// the rest wait on the stream.
+type Pool struct {
+	Handler *Handler
+	// Workers is the maximum number of messages handled at the same time, at least one
+	Workers int
+	// FetchMaxWait is how long a fetch waits for messages before it is retried, it must
+	// be positive
+	FetchMaxWait time.Duration
+	// Heartbeat is how often a message being handled is reported in progress, so it is
+	// not redelivered while a slow handler is still working on it. Disabled when zero.
+	Heartbeat time.Duration
+}
+
+// Run fetches and handles messages until the context is done, then waits for the
+// messages in flight to be handled.
+func (p *Pool) Run(ctx context.Context, fetcher Fetcher) {
+	workers, maxWait := p.Workers, p.FetchMaxWait
+
+	slots := make(chan struct{}, workers)
+	for i := 0; i < workers; i++ {
+		slots <- struct{}{}
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		// wait for a free worker, then take every other free worker for the same fetch
+		select {
+		case <-ctx.Done():
+			return
+		case <-slots:
+		}
+		free := 1
+		for free < workers && len(slots) > 0 {
+			<-slots
+			free++
+		}
+
+		batch, err := fetcher.Fetch(ctx, free, maxWait)
+		if err != nil {
+			release(slots, free)
+			if ctx.Err() != nil {
+				return
+			}
+			common.Logger.Errorf("Error in fetching messages. Exception:%v", err)
+			wait(ctx, maxWait)
+			continue
+		}
+
+		received := 0
+		for msg := range batch.Messages() {
+			received++
+			wg.Add(1)
+			go func(msg Message) {
+				defer wg.Done()
+				defer func() { slots <- struct{}{} }()
+				p.handle(msg)
+			}(msg)
+		}
+		release(slots, free-received)
+		fetched.Record(context.Background(), int64(received))
+		if err := batch.Error(); err != nil && ctx.Err() == nil {
+			common.Logger.Errorf("Error in receiving fetched messages. Exception:%v", err)
+		}
+	}
+}
+
+func (p *Pool) handle(msg Message) {
+	ctx := context.Background()
+	started := time.Now()
+	inFlight.Add(ctx, 1)
+	defer inFlight.Add(ctx, -1)
+	pending.Store(int64(msg.Pending()))
+	recordLag(ctx, msg, started)
+
+	if p.Heartbeat > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go heartbeat(msg, p.Heartbeat, done)
+	}
+
+	p.Handler.Handle(msg)
+	recordHandled(ctx, msg.Subject(), started)
+}
+
+func heartbeat(msg Message, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := msg.InProgress(); err != nil {
+				common.Logger.Errorf("Error in reporting a message on %s in progress. Exception:%v", msg.Subject(), err)
+			}
+		}
+	}
+}
+
+func release(slots chan struct{}, count int) {
+	for i := 0; i < count; i++ {
+		slots <- struct{}{}
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
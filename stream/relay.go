@@ -0,0 +1,214 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	defaultRelayInterval  = time.Second
+	defaultRelayBatchSize = 100
+	// relayPruneInterval is how often published events past their retention are deleted
+	relayPruneInterval = time.Hour
+)
+
+var relayed, _ = meter.Int64Counter("fx.rate_events.published",
+	metric.WithDescription("Rate events published from the outbox by event type"))
+
+// Relay publishes the rate events of the outbox to the stream, in the order they occurred.
+// Events stay in the outbox until the stream has acknowledged them, so no event is lost
+// while NATS is unavailable. An event may be published twice when the outbox cannot be
+// updated or several relays run, the event id is the message id so the stream drops such
+// duplicates within its duplicate window.
+type Relay struct {
+	Outbox    dal.RateEventDBService
+	Publisher Publisher
+	// SubjectPrefix is followed by the tenant and the currency pair of an event,
+	// e.g. fx.rates.1.USD.EUR
+	SubjectPrefix string
+	Interval      time.Duration
+	BatchSize     int
+	// Source is the source of the events and EventMode their content mode
+	Source    string
+	EventMode string
+	// Retention is how long published events are kept in the outbox, forever when zero
+	Retention time.Duration
+
+	lastPruned time.Time
+}
+
+// RateEventSubject is the subject a rate event is published to.
+func RateEventSubject(prefix string, event entity.RateEvent) string {
+	return strings.Join([]string{prefix, strconv.Itoa(event.TenantID),
+		strings.ToUpper(event.BaseCurrency), strings.ToUpper(event.TargetCurrency)}, ".")
+}
+
+// Run publishes pending events every interval until the context is done.
+func (r *Relay) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultRelayInterval
+	}
+	for {
+		// keep going while full batches are pending
+		for r.publishPending(ctx) {
+		}
+		r.prune(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// publishPending publishes a batch of pending events and reports whether more may be pending.
+func (r *Relay) publishPending(ctx context.Context) bool {
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRelayBatchSize
+	}
+	events, err := r.Outbox.GetPendingRateEvents(ctx, batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			common.Log(ctx).Errorf("Error in reading pending rate events. Exception:%v", err)
+		}
+		return false
+	}
+	for _, event := range events {
+		if err = r.publish(ctx, event); err != nil {
+			// later events of the same pair must not overtake this one, retry on the next run
+			common.Log(ctx).Errorf("Error in publishing rate event %s. Exception:%v", event.ID, err)
+			return false
+		}
+	}
+	return len(events) == batchSize && ctx.Err() == nil
+}
+
+// prune deletes the published events past their retention, once every relayPruneInterval.
+func (r *Relay) prune(ctx context.Context) {
+	if r.Retention <= 0 || time.Since(r.lastPruned) < relayPruneInterval || ctx.Err() != nil {
+		return
+	}
+	r.lastPruned = time.Now()
+	deleted, err := r.Outbox.DeletePublishedRateEvents(ctx, r.lastPruned.Add(-r.Retention))
+	if err != nil {
+		common.Log(ctx).Errorf("Error in deleting published rate events. Exception:%v", err)
+		return
+	}
+	if deleted > 0 {
+		common.Log(ctx).Debugf("Deleted %d published rate events", deleted)
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, event entity.RateEvent) error {
+	cloudEvent, err := NewEvent(EventRateChanged, event.Version, r.Source, event)
+	if err != nil {
+		return err
+	}
+	cloudEvent.ID = event.ID
+	cloudEvent.Subject = strings.ToUpper(event.BaseCurrency) + "/" + strings.ToUpper(event.TargetCurrency)
+	occurredAt := event.OccurredAt.UTC()
+	cloudEvent.Time = &occurredAt
+	msg := NewMsg(RateEventSubject(r.SubjectPrefix, event))
+	if err = EncodeEvent(msg, cloudEvent, r.EventMode); err != nil {
+		return err
+	}
+	msg.Header.Set(HeaderMsgID, event.ID)
+	if err = publishTraced(contextFromMap(ctx, event.TraceContext), r.Publisher, msg); err != nil {
+		return err
+	}
+	relayed.Add(ctx, 1, metric.WithAttributes(attribute.String("type", event.Type)))
+	if err = r.Outbox.MarkRateEventPublished(ctx, event.ID, time.Now()); err != nil {
+		return fmt.Errorf("marking the event published: %w", err)
+	}
+	return nil
+}
+
+// RelayRunner is a running relay.
+type RelayRunner struct {
+	transport Transport
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// StartRelay connects to the configured broker and relays the rate events of the outbox
+// until it is stopped. On NATS the stream keeping rate events is created when it does
+// not exist, kafka creates the topic with the first event.
+func StartRelay(fxConfig *config.Config, outbox dal.RateEventDBService) (*RelayRunner, error) {
+	interval, err := time.ParseDuration(fxConfig.Nats.RateEventsRelayInterval)
+	if err != nil {
+		return nil, fmt.Errorf("rate events relay interval: %w", err)
+	}
+	retention, err := time.ParseDuration(fxConfig.Nats.RateEventsRetention)
+	if err != nil {
+		return nil, fmt.Errorf("rate events retention: %w", err)
+	}
+	if err = checkEventMode(fxConfig.Nats.EventMode); err != nil {
+		return nil, err
+	}
+	transport, err := OpenTransport(fxConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	if creator, ok := transport.(streamCreator); ok {
+		if err = creator.CreateStream(context.Background(), fxConfig.Nats.RateEventsStream, fxConfig.Nats.RateEventsPrefix+".>"); err != nil {
+			_ = transport.Close()
+			return nil, err
+		}
+	}
+
+	prefix := destinationsOf(fxConfig).rateEvents
+	relay := &Relay{
+		Outbox:        outbox,
+		Publisher:     transport,
+		SubjectPrefix: prefix,
+		Interval:      interval,
+		Retention:     retention,
+		Source:        fxConfig.Nats.EventSource,
+		EventMode:     fxConfig.Nats.EventMode,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := &RelayRunner{transport: transport, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(runner.done)
+		relay.Run(ctx)
+	}()
+	common.Logger.Infof("Relaying rate events to %s.> over %s", prefix, transportName(fxConfig))
+	return runner, nil
+}
+
+// Stop stops relaying once the event being published is done and closes the transport.
+func (r *RelayRunner) Stop() {
+	_ = r.Shutdown(context.Background())
+	r.Close()
+}
+
+// Shutdown stops relaying and waits for the event being published until the context is
+// done. An event that was not marked published then is relayed again on restart.
+func (r *RelayRunner) Shutdown(ctx context.Context) error {
+	r.cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes the transport, once the relay is shut down.
+func (r *RelayRunner) Close() {
+	if err := r.transport.Close(); err != nil {
+		common.Logger.Errorf("Error in closing the stream transport. Exception:%v", err)
+	}
+}
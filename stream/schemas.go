@@ -0,0 +1,63 @@
+package stream
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+)
+
+// ReplyVersion is the schema version of the replies published by the consumer.
+const ReplyVersion = 1
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// EventSchema is a version of the data of an event type, its JSON Schema is in
+// schemas/<type>.v<version>.json.
+type EventSchema struct {
+	Type    string
+	Version int
+}
+
+// EventSchemas lists every published schema, a new version is added rather than
+// changing a published one.
+var EventSchemas = []EventSchema{
+	{Type: EventRequest, Version: 1},
+	{Type: EventReply, Version: ReplyVersion},
+	{Type: EventRateChanged, Version: entity.RateEventVersion},
+}
+
+// Schema returns the JSON Schema of the given event type and version.
+func Schema(eventType string, version int) ([]byte, bool) {
+	for _, schema := range EventSchemas {
+		if schema.Type == eventType && schema.Version == version {
+			data, err := schemaFiles.ReadFile("schemas/" + eventType + ".v" + strconv.Itoa(version) + ".json")
+			return data, err == nil
+		}
+	}
+	return nil, false
+}
+
+// decodeRequest reads the request carried by an event.
+func decodeRequest(event Event) (request.NatConvertRequest, error) {
+	var message request.NatConvertRequest
+	if !event.Legacy() && event.Type != EventRequest {
+		return message, fmt.Errorf("event type %s is not %s", event.Type, EventRequest)
+	}
+	version, err := event.Version()
+	if err != nil {
+		return message, err
+	}
+
+	switch version {
+	case 1:
+		err = json.Unmarshal(event.Data, &message)
+		return message, err
+	default:
+		return message, fmt.Errorf("%s version %d is not supported", event.Type, version)
+	}
+}
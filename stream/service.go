@@ -0,0 +1,172 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ServiceName    = "fx-rates"
+	ServiceVersion = "1.0.0"
+
+	EndpointConvert = "convert"
+	EndpointRate    = "rate"
+	EndpointQuote   = "quote"
+)
+
+// AddService registers the conversion service on a connection. Its endpoints answer
+// requests on {prefix}.convert, {prefix}.rate and {prefix}.quote over core NATS, the
+// instances of the service share the load through the queue group. Replies carry the
+// same response envelope as the api, failures of the service itself are also reported
+// through the micro error headers.
+func AddService(nc *nats.Conn, fxService *bal.Fx_service, prefix string, queueGroup string) (micro.Service, error) {
+	svc, err := micro.AddService(nc, micro.Config{
+		Name:        ServiceName,
+		Version:     ServiceVersion,
+		Description: "Currency conversions, rates and quotes",
+		QueueGroup:  queueGroup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoints := svc.AddGroup(prefix)
+	handlers := map[string]micro.HandlerFunc{
+		EndpointConvert: traced(serveConvert, fxService),
+		EndpointRate:    traced(serveRate, fxService),
+		EndpointQuote:   traced(serveQuote, fxService),
+	}
+	for _, name := range []string{EndpointConvert, EndpointRate, EndpointQuote} {
+		if err = endpoints.AddEndpoint(name, handlers[name]); err != nil {
+			_ = svc.Stop()
+			return nil, fmt.Errorf("adding the %s endpoint: %w", name, err)
+		}
+	}
+	return svc, nil
+}
+
+// traced runs an endpoint within the consumer span of the request, continuing the trace
+// of the requester.
+func traced(serve func(ctx context.Context, req micro.Request, fxService *bal.Fx_service), fxService *bal.Fx_service) micro.HandlerFunc {
+	return func(req micro.Request) {
+		ctx, span := startProcessSpan(req.Subject(), Header(req.Headers()), len(req.Data()))
+		defer span.End()
+		serve(ctx, req, fxService)
+	}
+}
+
+func serveConvert(ctx context.Context, req micro.Request, fxService *bal.Fx_service) {
+	var message request.NatConvertRequest
+	if !decode(ctx, req, &message) {
+		return
+	}
+	if e := requireFields(map[string]bool{"bankId": message.BankID > 0, "targetCurrency": message.TargetCurrency != ""}); e != nil {
+		respond(ctx, req, common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e), response.BadRequest)
+		return
+	}
+	conversionResponse := fxService.GetConvertedRate(&ctx, message.TenantID, message.BankID, message.Amount, message.BaseCurrency, message.TargetCurrency, message.Tier)
+	respond(ctx, req, conversionResponse, conversionResponse.Status)
+}
+
+func serveRate(ctx context.Context, req micro.Request, fxService *bal.Fx_service) {
+	var message request.NatRateRequest
+	if !decode(ctx, req, &message) {
+		return
+	}
+	if e := requireFields(map[string]bool{"bankId": message.BankID > 0, "baseCurrency": message.BaseCurrency != "", "targetCurrency": message.TargetCurrency != ""}); e != nil {
+		respond(ctx, req, common.GetArrayResponse[response.ForexDataResponse](nil, response.BadRequest, e), response.BadRequest)
+		return
+	}
+	rateResponse := fxService.GetForexRateByFilter(&ctx, message.TenantID, message.BankID, message.BaseCurrency, message.TargetCurrency, message.Tier)
+	respond(ctx, req, rateResponse, rateResponse.Status)
+}
+
+func serveQuote(ctx context.Context, req micro.Request, fxService *bal.Fx_service) {
+	var message request.QuoteRequest
+	if !decode(ctx, req, &message) {
+		return
+	}
+	quoteResponse := fxService.IssueQuote(&ctx, message)
+	respond(ctx, req, quoteResponse, quoteResponse.Status)
+}
+
+// decode reads the request body, replying with an error when it is not valid json.
+func decode(ctx context.Context, req micro.Request, message any) bool {
+	if err := json.Unmarshal(req.Data(), message); err != nil {
+		e := &[]response.Error{
+			{Code: "INVALID_MESSAGE", Message: "Message is invalid", Details: err.Error()},
+		}
+		respond(ctx, req, common.GetSimpleResponse[response.ConversionResponse](nil, response.BadRequest, e), response.BadRequest)
+		return false
+	}
+	return true
+}
+
+// respond replies to a request, the reply carries the trace context of the request span.
+func respond(ctx context.Context, req micro.Request, reply any, status response.StatusCode) {
+	headers := micro.Headers{}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(Header(headers)))
+	var err error
+	if status == response.InternalError {
+		trace.SpanFromContext(ctx).SetStatus(codes.Error, "Internal error")
+		data, _ := json.Marshal(reply)
+		err = req.Error("500", "Internal error", data, micro.WithHeaders(headers))
+	} else {
+		err = req.RespondJSON(reply, micro.WithHeaders(headers))
+	}
+	if err != nil {
+		common.Log(ctx).Errorf("Error in replying to a request on %s. Exception:%v", req.Subject(), err)
+	}
+}
+
+// Service is a running conversion service.
+type Service struct {
+	nc  *nats.Conn
+	svc micro.Service
+}
+
+// StartService connects to NATS and serves conversion requests until it is stopped.
+func StartService(fxConfig *config.Config, fxService *bal.Fx_service) (*Service, error) {
+	nc, err := nats.Connect(fxConfig.Nats.Url, nats.Timeout(5*time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("NATS connection: %w", err)
+	}
+	svc, err := AddService(nc, fxService, fxConfig.Nats.ServicePrefix, fxConfig.Nats.ServiceQueueGroup)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("NATS service: %w", err)
+	}
+	common.Logger.Infof("Serving conversion requests on %s.> in queue group %s", fxConfig.Nats.ServicePrefix, fxConfig.Nats.ServiceQueueGroup)
+	return &Service{nc: nc, svc: svc}, nil
+}
+
+// Stop stops taking requests and drains the connection, so requests being handled are
+// answered before it is closed.
+func (s *Service) Stop() {
+	_ = s.Shutdown(context.Background())
+}
+
+// Shutdown stops taking requests and drains the connection until the context is done,
+// the connection is closed then even if requests are still being handled.
+func (s *Service) Shutdown(ctx context.Context) error {
+	if err := s.svc.Stop(); err != nil {
+		common.Logger.Errorf("Error in stopping the NATS service. Exception:%v", err)
+	}
+	if err := drain(ctx, s.nc); err != nil {
+		common.Logger.Errorf("Error in draining the NATS connection. Exception:%v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer and the meter of the stream.
+const instrumentationName = "github.com/PeerIslands/aci-fx-go/stream"
+
+const messagingSystem = "nats"
+
+// headerCarrier carries the W3C trace context and baggage in the headers of a message.
+type headerCarrier Header
+
+// Get ignores the case of the key, producers using http.Header canonicalize it, e.g.
+// Traceparent.
+func (h headerCarrier) Get(key string) string {
+	if value := Header(h).Get(key); value != "" {
+		return value
+	}
+	for name, values := range h {
+		if strings.EqualFold(name, key) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func (h headerCarrier) Set(key string, value string) {
+	Header(h).Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startProcessSpan starts the consumer span of a received message, continuing the trace
+// of the producer found in its headers.
+func startProcessSpan(subject string, header Header, size int, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := context.Background()
+	if header != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(header))
+	}
+	attrs = append(attrs,
+		semconv.MessagingSystem(messagingSystem),
+		semconv.MessagingOperationProcess,
+		semconv.MessagingDestinationName(subject),
+		semconv.MessagingMessagePayloadSizeBytes(size),
+	)
+	return otel.Tracer(instrumentationName).Start(ctx, subject+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attrs...))
+}
+
+// startPublishSpan starts the producer span of a message and injects its context into
+// the headers of the message, so consumers continue the trace.
+func startPublishSpan(ctx context.Context, msg *Msg) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystem(messagingSystem),
+		semconv.MessagingOperationPublish,
+		semconv.MessagingDestinationName(msg.Subject),
+		semconv.MessagingMessagePayloadSizeBytes(len(msg.Data)),
+	}
+	if id := msg.Header.Get(HeaderMsgID); id != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(id))
+	}
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, msg.Subject+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(attrs...))
+	if msg.Header == nil {
+		msg.Header = Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(msg.Header))
+	return ctx, span
+}
+
+// publishTraced publishes a message within a producer span.
+func publishTraced(ctx context.Context, publisher Publisher, msg *Msg) error {
+	ctx, span := startPublishSpan(ctx, msg)
+	defer span.End()
+	err := publisher.Publish(ctx, msg)
+	endWithError(span, err)
+	return err
+}
+
+func endWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// contextFromMap returns a context continuing the trace kept in a map, e.g. the trace
+// of the request that changed a rate.
+func contextFromMap(ctx context.Context, traceContext map[string]string) context.Context {
+	if len(traceContext) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(traceContext))
+}
@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+)
+
+// Transports a stream can run on, selected by STREAM_TRANSPORT.
+const (
+	TransportNats  = "nats"
+	TransportKafka = "kafka"
+)
+
+// Header holds the headers of a message. Keys are case sensitive, as NATS headers are.
+type Header map[string][]string
+
+// Get returns the first value of a header, empty when it is missing.
+func (h Header) Get(key string) string {
+	if values := h[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values of a header.
+func (h Header) Set(key string, value string) {
+	h[key] = []string{value}
+}
+
+// Msg is a message to publish.
+type Msg struct {
+	Subject string
+	Header  Header
+	Data    []byte
+}
+
+// NewMsg returns an empty message for the subject.
+func NewMsg(subject string) *Msg {
+	return &Msg{Subject: subject, Header: Header{}}
+}
+
+// Message is a message received from a consumer. Every message ends with Ack, Retry
+// or Term.
+type Message interface {
+	Subject() string
+	Data() []byte
+	Headers() Header
+	// Deliveries is how many times the message was delivered, this delivery included
+	Deliveries() int
+	// Sequence is the position of the message in its stream or partition
+	Sequence() uint64
+	// Pending is how many messages wait behind this one
+	Pending() uint64
+	// Timestamp is when the broker stored the message, zero when it is not known
+	Timestamp() time.Time
+	Ack() error
+	// Retry delivers the message again after the delay
+	Retry(delay time.Duration) error
+	// Term drops the message for good
+	Term() error
+	// InProgress tells the broker the message is still being handled
+	InProgress() error
+}
+
+// Batch delivers the messages of a fetch as they arrive, the channel is closed once
+// the batch is complete or the fetch timed out.
+type Batch interface {
+	Messages() <-chan Message
+	// Error is the error that ended the batch early, nil when it timed out
+	Error() error
+}
+
+// Publisher publishes messages, it returns once the broker has stored the message.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Msg) error
+}
+
+// Fetcher pulls messages of a consumer.
+type Fetcher interface {
+	// Fetch pulls up to batch messages, waiting at most maxWait for them
+	Fetch(ctx context.Context, batch int, maxWait time.Duration) (Batch, error)
+}
+
+// Transport is a connection to the broker of the stream.
+type Transport interface {
+	Publisher
+	Fetcher
+	// Close waits for pending publishes and closes the connection
+	Close() error
+}
+
+// streamCreator is implemented by transports whose streams must be created before
+// messages are published to them.
+type streamCreator interface {
+	CreateStream(ctx context.Context, name string, subjects ...string) error
+}
+
+// Configured reports whether a broker is configured for the stream.
+func Configured(fxConfig *config.Config) bool {
+	switch fxConfig.Stream.Transport {
+	case TransportKafka:
+		return fxConfig.Kafka.Brokers != ""
+	default:
+		return fxConfig.Nats.Url != ""
+	}
+}
+
+// destinations are the subjects, or topics on kafka, and the consumer of the stream.
+type destinations struct {
+	consumer   string
+	listen     string
+	publish    string
+	deadLetter string
+	// rateEvents starts the subjects of the rate events
+	rateEvents string
+}
+
+// destinationsOf returns the destinations configured for the transport.
+func destinationsOf(fxConfig *config.Config) destinations {
+	if fxConfig.Stream.Transport == TransportKafka {
+		return destinations{
+			consumer:   fxConfig.Kafka.ConsumerGroup,
+			listen:     fxConfig.Kafka.ListenTopic,
+			publish:    fxConfig.Kafka.PublishTopic,
+			deadLetter: fxConfig.Kafka.DeadLetterTopic,
+			rateEvents: fxConfig.Kafka.RateEventsTopic,
+		}
+	}
+	return destinations{
+		consumer:   fxConfig.Nats.Consumer,
+		listen:     fxConfig.Nats.ListenSubject,
+		publish:    fxConfig.Nats.PublishSubject,
+		deadLetter: fxConfig.Nats.DeadLetterSubject,
+		rateEvents: fxConfig.Nats.RateEventsPrefix,
+	}
+}
+
+func transportName(fxConfig *config.Config) string {
+	if fxConfig.Stream.Transport == "" {
+		return TransportNats
+	}
+	return fxConfig.Stream.Transport
+}
+
+// OpenTransport connects to the configured broker. A consuming transport also joins
+// the configured consumer on the listen subject.
+func OpenTransport(fxConfig *config.Config, consume bool) (Transport, error) {
+	switch fxConfig.Stream.Transport {
+	case "", TransportNats:
+		transport, err := openJetStream(fxConfig, consume)
+		if err != nil {
+			return nil, err
+		}
+		return transport, nil
+	case TransportKafka:
+		transport, err := openKafka(fxConfig, consume)
+		if err != nil {
+			return nil, err
+		}
+		return transport, nil
+	default:
+		return nil, fmt.Errorf("stream transport %q must be %s or %s", fxConfig.Stream.Transport, TransportNats, TransportKafka)
+	}
+}
+
+// messageBatch is a Batch filled by a goroutine of the transport.
+type messageBatch struct {
+	messages chan Message
+	err      error
+}
+
+func newMessageBatch(size int) *messageBatch {
+	return &messageBatch{messages: make(chan Message, size)}
+}
+
+func (b *messageBatch) Messages() <-chan Message {
+	return b.messages
+}
+
+// Error must only be called once the messages channel is closed.
+func (b *messageBatch) Error() error {
+	return b.err
+}
+
+// done ends the batch, the error is visible to readers once the channel is closed.
+func (b *messageBatch) done(err error) {
+	b.err = err
+	close(b.messages)
+}
@@ -0,0 +1,175 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// HeaderMsgID is the id JetStream drops duplicate messages by.
+const HeaderMsgID = jetstream.MsgIDHeader
+
+// jetStreamTransport runs the stream on NATS JetStream. The consumer is a durable pull
+// consumer with explicit acks, retries are negative acks with a delay.
+type jetStreamTransport struct {
+	nc       *nats.Conn
+	js       jetstream.JetStream
+	consumer jetstream.Consumer
+}
+
+func openJetStream(fxConfig *config.Config, consume bool) (*jetStreamTransport, error) {
+	var ackWait time.Duration
+	if consume {
+		var err error
+		if ackWait, err = time.ParseDuration(fxConfig.Nats.AckWait); err != nil {
+			return nil, fmt.Errorf("NATS ack wait: %w", err)
+		}
+	}
+	nc, err := nats.Connect(fxConfig.Nats.Url)
+	if err != nil {
+		return nil, fmt.Errorf("NATS connection: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("JetStream context: %w", err)
+	}
+	t := &jetStreamTransport{nc: nc, js: js}
+	if !consume {
+		return t, nil
+	}
+
+	stream, err := js.Stream(context.Background(), fxConfig.Nats.Stream)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("JetStream stream %s: %w", fxConfig.Nats.Stream, err)
+	}
+	if fxConfig.Nats.DeadLetterStream != "" && fxConfig.Nats.DeadLetterSubject != "" {
+		// keep the dead letters so they can be replayed
+		if err = t.CreateStream(context.Background(), fxConfig.Nats.DeadLetterStream, fxConfig.Nats.DeadLetterSubject); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	t.consumer, err = stream.CreateOrUpdateConsumer(context.Background(), jetstream.ConsumerConfig{
+		Durable:       fxConfig.Nats.Consumer,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		FilterSubject: fxConfig.Nats.ListenSubject,
+		MaxWaiting:    0,
+	})
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("JetStream consumer %s: %w", fxConfig.Nats.Consumer, err)
+	}
+	return t, nil
+}
+
+func (t *jetStreamTransport) Publish(ctx context.Context, msg *Msg) error {
+	_, err := t.js.PublishMsg(ctx, &nats.Msg{Subject: msg.Subject, Header: nats.Header(msg.Header), Data: msg.Data})
+	return err
+}
+
+func (t *jetStreamTransport) Fetch(ctx context.Context, batch int, maxWait time.Duration) (Batch, error) {
+	if t.consumer == nil {
+		return nil, errors.New("the transport was not opened to consume")
+	}
+	fetched, err := t.consumer.Fetch(batch, jetstream.FetchMaxWait(maxWait))
+	if err != nil {
+		return nil, err
+	}
+	b := newMessageBatch(batch)
+	go func() {
+		for msg := range fetched.Messages() {
+			b.messages <- jetStreamMessage{msg}
+		}
+		err := fetched.Error()
+		if errors.Is(err, jetstream.ErrNoMessages) || errors.Is(err, nats.ErrTimeout) {
+			err = nil
+		}
+		b.done(err)
+	}()
+	return b, nil
+}
+
+func (t *jetStreamTransport) CreateStream(ctx context.Context, name string, subjects ...string) error {
+	_, err := t.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{Name: name, Subjects: subjects})
+	if err != nil {
+		return fmt.Errorf("JetStream stream %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close drains the connection, so the messages being published are flushed first.
+func (t *jetStreamTransport) Close() error {
+	return drain(context.Background(), t.nc)
+}
+
+// drain drains the connection and waits for it to close. The connection is closed
+// right away once the context is done, the drain timeout of the connection applies
+// otherwise.
+func drain(ctx context.Context, nc *nats.Conn) error {
+	closed := make(chan struct{})
+	nc.SetClosedHandler(func(*nats.Conn) { close(closed) })
+	if err := nc.Drain(); err != nil {
+		nc.Close()
+		return err
+	}
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		nc.Close()
+		return ctx.Err()
+	}
+}
+
+type jetStreamMessage struct {
+	msg jetstream.Msg
+}
+
+func (m jetStreamMessage) Subject() string { return m.msg.Subject() }
+func (m jetStreamMessage) Data() []byte    { return m.msg.Data() }
+func (m jetStreamMessage) Headers() Header { return Header(m.msg.Headers()) }
+func (m jetStreamMessage) Ack() error      { return m.msg.Ack() }
+func (m jetStreamMessage) Term() error     { return m.msg.Term() }
+
+func (m jetStreamMessage) InProgress() error { return m.msg.InProgress() }
+
+func (m jetStreamMessage) Retry(delay time.Duration) error {
+	return m.msg.NakWithDelay(delay)
+}
+
+func (m jetStreamMessage) Deliveries() int {
+	metadata, err := m.msg.Metadata()
+	if err != nil || metadata.NumDelivered == 0 {
+		return 1
+	}
+	return int(metadata.NumDelivered)
+}
+
+func (m jetStreamMessage) Sequence() uint64 {
+	if metadata, err := m.msg.Metadata(); err == nil {
+		return metadata.Sequence.Stream
+	}
+	return 0
+}
+
+func (m jetStreamMessage) Timestamp() time.Time {
+	if metadata, err := m.msg.Metadata(); err == nil {
+		return metadata.Timestamp
+	}
+	return time.Time{}
+}
+
+func (m jetStreamMessage) Pending() uint64 {
+	if metadata, err := m.msg.Metadata(); err == nil {
+		return metadata.NumPending
+	}
+	return 0
+}
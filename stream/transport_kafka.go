@@ -0,0 +1,324 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers kafka messages carry in place of the features of JetStream.
+const (
+	// HeaderSubject is the subject of a message published to the topic of its prefix,
+	// e.g. fx.rates.1.USD.EUR on the fx.rates topic
+	HeaderSubject = "Fx-Subject"
+	// HeaderRetryAt is when a retried message is due
+	HeaderRetryAt = "Fx-Retry-At"
+)
+
+const kafkaBatchTimeout = 5 * time.Millisecond
+
+// kafkaTransport runs the stream on Kafka. Subjects are published to the topic named
+// after their longest configured prefix and keyed by subject, so the messages of a
+// subject keep their order. The consumer is a consumer group committing offsets by
+// hand, an offset is only committed once it and every offset before it in its
+// partition are done. Retries are published again to the topic with the time they are
+// due and the number of deliveries, the consumer holds them back until then.
+type kafkaTransport struct {
+	writer KafkaWriter
+	reader KafkaReader
+	topics []string
+
+	mu      sync.Mutex
+	offsets map[int]*partitionOffsets
+	delayed []*kafkaMessage
+}
+
+// partitionOffsets are the offsets of a partition in the order they were fetched.
+type partitionOffsets struct {
+	fetched []int64
+	done    map[int64]bool
+}
+
+// KafkaWriter writes messages to their topics, as a kafka.Writer does.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaReader reads the messages of a consumer group, as a kafka.Reader does.
+type KafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// NewKafkaTransport returns a transport publishing with the writer to the topics, which
+// consumes the reader unless it is nil.
+func NewKafkaTransport(writer KafkaWriter, reader KafkaReader, topics ...string) Transport {
+	return newKafkaTransport(writer, reader, topics)
+}
+
+func newKafkaTransport(writer KafkaWriter, reader KafkaReader, topics []string) *kafkaTransport {
+	t := &kafkaTransport{writer: writer, reader: reader, offsets: map[int]*partitionOffsets{}}
+	for _, topic := range topics {
+		if topic != "" {
+			t.topics = append(t.topics, topic)
+		}
+	}
+	return t
+}
+
+func openKafka(fxConfig *config.Config, consume bool) (*kafkaTransport, error) {
+	brokers := strings.Split(fxConfig.Kafka.Brokers, ",")
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		BatchTimeout:           kafkaBatchTimeout,
+	}
+	topics := []string{fxConfig.Kafka.ListenTopic, fxConfig.Kafka.PublishTopic, fxConfig.Kafka.DeadLetterTopic, fxConfig.Kafka.RateEventsTopic}
+	if !consume {
+		return newKafkaTransport(writer, nil, topics), nil
+	}
+	if fxConfig.Kafka.ConsumerGroup == "" || fxConfig.Kafka.ListenTopic == "" {
+		return nil, errors.New("kafka consumer group and listen topic must be configured")
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     fxConfig.Kafka.ConsumerGroup,
+		Topic:       fxConfig.Kafka.ListenTopic,
+		StartOffset: kafka.FirstOffset,
+		// offsets are committed by hand
+		CommitInterval: 0,
+	})
+	return newKafkaTransport(writer, reader, topics), nil
+}
+
+// topic is the topic of a subject, the subject itself when no topic is a prefix of it.
+func (t *kafkaTransport) topic(subject string) string {
+	topic := subject
+	longest := 0
+	for _, candidate := range t.topics {
+		if (subject == candidate || strings.HasPrefix(subject, candidate+".")) && len(candidate) > longest {
+			topic, longest = candidate, len(candidate)
+		}
+	}
+	return topic
+}
+
+func (t *kafkaTransport) Publish(ctx context.Context, msg *Msg) error {
+	return t.writer.WriteMessages(ctx, t.kafkaMessage(msg))
+}
+
+func (t *kafkaTransport) kafkaMessage(msg *Msg) kafka.Message {
+	topic := t.topic(msg.Subject)
+	km := kafka.Message{Topic: topic, Key: []byte(msg.Subject), Value: msg.Data}
+	for key, values := range msg.Header {
+		for _, value := range values {
+			km.Headers = append(km.Headers, kafka.Header{Key: key, Value: []byte(value)})
+		}
+	}
+	if topic != msg.Subject {
+		km.Headers = append(km.Headers, kafka.Header{Key: HeaderSubject, Value: []byte(msg.Subject)})
+	}
+	return km
+}
+
+func (t *kafkaTransport) Fetch(ctx context.Context, batch int, maxWait time.Duration) (Batch, error) {
+	if t.reader == nil {
+		return nil, errors.New("the transport was not opened to consume")
+	}
+	b := newMessageBatch(batch)
+	go func() {
+		deadline := time.Now().Add(maxWait)
+		for received := 0; received < batch; {
+			if m := t.nextDue(); m != nil {
+				b.messages <- m
+				received++
+				continue
+			}
+			wait := time.Until(deadline)
+			if due, ok := t.nextDueAt(); ok && time.Until(due) < wait {
+				wait = time.Until(due)
+			}
+			if time.Until(deadline) <= 0 || ctx.Err() != nil {
+				break
+			}
+			if wait <= 0 {
+				continue
+			}
+
+			fetchCtx, cancel := context.WithTimeout(ctx, wait)
+			km, err := t.reader.FetchMessage(fetchCtx)
+			cancel()
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+					continue
+				}
+				b.done(err)
+				return
+			}
+			m := t.received(km)
+			if m.retryAt.After(time.Now()) {
+				t.delay(m)
+				continue
+			}
+			b.messages <- m
+			received++
+		}
+		b.done(nil)
+	}()
+	return b, nil
+}
+
+func kafkaHeader(km kafka.Message) Header {
+	header := Header{}
+	for _, h := range km.Headers {
+		header[h.Key] = append(header[h.Key], string(h.Value))
+	}
+	return header
+}
+
+// received tracks the offset of a fetched message until it is done.
+func (t *kafkaTransport) received(km kafka.Message) *kafkaMessage {
+	m := &kafkaMessage{t: t, msg: km, header: kafkaHeader(km)}
+	if retryAt, err := time.Parse(time.RFC3339Nano, m.header.Get(HeaderRetryAt)); err == nil {
+		m.retryAt = retryAt
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.offsets[km.Partition]
+	if p == nil || (len(p.fetched) > 0 && km.Offset <= p.fetched[len(p.fetched)-1]) {
+		// first message of the partition or the partition was assigned again
+		p = &partitionOffsets{done: map[int64]bool{}}
+		t.offsets[km.Partition] = p
+	}
+	p.fetched = append(p.fetched, km.Offset)
+	return m
+}
+
+func (t *kafkaTransport) delay(m *kafkaMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delayed = append(t.delayed, m)
+	sort.SliceStable(t.delayed, func(i, j int) bool { return t.delayed[i].retryAt.Before(t.delayed[j].retryAt) })
+}
+
+func (t *kafkaTransport) nextDue() *kafkaMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.delayed) == 0 || t.delayed[0].retryAt.After(time.Now()) {
+		return nil
+	}
+	m := t.delayed[0]
+	t.delayed = t.delayed[1:]
+	return m
+}
+
+func (t *kafkaTransport) nextDueAt() (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.delayed) == 0 {
+		return time.Time{}, false
+	}
+	return t.delayed[0].retryAt, true
+}
+
+// complete marks a message done and commits the offsets of its partition that are done
+// along with every offset before them.
+func (t *kafkaTransport) complete(m *kafkaMessage) error {
+	// commits are made under the lock so they are not reordered
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.offsets[m.msg.Partition]
+	if p == nil {
+		return nil
+	}
+	p.done[m.msg.Offset] = true
+	commit := int64(-1)
+	for len(p.fetched) > 0 && p.done[p.fetched[0]] {
+		commit = p.fetched[0]
+		delete(p.done, commit)
+		p.fetched = p.fetched[1:]
+	}
+	if commit < 0 {
+		return nil
+	}
+	return t.reader.CommitMessages(context.Background(), kafka.Message{Topic: m.msg.Topic, Partition: m.msg.Partition, Offset: commit})
+}
+
+// Close waits for pending writes and leaves the consumer group.
+func (t *kafkaTransport) Close() error {
+	err := t.writer.Close()
+	if t.reader != nil {
+		if readerErr := t.reader.Close(); err == nil {
+			err = readerErr
+		}
+	}
+	return err
+}
+
+type kafkaMessage struct {
+	t       *kafkaTransport
+	msg     kafka.Message
+	header  Header
+	retryAt time.Time
+}
+
+func (m *kafkaMessage) Subject() string {
+	if subject := m.header.Get(HeaderSubject); subject != "" {
+		return subject
+	}
+	return m.msg.Topic
+}
+
+func (m *kafkaMessage) Data() []byte         { return m.msg.Value }
+func (m *kafkaMessage) Headers() Header      { return m.header }
+func (m *kafkaMessage) Sequence() uint64     { return uint64(m.msg.Offset) }
+func (m *kafkaMessage) Timestamp() time.Time { return m.msg.Time }
+func (m *kafkaMessage) Ack() error           { return m.t.complete(m) }
+func (m *kafkaMessage) Term() error          { return m.t.complete(m) }
+
+// InProgress does nothing, the group only redelivers messages of members that left.
+func (m *kafkaMessage) InProgress() error { return nil }
+
+func (m *kafkaMessage) Deliveries() int {
+	if deliveries, err := strconv.Atoi(m.header.Get(HeaderDeliveries)); err == nil && deliveries > 0 {
+		return deliveries
+	}
+	return 1
+}
+
+func (m *kafkaMessage) Pending() uint64 {
+	if pending := m.msg.HighWaterMark - m.msg.Offset - 1; pending > 0 {
+		return uint64(pending)
+	}
+	return 0
+}
+
+// Retry publishes the message again, due after the delay, and completes this delivery.
+func (m *kafkaMessage) Retry(delay time.Duration) error {
+	retry := NewMsg(m.Subject())
+	retry.Data = m.msg.Value
+	for key, values := range m.header {
+		if key != HeaderSubject {
+			retry.Header[key] = values
+		}
+	}
+	retry.Header.Set(HeaderDeliveries, strconv.Itoa(m.Deliveries()+1))
+	retry.Header.Set(HeaderRetryAt, time.Now().Add(delay).UTC().Format(time.RFC3339Nano))
+	if err := m.t.Publish(context.Background(), retry); err != nil {
+		return fmt.Errorf("publishing the retry: %w", err)
+	}
+	return m.t.complete(m)
+}
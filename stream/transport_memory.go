@@ -0,0 +1,146 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryTransport keeps messages in memory, for tests. Messages published to Subject
+// are consumed by Fetch, every published message is also kept in Published.
+type MemoryTransport struct {
+	Subject string
+
+	mu        sync.Mutex
+	ready     chan struct{}
+	queue     []*memoryMessage
+	sequence  uint64
+	published []*Msg
+	acked     []*memoryMessage
+	termed    []*memoryMessage
+}
+
+// NewMemoryTransport returns a transport consuming the messages published to subject.
+func NewMemoryTransport(subject string) *MemoryTransport {
+	return &MemoryTransport{Subject: subject, ready: make(chan struct{})}
+}
+
+func (t *MemoryTransport) Publish(ctx context.Context, msg *Msg) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.published = append(t.published, msg)
+	if msg.Subject == t.Subject {
+		t.sequence++
+		t.enqueue(&memoryMessage{t: t, msg: msg, sequence: t.sequence, deliveries: 1, published: time.Now()})
+	}
+	return nil
+}
+
+// enqueue must be called with the lock held.
+func (t *MemoryTransport) enqueue(m *memoryMessage) {
+	t.queue = append(t.queue, m)
+	close(t.ready)
+	t.ready = make(chan struct{})
+}
+
+func (t *MemoryTransport) Fetch(ctx context.Context, batch int, maxWait time.Duration) (Batch, error) {
+	b := newMessageBatch(batch)
+	go func() {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		for received := 0; received < batch; {
+			t.mu.Lock()
+			if len(t.queue) > 0 {
+				m := t.queue[0]
+				t.queue = t.queue[1:]
+				t.mu.Unlock()
+				b.messages <- m
+				received++
+				continue
+			}
+			ready := t.ready
+			t.mu.Unlock()
+			select {
+			case <-ready:
+			case <-timer.C:
+				b.done(nil)
+				return
+			case <-ctx.Done():
+				b.done(nil)
+				return
+			}
+		}
+		b.done(nil)
+	}()
+	return b, nil
+}
+
+func (t *MemoryTransport) Close() error {
+	return nil
+}
+
+// Published returns the messages published to the subject, every subject when empty.
+func (t *MemoryTransport) Published(subject string) []*Msg {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var published []*Msg
+	for _, msg := range t.published {
+		if subject == "" || msg.Subject == subject {
+			published = append(published, msg)
+		}
+	}
+	return published
+}
+
+// Outcomes returns how many consumed messages were acknowledged and terminated.
+func (t *MemoryTransport) Outcomes() (acked int, termed int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.acked), len(t.termed)
+}
+
+type memoryMessage struct {
+	t          *MemoryTransport
+	msg        *Msg
+	sequence   uint64
+	deliveries int
+	published  time.Time
+}
+
+func (m *memoryMessage) Subject() string      { return m.msg.Subject }
+func (m *memoryMessage) Data() []byte         { return m.msg.Data }
+func (m *memoryMessage) Headers() Header      { return m.msg.Header }
+func (m *memoryMessage) Deliveries() int      { return m.deliveries }
+func (m *memoryMessage) Sequence() uint64     { return m.sequence }
+func (m *memoryMessage) InProgress() error    { return nil }
+func (m *memoryMessage) Timestamp() time.Time { return m.published }
+
+func (m *memoryMessage) Pending() uint64 {
+	m.t.mu.Lock()
+	defer m.t.mu.Unlock()
+	return uint64(len(m.t.queue))
+}
+
+func (m *memoryMessage) Ack() error {
+	m.t.mu.Lock()
+	defer m.t.mu.Unlock()
+	m.t.acked = append(m.t.acked, m)
+	return nil
+}
+
+func (m *memoryMessage) Term() error {
+	m.t.mu.Lock()
+	defer m.t.mu.Unlock()
+	m.t.termed = append(m.t.termed, m)
+	return nil
+}
+
+// Retry delivers the message again once the delay passed.
+func (m *memoryMessage) Retry(delay time.Duration) error {
+	time.AfterFunc(delay, func() {
+		m.t.mu.Lock()
+		defer m.t.mu.Unlock()
+		m.t.enqueue(&memoryMessage{t: m.t, msg: m.msg, sequence: m.sequence, deliveries: m.deliveries + 1, published: m.published})
+	})
+	return nil
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/auth"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const issuer = "https://idp.local"
+
+// writeKeySet stands in for the identity provider by writing the public key to a JWKS file.
+func writeKeySet(t *testing.T, key *rsa.PrivateKey) string {
+	jwks, _ := json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatalf("Failed to write key set: %v", err)
+	}
+	return path
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func newApp(t *testing.T, key *rsa.PrivateKey) *fiber.App {
+	keys, err := auth.LoadKeySet(writeKeySet(t, key))
+	if err != nil {
+		t.Fatalf("Failed to load key set: %v", err)
+	}
+	apiKeys, err := auth.NewAPIKeyAuthenticator([]auth.APIKey{
+		{Key: "batch-key", Subject: "batch-loader", TenantId: 2, BankIds: []int{4}, Roles: []string{"rate-editor"}},
+		{Key: "admin-key", Subject: "platform-admin", Roles: []string{auth.RoleAdmin}},
+		{Key: "unbound-key", Subject: "unbound"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create api key authenticator: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(auth.Middleware([]auth.Authenticator{
+		&auth.JWTAuthenticator{Keys: keys, Issuer: issuer, TenantClaim: "tenant_id", BanksClaim: "bank_ids", RolesClaim: "roles"},
+		apiKeys,
+	}, "/health"))
+	app.Use(auth.TenantScope())
+	app.Get("/health", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/whoami", func(c *fiber.Ctx) error {
+		principal := auth.GetPrincipal(c)
+		return c.JSON(map[string]any{
+			"subject": principal.Subject,
+			"tenant":  principal.TenantId,
+			"banks":   principal.BankIds,
+			"roles":   principal.Roles,
+			"actor":   common.ActorFromContext(c.UserContext()),
+			"scope":   scopeOf(c),
+		})
+	})
+	return app
+}
+
+func scopeOf(c *fiber.Ctx) any {
+	scope, ok := common.TenantScopeFromContext(c.UserContext())
+	if !ok {
+		return nil
+	}
+	return scope.TenantId
+}
+
+func call(t *testing.T, app *fiber.App, method string, path string, headers map[string]string) (int, map[string]any) {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	var body map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	app := newApp(t, key)
+
+	token := signToken(t, key, jwt.MapClaims{
+		"sub": "treasury-admin", "iss": issuer, "exp": time.Now().Add(time.Minute).Unix(),
+		"tenant_id": 1, "bank_ids": []int{1, 3}, "roles": "viewer converter",
+	})
+	status, body := call(t, app, "GET", "/whoami", map[string]string{"Authorization": "Bearer " + token})
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "treasury-admin", body["subject"])
+	assert.Equal(t, 1.0, body["tenant"])
+	assert.Equal(t, []any{1.0, 3.0}, body["banks"])
+	assert.Equal(t, []any{"viewer", "converter"}, body["roles"])
+	assert.Equal(t, "treasury-admin", body["actor"])
+}
+
+func TestJWTAuthenticationRejectsInvalidTokens(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	app := newApp(t, key)
+
+	for name, token := range map[string]string{
+		"expired":       signToken(t, key, jwt.MapClaims{"sub": "a", "iss": issuer, "exp": time.Now().Add(-time.Minute).Unix()}),
+		"no expiry":     signToken(t, key, jwt.MapClaims{"sub": "a", "iss": issuer}),
+		"wrong issuer":  signToken(t, key, jwt.MapClaims{"sub": "a", "iss": "other", "exp": time.Now().Add(time.Minute).Unix()}),
+		"wrong key":     signToken(t, otherKey, jwt.MapClaims{"sub": "a", "iss": issuer, "exp": time.Now().Add(time.Minute).Unix()}),
+		"not a jwt":     "abc.def.ghi",
+		"wrong subject": signToken(t, key, jwt.MapClaims{"iss": issuer, "exp": time.Now().Add(time.Minute).Unix()}),
+	} {
+		status, body := call(t, app, "GET", "/whoami", map[string]string{"Authorization": "Bearer " + token})
+		assert.Equal(t, fiber.StatusUnauthorized, status, name)
+		assert.Equal(t, "Unauthorized", body["status"], name)
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	app := newApp(t, key)
+
+	status, body := call(t, app, "GET", "/whoami", map[string]string{auth.APIKeyHeader: "batch-key"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "batch-loader", body["subject"])
+	assert.Equal(t, 2.0, body["tenant"])
+
+	status, _ = call(t, app, "GET", "/whoami", map[string]string{auth.APIKeyHeader: "guessed-key"})
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+}
+
+func TestMissingCredentials(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	app := newApp(t, key)
+
+	status, body := call(t, app, "GET", "/whoami", nil)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, "UNAUTHENTICATED", body["errors"].([]any)[0].(map[string]any)["code"])
+
+	status, _ = call(t, app, "GET", "/health", nil)
+	assert.Equal(t, fiber.StatusOK, status)
+}
+
+func TestTenantScope(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	app := newApp(t, key)
+
+	status, body := call(t, app, "GET", "/whoami", map[string]string{auth.APIKeyHeader: "batch-key"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 2.0, body["scope"])
+
+	status, body = call(t, app, "GET", "/whoami", map[string]string{auth.APIKeyHeader: "batch-key", auth.TenantHeader: "3"})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "Forbidden", body["status"])
+
+	status, body = call(t, app, "GET", "/whoami", map[string]string{auth.APIKeyHeader: "admin-key", auth.TenantHeader: "3"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 3.0, body["scope"])
+
+	status, body = call(t, app, "GET", "/whoami", map[string]string{auth.APIKeyHeader: "admin-key"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Nil(t, body["scope"])
+
+	status, body = call(t, app, "GET", "/whoami", map[string]string{auth.APIKeyHeader: "unbound-key"})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "TENANT_REQUIRED", body["errors"].([]any)[0].(map[string]any)["code"])
+}
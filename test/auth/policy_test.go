@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/service/auth"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := auth.DefaultPolicy()
+	viewer := &auth.Principal{Roles: []string{auth.RoleViewer}}
+	converter := &auth.Principal{Roles: []string{auth.RoleConverter}}
+	editor := &auth.Principal{Roles: []string{auth.RoleRateEditor}}
+	approver := &auth.Principal{Roles: []string{auth.RoleApprover}}
+	admin := &auth.Principal{Roles: []string{auth.RoleAdmin}}
+
+	assert.True(t, policy.Allows(viewer, auth.PermissionReadRates))
+	assert.False(t, policy.Allows(viewer, auth.PermissionConvert))
+	assert.True(t, policy.Allows(converter, auth.PermissionConvert))
+	assert.False(t, policy.Allows(converter, auth.PermissionWriteRates))
+	assert.True(t, policy.Allows(editor, auth.PermissionWriteRates))
+	assert.False(t, policy.Allows(editor, auth.PermissionDeleteRates))
+	assert.True(t, policy.Allows(approver, auth.PermissionDeleteRates))
+	assert.True(t, policy.Allows(admin, auth.PermissionWritePricing))
+	assert.False(t, policy.Allows(&auth.Principal{}, auth.PermissionReadRates))
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	_ = os.WriteFile(path, []byte(`{"roles": {"auditor": ["rates:read"], "treasury": ["rates:write", "rates:delete"]}}`), 0o600)
+
+	policy, err := auth.LoadPolicy(path)
+	assert.NoError(t, err)
+	assert.True(t, policy.Allows(&auth.Principal{Roles: []string{"treasury"}}, auth.PermissionDeleteRates))
+	assert.False(t, policy.Allows(&auth.Principal{Roles: []string{auth.RoleAdmin}}, auth.PermissionReadRates))
+
+	_ = os.WriteFile(path, []byte(`{"roles": {"auditor": ["rates:everything"]}}`), 0o600)
+	_, err = auth.LoadPolicy(path)
+	assert.ErrorContains(t, err, "unknown permission")
+}
+
+func TestRequirePermission(t *testing.T) {
+	apiKeys, _ := auth.NewAPIKeyAuthenticator([]auth.APIKey{
+		{Key: "viewer-key", Subject: "dashboard", TenantId: 1, Roles: []string{auth.RoleViewer}},
+		{Key: "approver-key", Subject: "treasury", TenantId: 1, Roles: []string{auth.RoleApprover}},
+	})
+	policy := auth.DefaultPolicy()
+	app := fiber.New()
+	app.Use(auth.Middleware([]auth.Authenticator{apiKeys}))
+	app.Delete("/api/forexrates/:id", policy.Require(auth.PermissionDeleteRates), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	status, body := call(t, app, "DELETE", "/api/forexrates/1", map[string]string{auth.APIKeyHeader: "viewer-key"})
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "PERMISSION_DENIED", body["errors"].([]any)[0].(map[string]any)["code"])
+
+	status, _ = call(t, app, "DELETE", "/api/forexrates/1", map[string]string{auth.APIKeyHeader: "approver-key"})
+	assert.Equal(t, fiber.StatusNoContent, status)
+}
@@ -0,0 +1,54 @@
+package dal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queryRecorder keeps the statements sent to the database.
+type queryRecorder struct {
+	queries []string
+}
+
+func (r *queryRecorder) BeforeQuery(ctx context.Context, event *pg.QueryEvent) (context.Context, error) {
+	query, err := event.FormattedQuery()
+	if err == nil {
+		r.queries = append(r.queries, string(query))
+	}
+	return ctx, nil
+}
+
+func (r *queryRecorder) AfterQuery(context.Context, *pg.QueryEvent) error {
+	return nil
+}
+
+func TestTenantQueriesOfABankScopeFilterOnTheTenantOnly(t *testing.T) {
+	db := pg.Connect(&pg.Options{
+		Addr:        "127.0.0.1:1",
+		Database:    "fx_data",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  0,
+	})
+	t.Cleanup(func() { _ = db.Close() })
+	recorder := &queryRecorder{}
+	db.AddQueryHook(recorder)
+	tenants := &dal.YugaByteTenantService{YbDB: db}
+
+	ctx := common.WithTenantScope(context.Background(), common.TenantScope{TenantId: 7, BankIds: []int{1, 2}})
+	_, _ = tenants.GetTenant(ctx, 7)
+	_, _ = tenants.GetTenants(ctx)
+	_ = tenants.DeleteTenant(ctx, 7)
+
+	require.Len(t, recorder.queries, 3)
+	for _, query := range recorder.queries {
+		assert.Contains(t, query, "tenant_id = 7")
+		assert.NotContains(t, query, "bank_id", "tenant configurations have no bank")
+	}
+}
@@ -0,0 +1,136 @@
+package dal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func rawCommand(t *testing.T, command bson.D) bson.Raw {
+	raw, err := bson.Marshal(command)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestCommandMonitorTracesMongoCommands(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	monitor := dal.CommandMonitor()
+
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: rawCommand(t, bson.D{
+			{Key: "find", Value: "forex_data"},
+			{Key: "filter", Value: bson.D{
+				{Key: "tenantId", Value: "acme"},
+				{Key: "tier", Value: bson.D{{Key: "$in", Value: bson.A{"gold", "silver"}}}},
+			}},
+			{Key: "limit", Value: 1},
+			{Key: "lsid", Value: bson.D{{Key: "id", Value: "session"}}},
+			{Key: "$db", Value: "fx_data"},
+		}),
+		DatabaseName: "fx_data",
+		CommandName:  "find",
+		RequestID:    1,
+		ConnectionID: "localhost:27017[-1]",
+	})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, ConnectionID: "localhost:27017[-1]"},
+		Reply: rawCommand(t, bson.D{
+			{Key: "cursor", Value: bson.D{{Key: "firstBatch", Value: bson.A{bson.D{{Key: "_id", Value: 1}}}}}},
+			{Key: "ok", Value: 1},
+		}),
+	})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, "find fx_data.forex_data", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	attrs := attributes(span)
+	assert.Equal(t, "mongodb", attrs["db.system"].AsString())
+	assert.Equal(t, "find", attrs["db.operation"].AsString())
+	assert.Equal(t, "forex_data", attrs["db.mongodb.collection"].AsString())
+	assert.Equal(t, `{"find":"forex_data","filter":{"tenantId":"?","tier":{"$in":["?"]}},"limit":"?"}`,
+		attrs["db.statement"].AsString(), "values are left out of the statement")
+	assert.Equal(t, int64(1), attrs["db.rows_returned"].AsInt64())
+
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command:      rawCommand(t, bson.D{{Key: "insert", Value: "quotes"}}),
+		DatabaseName: "fx_data",
+		CommandName:  "insert",
+		RequestID:    2,
+	})
+	monitor.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", RequestID: 2},
+		Failure:              "E11000 duplicate key error",
+	})
+	span = recorder.Ended()[2]
+	assert.Equal(t, "insert fx_data.quotes", span.Name())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, "E11000 duplicate key error", span.Status().Description)
+}
+
+func TestQueryHookTracesPostgresQueries(t *testing.T) {
+	recorder := recordSpans(t)
+	db := pg.Connect(&pg.Options{
+		Addr:        "127.0.0.1:1",
+		Database:    "fx_data",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  0,
+	})
+	t.Cleanup(func() { _ = db.Close() })
+	db.AddQueryHook(dal.QueryHook{Database: "fx_data"})
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	var data entity.ForexData
+	err := db.ModelContext(ctx, &data).Where("tenant_id = ?", "acme").First()
+	parent.End()
+	require.Error(t, err, "nothing listens on the address")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	attrs := attributes(span)
+	assert.Equal(t, "postgresql", attrs["db.system"].AsString())
+	assert.Equal(t, "fx_data", attrs["db.name"].AsString())
+	assert.Equal(t, "SELECT", attrs["db.operation"].AsString())
+	assert.Equal(t, "SELECT forex_data", span.Name())
+	assert.Equal(t, "forex_data", attrs["db.sql.table"].AsString())
+	assert.Contains(t, attrs["db.statement"].AsString(), "tenant_id = ?")
+	assert.NotContains(t, attrs["db.statement"].AsString(), "acme")
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
@@ -0,0 +1,81 @@
+package ingest
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/ingest"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseFixture(t *testing.T, name string) *ingest.ReferenceRates {
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer file.Close()
+
+	rates, err := ingest.ParseECB(file)
+	if err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+	return rates
+}
+
+func TestParseECBDaily(t *testing.T) {
+	rates := parseFixture(t, "eurofxref-daily.xml")
+
+	assert.Equal(t, "EUR", rates.BaseCurrency)
+	assert.Equal(t, time.Date(2023, 11, 10, 0, 0, 0, 0, time.UTC), rates.Date)
+	assert.Equal(t, 5, len(rates.Rates))
+	assert.Equal(t, 1.0683, rates.Rates["USD"])
+	assert.Equal(t, 161.89, rates.Rates["JPY"])
+}
+
+func TestParseECBHistoryUsesLatestDay(t *testing.T) {
+	rates := parseFixture(t, "eurofxref-hist-90d.xml")
+
+	assert.Equal(t, time.Date(2023, 11, 10, 0, 0, 0, 0, time.UTC), rates.Date)
+	assert.Equal(t, 0.87385, rates.Rates["GBP"])
+}
+
+func TestParseECBRejectsEmptyFeed(t *testing.T) {
+	_, err := ingest.ParseECB(strings.NewReader(`<Envelope><Cube></Cube></Envelope>`))
+	assert.Error(t, err)
+}
+
+func TestToForexRequests(t *testing.T) {
+	rates := parseFixture(t, "eurofxref-daily.xml")
+	targets, err := ingest.LoadTargets("testdata/targets.json")
+	if err != nil {
+		t.Fatalf("Failed to load targets: %v", err)
+	}
+
+	requests := ingest.ToForexRequests(rates, targets)
+
+	// tenant 1: GBP and USD (AUD is not in the feed) x 2 tiers x direct and inverse,
+	// tenant 2: every currency x 1 tier
+	assert.Equal(t, 2*2*2+5, len(requests))
+
+	first := requests[0]
+	assert.Equal(t, 1, first.TenantId)
+	assert.Equal(t, "EUR", first.BaseCurrency)
+	assert.Equal(t, "GBP", first.TargetCurrency)
+	assert.Equal(t, "1", first.Tier)
+	assert.Equal(t, 0.869481, first.BuyRate)
+	assert.Equal(t, 0.878219, first.SellRate)
+
+	inverse := requests[1]
+	assert.Equal(t, "GBP", inverse.BaseCurrency)
+	assert.Equal(t, "EUR", inverse.TargetCurrency)
+	assert.InDelta(t, 1/0.87385*0.995, inverse.BuyRate, 1e-6)
+
+	last := requests[len(requests)-1]
+	assert.Equal(t, 2, last.TenantId)
+	assert.Equal(t, 7, last.BankId)
+	assert.Equal(t, "USD", last.TargetCurrency)
+	assert.Equal(t, last.BuyRate, last.SellRate)
+	assert.Equal(t, rates.Date, *last.EffectiveDate)
+}
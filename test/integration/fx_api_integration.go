@@ -71,7 +71,7 @@ func createForexData(ts *httptest.Server, t *testing.T) string {
 		TolerancePercentage:          1,
 		EffectiveDate:                &effectiveDate,
 		ExpirationDate:               nil,
-		ContractRequirementThreshold: "",
+		ContractRequirementThreshold: nil,
 	})
 	resp, err := http.Post(ts.URL+"/api/forexrates", "application/json", bytes.NewBuffer(requestDataJSON))
 	if err != nil {
@@ -113,7 +113,7 @@ func updateForexDataById(ts *httptest.Server, t *testing.T, id string) {
 		TolerancePercentage:          1,
 		EffectiveDate:                &effectiveDate,
 		ExpirationDate:               nil,
-		ContractRequirementThreshold: "",
+		ContractRequirementThreshold: nil,
 	})
 	req, err := http.NewRequest("PUT", ts.URL+"/api/forexrates/"+id, bytes.NewBuffer(requestDataJSON))
 	if err != nil {
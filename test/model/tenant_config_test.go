@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundingPolicy(t *testing.T) {
+	var none *entity.RoundingPolicy
+	assert.Equal(t, 1.23456, none.Round(1.23456))
+
+	assert.Equal(t, 2.68, (&entity.RoundingPolicy{Decimals: 2}).Round(2.675))
+	assert.Equal(t, 2.67, (&entity.RoundingPolicy{Decimals: 2, Mode: entity.RoundingDown}).Round(2.679))
+	assert.Equal(t, 1.1, (&entity.RoundingPolicy{Decimals: 2, Mode: entity.RoundingUp}).Round(1.1))
+	assert.Equal(t, 1.11, (&entity.RoundingPolicy{Decimals: 2, Mode: entity.RoundingUp}).Round(1.101))
+	assert.Equal(t, 12.0, (&entity.RoundingPolicy{Decimals: 0, Mode: entity.RoundingHalfEven}).Round(12.5))
+
+	assert.Error(t, (&entity.RoundingPolicy{Decimals: 2, Mode: "nearest"}).Validate())
+}
+
+func TestEffectiveSettings(t *testing.T) {
+	tenant := entity.TenantConfig{
+		TenantID:     1,
+		DefaultTier:  "2",
+		AllowedPairs: []string{"USD/EUR", "USD/GBP"},
+		Rounding:     &entity.RoundingPolicy{Decimals: 2},
+		Banks: []entity.BankConfig{
+			{BankID: 7, DefaultTier: "1", AllowedPairs: []string{"USD/INR"}},
+		},
+	}
+
+	tier, pairs, rounding := tenant.EffectiveSettings(3)
+	assert.Equal(t, "2", tier)
+	assert.True(t, entity.PairAllowed(pairs, "usd", "eur"))
+	assert.Equal(t, 2, rounding.Decimals)
+
+	tier, pairs, rounding = tenant.EffectiveSettings(7)
+	assert.Equal(t, "1", tier)
+	assert.False(t, entity.PairAllowed(pairs, "USD", "EUR"))
+	assert.True(t, entity.PairAllowed(pairs, "USD", "INR"))
+	assert.Equal(t, 2, rounding.Decimals)
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestThresholdReadsLegacyStrings(t *testing.T) {
+	for value, expected := range map[string]entity.Threshold{
+		"":          {},
+		"10000":     {Amount: 10000},
+		"10000 usd": {Amount: 10000, Currency: "USD"},
+		"EUR 2500":  {Amount: 2500, Currency: "EUR"},
+	} {
+		threshold, err := entity.ParseThreshold(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, threshold)
+	}
+
+	_, err := entity.ParseThreshold("USD")
+	assert.Error(t, err)
+}
+
+func TestThresholdJSON(t *testing.T) {
+	var legacy, typed struct {
+		Threshold *entity.Threshold `json:"threshold"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"threshold":"5000 GBP"}`), &legacy))
+	assert.NoError(t, json.Unmarshal([]byte(`{"threshold":{"amount":5000,"currency":"GBP"}}`), &typed))
+
+	assert.Equal(t, typed.Threshold, legacy.Threshold)
+	assert.True(t, typed.Threshold.IsSet())
+}
+
+func TestThresholdBSON(t *testing.T) {
+	legacy, _ := bson.Marshal(bson.M{"tier": "1", "contractRequirementThreshold": "75000 INR"})
+	empty, _ := bson.Marshal(bson.M{"tier": "1", "contractRequirementThreshold": nil})
+	typed, _ := bson.Marshal(entity.ForexData{ContractRequirementThreshold: &entity.Threshold{Amount: 75000, Currency: "INR"}})
+
+	var fromLegacy, fromEmpty, fromTyped entity.ForexData
+	assert.NoError(t, bson.Unmarshal(legacy, &fromLegacy))
+	assert.NoError(t, bson.Unmarshal(empty, &fromEmpty))
+	assert.NoError(t, bson.Unmarshal(typed, &fromTyped))
+
+	assert.Equal(t, fromTyped.ContractRequirementThreshold, fromLegacy.ContractRequirementThreshold)
+	assert.False(t, fromEmpty.ContractRequirementThreshold.IsSet())
+}
+
+// columnReader holds the text of a single column as read from postgres.
+type columnReader struct {
+	*bytes.Reader
+	data []byte
+}
+
+func newColumnReader(value string) *columnReader {
+	return &columnReader{Reader: bytes.NewReader([]byte(value)), data: []byte(value)}
+}
+
+func (r *columnReader) Buffered() int                  { return r.Len() }
+func (r *columnReader) Bytes() []byte                  { return r.data }
+func (r *columnReader) ReadSlice(byte) ([]byte, error) { return r.ReadFull() }
+func (r *columnReader) Discard(n int) (int, error)     { return n, nil }
+func (r *columnReader) ReadFullTemp() ([]byte, error)  { return r.ReadFull() }
+func (r *columnReader) ReadFull() ([]byte, error)      { return io.ReadAll(r.Reader) }
+
+func TestThresholdColumn(t *testing.T) {
+	field, err := orm.GetTable(reflect.TypeOf(entity.ForexData{})).GetField("contract_requirement_threshold")
+	require.NoError(t, err)
+
+	for column, expected := range map[string]*entity.Threshold{
+		`10000`:                            {Amount: 10000},
+		`10000 USD`:                        {Amount: 10000, Currency: "USD"},
+		`{"amount":2500,"currency":"EUR"}`: {Amount: 2500, Currency: "EUR"},
+	} {
+		var record entity.ForexData
+		require.NoError(t, field.ScanValue(reflect.ValueOf(&record).Elem(), newColumnReader(column), len(column)), column)
+		assert.Equal(t, expected, record.ContractRequirementThreshold, column)
+	}
+
+	var record entity.ForexData
+	require.NoError(t, field.ScanValue(reflect.ValueOf(&record).Elem(), newColumnReader(""), -1))
+	assert.Nil(t, record.ContractRequirementThreshold, "NULL")
+
+	record.ContractRequirementThreshold = &entity.Threshold{Amount: 75000, Currency: "INR"}
+	written := string(field.AppendValue(nil, reflect.ValueOf(record), 1))
+	assert.Equal(t, `'{"amount":75000,"currency":"INR"}'`, written)
+}
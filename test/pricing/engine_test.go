@@ -0,0 +1,89 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/pricing"
+	"github.com/stretchr/testify/assert"
+)
+
+var rules = []pricing.Rule{
+	{Id: "default", SpreadBps: 100},
+	{Id: "usd-eur", BaseCurrency: "USD", TargetCurrency: "EUR", SpreadBps: 50},
+	{Id: "tenant-1", TenantId: 1, SpreadPercent: 0.25, Bands: []pricing.Band{
+		{MinAmount: 0, MaxAmount: 10000, SpreadBps: 40},
+		{MinAmount: 10000, SpreadBps: 10},
+	}},
+	{Id: "tenant-1-tier-2", TenantId: 1, Tier: "2", SpreadBps: 5},
+}
+
+func TestMatchPrefersMostSpecificRule(t *testing.T) {
+	engine, err := pricing.NewEngine("MID", rules)
+	assert.NoError(t, err)
+
+	rule, ok := engine.Match(2, 1, "GBP", "INR", "1")
+	assert.True(t, ok)
+	assert.Equal(t, "default", rule.Id)
+
+	rule, _ = engine.Match(2, 1, "usd", "eur", "1")
+	assert.Equal(t, "usd-eur", rule.Id)
+
+	rule, _ = engine.Match(1, 1, "USD", "EUR", "1")
+	assert.Equal(t, "tenant-1", rule.Id)
+
+	rule, _ = engine.Match(1, 3, "USD", "EUR", "2")
+	assert.Equal(t, "tenant-1-tier-2", rule.Id)
+}
+
+func TestApplyUsesAmountBand(t *testing.T) {
+	engine, _ := pricing.NewEngine("MID", rules)
+	rule, _ := engine.Match(1, 1, "USD", "EUR", "1")
+
+	small := engine.Apply(rule, 0.9, 500)
+	assert.Equal(t, 40.0, small.SpreadBps)
+	assert.Equal(t, 0.8964, small.BuyRate)
+	assert.Equal(t, 0.9036, small.SellRate)
+	assert.Equal(t, 10000.0, small.Band.MaxAmount)
+
+	large := engine.Apply(rule, 0.9, 10000)
+	assert.Equal(t, 10.0, large.SpreadBps)
+	assert.Equal(t, 0.8991, large.BuyRate)
+
+	rule, _ = engine.Match(2, 1, "USD", "EUR", "1")
+	flat := engine.Apply(rule, 0.9, 500)
+	assert.Nil(t, flat.Band)
+	assert.Equal(t, 0.8955, flat.BuyRate)
+}
+
+func TestSetRulesRejectsInvalidRules(t *testing.T) {
+	engine, _ := pricing.NewEngine("MID", nil)
+
+	assert.Error(t, engine.SetRules(0, []pricing.Rule{{SpreadBps: 10}}))
+	assert.Error(t, engine.SetRules(0, []pricing.Rule{{Id: "negative", SpreadBps: -1}}))
+	assert.Error(t, engine.SetRules(0, []pricing.Rule{{Id: "band", Bands: []pricing.Band{{MinAmount: 100, MaxAmount: 50}}}}))
+	assert.Empty(t, engine.Rules(0))
+
+	_, ok := engine.Match(1, 1, "USD", "EUR", "1")
+	assert.False(t, ok)
+}
+
+func TestSetRulesKeepsTheRulesOfEachTenant(t *testing.T) {
+	engine, _ := pricing.NewEngine("MID", rules)
+	assert.Len(t, engine.Rules(0), 2)
+	assert.Len(t, engine.Rules(1), 2)
+
+	assert.NoError(t, engine.SetRules(2, []pricing.Rule{{Id: "tenant-2", SpreadBps: 20}}))
+	assert.Equal(t, 2, engine.Rules(2)[0].TenantId, "rules are stored for the tenant setting them")
+	assert.Len(t, engine.Rules(1), 2, "other tenants keep their rules")
+
+	rule, _ := engine.Match(2, 1, "USD", "EUR", "1")
+	assert.Equal(t, "tenant-2", rule.Id)
+	rule, _ = engine.Match(3, 1, "USD", "EUR", "1")
+	assert.Equal(t, "usd-eur", rule.Id, "the rules of a tenant do not apply to the others")
+
+	assert.ErrorIs(t, engine.SetRules(2, []pricing.Rule{{Id: "other", TenantId: 1, SpreadBps: 1}}), common.ErrCrossTenant)
+	assert.ErrorIs(t, engine.SetRules(0, []pricing.Rule{{Id: "other", TenantId: 1, SpreadBps: 1}}), common.ErrCrossTenant)
+	assert.Equal(t, "tenant-2", engine.Rules(2)[0].Id)
+	assert.Len(t, engine.Rules(1), 2)
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+var pairs = []provider.Pair{
+	{BaseCurrency: "USD", TargetCurrency: "EUR"},
+	{BaseCurrency: "USD", TargetCurrency: "INR"},
+	{BaseCurrency: "USD", TargetCurrency: "JPY"},
+}
+
+func TestFileProvider(t *testing.T) {
+	fileProvider := &provider.FileProvider{ProviderName: "fixture", Path: "testdata/rates.json"}
+
+	rates, err := fileProvider.FetchRates(context.Background(), pairs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rates))
+	assert.Equal(t, "USD/EUR", rates[0].String())
+	assert.Equal(t, 0.9361, rates[0].Mid)
+	assert.Equal(t, time.Date(2023, 11, 10, 16, 0, 0, 0, time.UTC), rates[0].Timestamp)
+}
+
+func TestHTTPJSONProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "USD", r.URL.Query().Get("base"))
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"base":"USD","timestamp":1699632000,"rates":{"EUR":0.9361,"INR":"83.29"}}`))
+	}))
+	defer server.Close()
+
+	httpProvider := &provider.HTTPJSONProvider{
+		ProviderName:  "market",
+		Url:           server.URL + "/latest?base={base}",
+		RatePath:      "rates.{target}",
+		TimestampPath: "timestamp",
+		Headers:       map[string]string{"X-Api-Key": "secret"},
+	}
+
+	rates, err := httpProvider.FetchRates(context.Background(), pairs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 2, len(rates))
+	assert.Equal(t, 83.29, rates[1].Mid)
+	assert.Equal(t, int64(1699632000), rates[1].Timestamp.Unix())
+}
+
+func TestStatusStale(t *testing.T) {
+	now := time.Now()
+	status := provider.Status{Provider: "market", StaleAfter: time.Minute}
+	assert.True(t, status.Stale(now))
+
+	status.LastSuccess = now.Add(-30 * time.Second)
+	assert.False(t, status.Stale(now))
+
+	status.LastSuccess = now.Add(-2 * time.Minute)
+	assert.True(t, status.Stale(now))
+}
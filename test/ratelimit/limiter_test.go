@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/service/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var settings = ratelimit.Settings{
+	Default: ratelimit.Limit{RequestsPerMinute: 600, Burst: 100},
+	Rules: []ratelimit.Rule{
+		{Route: "/api/convert", Limit: ratelimit.Limit{RequestsPerMinute: 60, Burst: 2}},
+		{TenantId: 2, Limit: ratelimit.Limit{RequestsPerMinute: 6000}},
+		{TenantId: 2, Route: "/api/convert", Limit: ratelimit.Limit{DailyQuota: 2}},
+	},
+}
+
+func TestLimitFor(t *testing.T) {
+	assert.Equal(t, 100, settings.LimitFor(1, "/api/forexrates").Burst)
+	assert.Equal(t, 2, settings.LimitFor(1, "/api/convert").Burst)
+	assert.Equal(t, 6000.0, settings.LimitFor(2, "/api/forexrates").RequestsPerMinute)
+	assert.Equal(t, ratelimit.Limit{RequestsPerMinute: 60, Burst: 2, DailyQuota: 2}, settings.LimitFor(2, "/api/convert"),
+		"unset fields come from the less specific rules")
+	assert.Equal(t, ratelimit.Limit{RequestsPerMinute: 6000, Burst: 100}, settings.LimitFor(2, "/api/quotes/:id"))
+}
+
+func TestTokenBucket(t *testing.T) {
+	limiter := ratelimit.NewLimiter(settings, ratelimit.NewMemoryStore())
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	first, _ := limiter.Allow(ctx, 1, "apikey:loader", "/api/convert", now)
+	second, _ := limiter.Allow(ctx, 1, "apikey:loader", "/api/convert", now)
+	third, _ := limiter.Allow(ctx, 1, "apikey:loader", "/api/convert", now)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.False(t, third.Allowed)
+	assert.Equal(t, time.Second, third.RetryAfter)
+
+	other, _ := limiter.Allow(ctx, 1, "apikey:dashboard", "/api/convert", now)
+	assert.True(t, other.Allowed, "buckets are kept per caller")
+
+	refilled, _ := limiter.Allow(ctx, 1, "apikey:loader", "/api/convert", now.Add(time.Second))
+	assert.True(t, refilled.Allowed)
+}
+
+func TestDailyQuota(t *testing.T) {
+	limiter := ratelimit.NewLimiter(settings, ratelimit.NewMemoryStore())
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		decision, _ := limiter.Allow(ctx, 2, "jwt:treasury", "/api/convert", now)
+		assert.True(t, decision.Allowed)
+	}
+	decision, _ := limiter.Allow(ctx, 2, "jwt:other", "/api/convert", now)
+	assert.False(t, decision.Allowed, "quotas are shared by the callers of a tenant")
+	assert.True(t, decision.QuotaExceeded)
+	assert.Equal(t, time.Hour, decision.RetryAfter)
+
+	decision, _ = limiter.Allow(ctx, 2, "jwt:treasury", "/api/convert", now.Add(time.Hour))
+	assert.True(t, decision.Allowed)
+}
+
+func TestHandler(t *testing.T) {
+	limiter := ratelimit.NewLimiter(settings, ratelimit.NewMemoryStore())
+	app := fiber.New()
+	app.Get("/api/convert", limiter.Handler(), func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	for i, expected := range []int{fiber.StatusOK, fiber.StatusOK, fiber.StatusTooManyRequests} {
+		res, err := app.Test(httptest.NewRequest("GET", "/api/convert", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, res.StatusCode, "request %d", i)
+		assert.Equal(t, "2", res.Header.Get("RateLimit-Limit"))
+		if expected == fiber.StatusTooManyRequests {
+			assert.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+			assert.Equal(t, "1", res.Header.Get("Retry-After"))
+		}
+	}
+}
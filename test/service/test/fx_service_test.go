@@ -1,8 +1,19 @@
 package test
 
 import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
 	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/pricing"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MockDbService struct {
@@ -14,60 +25,219 @@ func (m *MockDbService) Init(credentials ...string) {
 	panic("implement me")
 }
 
-func (m *MockDbService) Get(filter any) ([]entity.ForexData, error) {
+func (m *MockDbService) Get(ctx context.Context, filter any) ([]entity.ForexData, error) {
 	args := m.Called(filter)
 	return args.Get(0).([]entity.ForexData), nil
 }
 
-func (m *MockDbService) CreateOne(document entity.ForexData) (entity.ForexData, error) {
+func (m *MockDbService) CreateOne(ctx context.Context, document entity.ForexData) (entity.ForexData, error) {
 	args := m.Called(document)
 	return args.Get(0).(entity.ForexData), nil
 }
 
-func (m *MockDbService) UpdateOne(document any, filter any) (any, error) {
+func (m *MockDbService) UpdateOne(ctx context.Context, document any, filter any) (any, error) {
 	args := m.Called(filter, document)
 	return args.Get(0).(entity.ForexData), nil
 }
 
-func (m *MockDbService) UpdateOneById(id any) (any, error) {
-	args := m.Called(id)
+func (m *MockDbService) UpdateOneById(ctx context.Context, document any, id any) (any, error) {
+	args := m.Called(id, document)
 	return args.Get(0).(entity.ForexData), nil
 }
-func (m *MockDbService) DeleteOne(filter any) (int64, error) {
+func (m *MockDbService) DeleteOne(ctx context.Context, filter any) (entity.ForexData, error) {
 	args := m.Called(filter)
-	return args.Get(0).(int64), nil
+	return args.Get(0).(entity.ForexData), args.Error(1)
 }
 
-func (m *MockDbService) GetOne(filter any) (entity.ForexData, error) {
+func (m *MockDbService) GetOne(ctx context.Context, filter any) (entity.ForexData, error) {
 	args := m.Called(filter)
-	return args.Get(0).(entity.ForexData), nil
+	return args.Get(0).(entity.ForexData), args.Error(1)
+}
+
+func (m *MockDbService) GetOneById(ctx context.Context, id int) (entity.ForexData, error) {
+	args := m.Called(id)
+	return args.Get(0).(entity.ForexData), args.Error(1)
+}
+
+func (m *MockDbService) BulkInsert(ctx context.Context, documents []entity.ForexData) (entity.ForexData, error) {
+	args := m.Called(documents)
+	return args.Get(0).(entity.ForexData), args.Error(1)
+}
+
+func (m *MockDbService) UpsertOne(ctx context.Context, document entity.ForexData, filter any) (entity.ForexData, error) {
+	args := m.Called(document, filter)
+	return args.Get(0).(entity.ForexData), args.Error(1)
+}
+
+func forexData(tier string, buyRate float64, sellRate float64) entity.ForexData {
+	return entity.ForexData{
+		ID:                           primitive.ObjectID{},
+		Tier:                         tier,
+		DirectIndirectFlag:           "Y",
+		Multiplier:                   1,
+		BuyRate:                      buyRate,
+		SellRate:                     sellRate,
+		TolerancePercentage:          0,
+		EffectiveDate:                nil,
+		ExpirationDate:               nil,
+		ContractRequirementThreshold: nil,
+		TenantID:                     1,
+		BankID:                       1,
+		BaseCurrency:                 "USD",
+		TargetCurrency:               "EUR",
+		CreatedDate:                  time.Time{},
+		DocVersion:                   0,
+		UpdatedDate:                  time.Time{},
+	}
+}
+
+func withTier(tier string) any {
+	return mock.MatchedBy(func(r request.FxDataRequest) bool { return r.Tier == tier })
+}
+
+func TestGetConvertedRate(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", mock.Anything).Return(forexData("1", 2, 3), nil)
+	service := bal.Fx_service{DbService: mockRepo}
+	ctx := context.Background()
+
+	res := service.GetConvertedRate(&ctx, 1, 1, 1000, "USD", "EUR", "1")
+
+	assert.Equal(t, 2000.00, res.Data.ConvertedAmount)
+	assert.Nil(t, res.Data.AppliedRule)
+}
+
+func TestGetConvertedRateWithPricingRule(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", withTier("MID")).Return(forexData("MID", 0.9, 0.9), nil)
+	engine, _ := pricing.NewEngine("MID", []pricing.Rule{
+		{Id: "tier-1", Tier: "1", SpreadBps: 50, Bands: []pricing.Band{{MinAmount: 100000, SpreadBps: 20}}},
+	})
+	service := bal.Fx_service{DbService: mockRepo, Pricing: engine}
+	ctx := context.Background()
+
+	res := service.GetConvertedRate(&ctx, 1, 1, 1000, "USD", "EUR", "1")
+
+	assert.Equal(t, 0.8955, res.Data.Rate)
+	assert.InDelta(t, 895.5, res.Data.ConvertedAmount, 1e-9)
+	assert.Equal(t, "tier-1", res.Data.AppliedRule.RuleId)
+	assert.Equal(t, 50.0, res.Data.AppliedRule.SpreadBps)
+	assert.Nil(t, res.Data.AppliedRule.BandMinAmount)
+
+	res = service.GetConvertedRate(&ctx, 1, 1, 250000, "USD", "EUR", "1")
+
+	assert.Equal(t, 0.8982, res.Data.Rate)
+	assert.Equal(t, 100000.0, *res.Data.AppliedRule.BandMinAmount)
+	mockRepo.AssertNotCalled(t, "GetOne", withTier("1"))
 }
 
-//
-//func TestGetConvertedRate(t *testing.T) {
-//	mockRepo := new(MockDbService)
-//	mockRepo.On("GetOne", mock.Anything).Return(entity.ForexData{
-//		ID:                           primitive.ObjectID{},
-//		Tier:                         "1",
-//		DirectIndirectFlag:           "Y",
-//		Multiplier:                   1,
-//		BuyRate:                      2,
-//		SellRate:                     3,
-//		TolerancePercentage:          0,
-//		EffectiveDate:                nil,
-//		ExpirationDate:               nil,
-//		ContractRequirementThreshold: "",
-//		TenantID:                     1,
-//		BankID:                       1,
-//		BaseCurrency:                 "USD",
-//		TargetCurrency:               "EUR",
-//		CreatedDate:                  time.Time{},
-//		DocVersion:                   0,
-//		UpdatedDate:                  time.Time{},
-//	}, nil)
-//	service := bal.Fx_service{DbService: mockRepo}
-//
-//	res := service.GetConvertedRate(1, 1, 1000, "USD", "EUR", "1")
-//
-//	assert.Equal(t, 2000.00, res.Data.ConvertedAmount)
-//}
+func withThreshold(record entity.ForexData, amount float64, currency string) entity.ForexData {
+	record.ContractRequirementThreshold = &entity.Threshold{Amount: amount, Currency: currency}
+	return record
+}
+
+func TestGetConvertedRateAboveContractThreshold(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", mock.Anything).Return(withThreshold(forexData("1", 2, 3), 5000, "EUR"), nil)
+	service := bal.Fx_service{DbService: mockRepo}
+	ctx := context.Background()
+
+	res := service.GetConvertedRate(&ctx, 1, 1, 2000, "USD", "EUR", "1")
+	assert.Equal(t, response.Success, res.Status)
+	assert.False(t, res.Data.ContractRequired)
+
+	res = service.GetConvertedRate(&ctx, 1, 1, 3000, "USD", "EUR", "1")
+	assert.Equal(t, response.Success, res.Status)
+	assert.True(t, res.Data.ContractRequired)
+	assert.Equal(t, 5000.0, res.Data.ContractRequirementThreshold.Amount)
+
+	service.ContractThresholdMode = bal.ContractThresholdReject
+	res = service.GetConvertedRate(&ctx, 1, 1, 3000, "USD", "EUR", "1")
+	assert.Equal(t, response.ContractRequired, res.Status)
+	assert.Nil(t, res.Data)
+	assert.Equal(t, "CONTRACT_REQUIRED", (*res.Errors)[0].Code)
+}
+
+func TestGetConvertedRateResolvesTierByAmount(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("Get", mock.Anything).Return([]entity.ForexData{
+		forexData("3", 2.3, 2.4),
+		withThreshold(forexData("2", 2.2, 2.3), 100000, ""),
+		withThreshold(forexData("1", 2.1, 2.2), 10000, "USD"),
+	}, nil)
+	mockRepo.On("GetOne", withTier("1")).Return(withThreshold(forexData("1", 2.1, 2.2), 10000, "USD"), nil)
+	mockRepo.On("GetOne", withTier("2")).Return(withThreshold(forexData("2", 2.2, 2.3), 100000, ""), nil)
+	mockRepo.On("GetOne", withTier("3")).Return(forexData("3", 2.3, 2.4), nil)
+	service := bal.Fx_service{DbService: mockRepo}
+	ctx := context.Background()
+
+	assert.Equal(t, "1", service.GetConvertedRate(&ctx, 1, 1, 500, "USD", "EUR", "").Data.Tier)
+	assert.Equal(t, "2", service.GetConvertedRate(&ctx, 1, 1, 10001, "USD", "EUR", bal.AutoTier).Data.Tier)
+
+	res := service.GetConvertedRate(&ctx, 1, 1, 250000, "USD", "EUR", bal.AutoTier)
+	assert.Equal(t, "3", res.Data.Tier)
+	assert.Equal(t, 2.3, res.Data.Rate)
+	assert.False(t, res.Data.ContractRequired)
+}
+
+func TestResolvedTierBandsAreInTheBaseCurrency(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("Get", mock.Anything).Return([]entity.ForexData{
+		// 9000 EUR is 10000 USD, above the 5000 USD band of tier 1
+		withThreshold(forexData("2", 0.9, 0.9), 9000, "EUR"),
+		withThreshold(forexData("1", 0.9, 0.9), 5000, "USD"),
+		withThreshold(forexData("MID", 0.9, 0.9), 1, ""),
+		forexData("3", 0.9, 0.9),
+	}, nil)
+	mockRepo.On("GetOne", withTier("1")).Return(forexData("1", 0.9, 0.9), nil)
+	mockRepo.On("GetOne", withTier("2")).Return(forexData("2", 0.9, 0.9), nil)
+	engine, _ := pricing.NewEngine("MID", nil)
+	service := bal.Fx_service{DbService: mockRepo, Pricing: engine}
+	ctx := context.Background()
+
+	assert.Equal(t, "1", service.GetConvertedRate(&ctx, 1, 1, 500, "USD", "EUR", bal.AutoTier).Data.Tier,
+		"the base tier record has no band")
+	assert.Equal(t, "2", service.GetConvertedRate(&ctx, 1, 1, 8000, "USD", "EUR", bal.AutoTier).Data.Tier)
+
+	mockRepo = new(MockDbService)
+	mockRepo.On("Get", mock.Anything).Return([]entity.ForexData{
+		withThreshold(forexData("1", 0.9, 0.9), 5000, "GBP"),
+		forexData("2", 0.9, 0.9),
+	}, nil)
+	service = bal.Fx_service{DbService: mockRepo}
+	res := service.GetConvertedRate(&ctx, 1, 1, 500, "USD", "EUR", bal.AutoTier)
+	assert.NotEqual(t, response.Success, res.Status, "a threshold in a third currency cannot be banded")
+	mockRepo.AssertNotCalled(t, "GetOne", mock.Anything)
+}
+
+func TestGetConvertedRateIsScopedToTenant(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", mock.MatchedBy(func(r request.FxDataRequest) bool { return r.TenantId == 7 })).Return(forexData("1", 2, 3), nil)
+	service := bal.Fx_service{DbService: mockRepo}
+	ctx := common.WithTenantScope(context.Background(), common.TenantScope{TenantId: 7, BankIds: []int{1}})
+
+	res := service.GetConvertedRate(&ctx, 0, 1, 1000, "USD", "EUR", "1")
+	assert.Equal(t, response.Success, res.Status)
+
+	res = service.GetConvertedRate(&ctx, 8, 1, 1000, "USD", "EUR", "1")
+	assert.Equal(t, response.Forbidden, res.Status)
+	assert.Equal(t, "TENANT_FORBIDDEN", (*res.Errors)[0].Code)
+
+	res = service.GetConvertedRate(&ctx, 7, 2, 1000, "USD", "EUR", "1")
+	assert.Equal(t, response.Forbidden, res.Status)
+	mockRepo.AssertNumberOfCalls(t, "GetOne", 1)
+}
+
+func TestCreateForexDataIsScopedToTenant(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("CreateOne", mock.MatchedBy(func(r entity.ForexData) bool { return r.TenantID == 7 })).Return(forexData("1", 2, 3), nil)
+	service := bal.Fx_service{DbService: mockRepo}
+	ctx := common.WithTenantScope(context.Background(), common.TenantScope{TenantId: 7})
+
+	res := service.CreateForexData(&ctx, request.CreateForexDataRequest{BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1"})
+	assert.Equal(t, response.Success, res.Status)
+
+	res = service.CreateForexData(&ctx, request.CreateForexDataRequest{TenantId: 8, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1"})
+	assert.Equal(t, response.Forbidden, res.Status)
+	mockRepo.AssertNumberOfCalls(t, "CreateOne", 1)
+}
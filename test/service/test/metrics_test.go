@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var (
+	metricReader     *sdkmetric.ManualReader
+	metricReaderOnce sync.Once
+)
+
+// collect returns the metrics recorded so far. Instruments are bound to the first meter
+// provider installed, so every test of the package shares the same reader.
+func collect(t *testing.T) metricdata.ResourceMetrics {
+	metricReaderOnce.Do(func() {
+		metricReader = sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metricReader)))
+	})
+	var metrics metricdata.ResourceMetrics
+	require.NoError(t, metricReader.Collect(context.Background(), &metrics))
+	return metrics
+}
+
+func findMetric(metrics metricdata.ResourceMetrics, name string) (metricdata.Metrics, bool) {
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
+
+func counted(metrics metricdata.ResourceMetrics, name string, attrs ...attribute.KeyValue) int64 {
+	m, _ := findMetric(metrics, name)
+	sum, _ := m.Data.(metricdata.Sum[int64])
+	set := attribute.NewSet(attrs...)
+	for _, point := range sum.DataPoints {
+		if point.Attributes.Equals(&set) {
+			return point.Value
+		}
+	}
+	return 0
+}
+
+func histogram(metrics metricdata.ResourceMetrics, name string, attrs ...attribute.KeyValue) (uint64, float64) {
+	m, _ := findMetric(metrics, name)
+	data, _ := m.Data.(metricdata.Histogram[float64])
+	set := attribute.NewSet(attrs...)
+	for _, point := range data.DataPoints {
+		if point.Attributes.Equals(&set) {
+			return point.Count, point.Sum
+		}
+	}
+	return 0, 0
+}
+
+func TestConversionsAreMeasured(t *testing.T) {
+	before := collect(t)
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", mock.MatchedBy(func(r request.FxDataRequest) bool { return r.TargetCurrency == "EUR" })).Return(forexData("1", 2, 3), nil)
+	mockRepo.On("GetOne", mock.Anything).Return(entity.ForexData{}, mongo.ErrNoDocuments)
+	service := bal.Fx_service{DbService: mockRepo}
+	ctx := context.Background()
+
+	service.GetConvertedRate(&ctx, 31, 1, 1000, "USD", "EUR", "1")
+	service.GetConvertedRate(&ctx, 31, 1, 500, "USD", "EUR", "1")
+	service.GetConvertedRate(&ctx, 31, 1, 1000, "USD", "JPY", "1")
+
+	after := collect(t)
+	delta := func(name string, attrs ...attribute.KeyValue) int64 {
+		return counted(after, name, attrs...) - counted(before, name, attrs...)
+	}
+	tenant, tier := attribute.String("tenant", "31"), attribute.String("tier", "1")
+	eur, jpy := attribute.String("pair", "USD/EUR"), attribute.String("pair", "USD/JPY")
+	assert.Equal(t, int64(2), delta("fx.conversions", tenant, eur, tier, attribute.String("status", "Success")))
+	assert.Equal(t, int64(1), delta("fx.conversions", tenant, jpy, tier, attribute.String("status", "NotFound")))
+	assert.Equal(t, int64(1), delta("fx.rate.lookup.misses", tenant, jpy, tier, attribute.String("reason", "not_found")))
+
+	countBefore, sumBefore := histogram(before, "fx.conversion.amount", tenant, eur, tier)
+	count, sum := histogram(after, "fx.conversion.amount", tenant, eur, tier)
+	assert.Equal(t, uint64(2), count-countBefore)
+	assert.Equal(t, 1500.0, sum-sumBefore)
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type memoryQuoteService struct {
+	mutex  sync.Mutex
+	quotes map[string]entity.Quote
+}
+
+func (m *memoryQuoteService) CreateQuote(ctx context.Context, quote entity.Quote) (entity.Quote, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.quotes[quote.ID] = quote
+	return quote, nil
+}
+
+func (m *memoryQuoteService) GetQuote(ctx context.Context, id string) (entity.Quote, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	quote, ok := m.quotes[id]
+	if !ok {
+		return quote, dal.ErrNoRecord
+	}
+	return quote, nil
+}
+
+func (m *memoryQuoteService) RedeemQuote(ctx context.Context, id string, redeemedBy string, now time.Time) (entity.Quote, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	quote, ok := m.quotes[id]
+	if !ok || quote.Status != entity.QuoteStatusIssued || !quote.ExpiresAt.After(now) {
+		return quote, dal.ErrNoRecord
+	}
+	quote.Status = entity.QuoteStatusRedeemed
+	quote.RedeemedAt = &now
+	quote.RedeemedBy = redeemedBy
+	m.quotes[id] = quote
+	return quote, nil
+}
+
+func newQuoteTestService(ttl time.Duration) bal.Fx_service {
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", mock.Anything).Return(forexData("1", 0.9, 0.95), nil)
+	return bal.Fx_service{
+		DbService:    mockRepo,
+		QuoteService: &memoryQuoteService{quotes: map[string]entity.Quote{}},
+		QuoteTTL:     ttl,
+	}
+}
+
+var quoteRequest = request.QuoteRequest{
+	TenantId:       1,
+	BankId:         1,
+	BaseCurrency:   "USD",
+	TargetCurrency: "EUR",
+	Tier:           "1",
+	Side:           "sell",
+	Amount:         1000,
+}
+
+func TestIssueAndRedeemQuote(t *testing.T) {
+	service := newQuoteTestService(time.Minute)
+	ctx := context.Background()
+
+	issued := service.IssueQuote(&ctx, quoteRequest)
+	assert.Equal(t, response.Success, issued.Status)
+	assert.Equal(t, entity.QuoteSideSell, issued.Data.Side)
+	assert.Equal(t, 0.95, issued.Data.Rate)
+	assert.Equal(t, 950.0, issued.Data.ConvertedAmount)
+	assert.Equal(t, entity.QuoteStatusIssued, issued.Data.Status)
+
+	redeemed := service.RedeemQuote(&ctx, issued.Data.QuoteId)
+	assert.Equal(t, response.Success, redeemed.Status)
+	assert.Equal(t, entity.QuoteStatusRedeemed, redeemed.Data.Status)
+	assert.Equal(t, 0.95, redeemed.Data.Rate)
+
+	again := service.RedeemQuote(&ctx, issued.Data.QuoteId)
+	assert.Equal(t, response.Conflict, again.Status)
+	assert.Equal(t, "QUOTE_ALREADY_REDEEMED", (*again.Errors)[0].Code)
+
+	unknown := service.RedeemQuote(&ctx, "unknown")
+	assert.Equal(t, response.NotFound, unknown.Status)
+}
+
+func TestRedeemExpiredQuote(t *testing.T) {
+	service := newQuoteTestService(time.Millisecond)
+	ctx := context.Background()
+
+	issued := service.IssueQuote(&ctx, quoteRequest)
+	time.Sleep(5 * time.Millisecond)
+
+	expired := service.RedeemQuote(&ctx, issued.Data.QuoteId)
+	assert.Equal(t, response.Conflict, expired.Status)
+	assert.Equal(t, "QUOTE_EXPIRED", (*expired.Errors)[0].Code)
+}
+
+func TestIssueQuoteRejectsInvalidSide(t *testing.T) {
+	service := newQuoteTestService(time.Minute)
+	ctx := context.Background()
+	invalid := quoteRequest
+	invalid.Side = "HOLD"
+
+	assert.Equal(t, response.BadRequest, service.IssueQuote(&ctx, invalid).Status)
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type memoryRateEventService struct {
+	dal.RateEventDBService
+	events []entity.RateEvent
+	err    error
+	// transactional reports whether the last events were added in a transaction
+	transactional bool
+}
+
+func (m *memoryRateEventService) AddRateEvents(ctx context.Context, events []entity.RateEvent) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.transactional = ctx.Value(txKey{}) != nil
+	m.events = append(m.events, events...)
+	return nil
+}
+
+type txKey struct{}
+
+// memoryTransactor marks the context of its transactions and counts the rolled back ones.
+type memoryTransactor struct {
+	rolledBack int
+}
+
+func (m *memoryTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(context.WithValue(ctx, txKey{}, true))
+	if err != nil {
+		m.rolledBack++
+	}
+	return err
+}
+
+func TestRateChangesAreRecorded(t *testing.T) {
+	mockRepo := new(MockDbService)
+	outbox := &memoryRateEventService{}
+	service := bal.Fx_service{DbService: mockRepo, RateEvents: outbox}
+	ctx := context.Background()
+
+	created := forexData("1", 2, 3)
+	created.DocVersion = 1
+	mockRepo.On("CreateOne", mock.Anything).Return(created, nil)
+	res := service.CreateForexData(&ctx, request.CreateForexDataRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", BuyRate: 2, SellRate: 3})
+	assert.Equal(t, response.Success, res.Status)
+
+	before := forexData("1", 2, 3)
+	before.DocVersion = 1
+	after := forexData("1", 2.1, 3.1)
+	after.DocVersion = 2
+	mockRepo.On("GetOne", withTier("1")).Return(before, nil)
+	mockRepo.On("UpsertOne", mock.Anything, mock.Anything).Return(after, nil)
+	upserted := service.UpsertForexData(&ctx, request.CreateForexDataRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", BuyRate: 2.1, SellRate: 3.1})
+	assert.Equal(t, response.Success, upserted.Status)
+
+	mockRepo.On("DeleteOne", "abc").Return(after, nil)
+	assert.Equal(t, response.Success, service.DeleteForexRateById(&ctx, "abc").Status)
+
+	assert.Len(t, outbox.events, 3)
+	assert.Equal(t, entity.RateEventCreated, outbox.events[0].Type)
+	assert.Nil(t, outbox.events[0].Before)
+	assert.Equal(t, 2.0, outbox.events[0].After.BuyRate)
+
+	update := outbox.events[1]
+	assert.Equal(t, entity.RateEventUpdated, update.Type)
+	assert.Equal(t, entity.RateEventVersion, update.Version)
+	assert.Equal(t, 2, update.DocVersion)
+	assert.Equal(t, 2.0, update.Before.BuyRate)
+	assert.Equal(t, 2.1, update.After.BuyRate)
+	assert.Equal(t, "USD", update.BaseCurrency)
+	assert.NotEqual(t, outbox.events[0].ID, update.ID)
+
+	assert.Equal(t, entity.RateEventDeleted, outbox.events[2].Type)
+	assert.Nil(t, outbox.events[2].After)
+	assert.Equal(t, 2, outbox.events[2].Before.DocVersion)
+}
+
+func TestFailedRateChangesAreNotRecorded(t *testing.T) {
+	mockRepo := new(MockDbService)
+	outbox := &memoryRateEventService{}
+	service := bal.Fx_service{DbService: mockRepo, RateEvents: outbox}
+	ctx := context.Background()
+
+	mockRepo.On("DeleteOne", "abc").Return(entity.ForexData{}, dal.ErrNoRecord)
+	assert.Equal(t, response.NotFound, service.DeleteForexRateById(&ctx, "abc").Status)
+
+	mockRepo.On("GetOne", mock.Anything).Return(entity.ForexData{}, dal.ErrNoRecord)
+	mockRepo.On("UpsertOne", mock.Anything, mock.Anything).Return(entity.ForexData{}, errors.New("connection refused"))
+	res := service.UpsertForexData(&ctx, request.CreateForexDataRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1"})
+	assert.Equal(t, response.InternalError, res.Status)
+
+	assert.Empty(t, outbox.events)
+}
+
+func TestRateChangesFailWhenTheirEventsCannotBeStored(t *testing.T) {
+	mockRepo := new(MockDbService)
+	outbox := &memoryRateEventService{err: errors.New("connection refused")}
+	transactions := &memoryTransactor{}
+	service := bal.Fx_service{DbService: mockRepo, RateEvents: outbox, Transactions: transactions}
+	ctx := context.Background()
+
+	mockRepo.On("CreateOne", mock.Anything).Return(forexData("1", 2, 3), nil)
+	res := service.CreateForexData(&ctx, request.CreateForexDataRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", BuyRate: 2, SellRate: 3})
+	assert.Equal(t, response.InternalError, res.Status)
+
+	mockRepo.On("DeleteOne", "abc").Return(forexData("1", 2, 3), nil)
+	assert.Equal(t, response.InternalError, service.DeleteForexRateById(&ctx, "abc").Status)
+	assert.Equal(t, 2, transactions.rolledBack)
+}
+
+func TestRateUpdatesRecordTheRateBefore(t *testing.T) {
+	mockRepo := new(MockDbService)
+	outbox := &memoryRateEventService{}
+	service := bal.Fx_service{DbService: mockRepo, RateEvents: outbox, Transactions: &memoryTransactor{}}
+	ctx := context.Background()
+
+	before := forexData("1", 2, 3)
+	after := forexData("1", 2.01, 3)
+	mockRepo.On("GetOne", withTier("1")).Return(before, nil)
+	mockRepo.On("UpdateOne", mock.Anything, mock.Anything).Return(after, nil)
+	res := service.UpdateForexRate(&ctx, 1, 1, "USD", "EUR", "1", request.UpdateForexDataRequest{BuyRate: 2.01, SellRate: 3})
+	assert.Equal(t, response.Success, res.Status)
+
+	assert.Len(t, outbox.events, 1)
+	assert.True(t, outbox.transactional)
+	assert.Equal(t, entity.RateEventUpdated, outbox.events[0].Type)
+	assert.Equal(t, 2.0, outbox.events[0].Before.BuyRate)
+	assert.Equal(t, 2.01, outbox.events[0].After.BuyRate)
+}
+
+func TestRateUpdatesByIdRecordTheRateBefore(t *testing.T) {
+	mockRepo := new(MockDbService)
+	outbox := &memoryRateEventService{}
+	service := bal.Fx_service{DbService: mockRepo, RateEvents: outbox, Transactions: &memoryTransactor{}}
+	ctx := context.Background()
+
+	before := forexData("1", 2, 3)
+	after := forexData("1", 2.01, 3)
+	after.DocVersion = before.DocVersion + 1
+	mockRepo.On("GetOneById", 7).Return(before, nil)
+	mockRepo.On("UpdateOneById", 7, mock.Anything).Return(after, nil)
+	res := service.UpdateForexById(&ctx, 7, request.UpdateForexDataRequest{BuyRate: 2.01, SellRate: 3})
+	assert.Equal(t, response.Success, res.Status)
+
+	assert.Len(t, outbox.events, 1)
+	assert.True(t, outbox.transactional)
+	assert.Equal(t, entity.RateEventUpdated, outbox.events[0].Type)
+	assert.Equal(t, before.DocVersion, outbox.events[0].Before.DocVersion)
+	assert.Equal(t, after.DocVersion, outbox.events[0].After.DocVersion)
+}
+
+func TestRateUpdatesWriteTheRequestedRates(t *testing.T) {
+	mockRepo := new(MockDbService)
+	service := bal.Fx_service{DbService: mockRepo}
+	ctx := context.Background()
+
+	before := forexData("1", 2, 3)
+	before.ContractRequirementThreshold = &entity.Threshold{Amount: 10000}
+	mockRepo.On("GetOne", withTier("1")).Return(before, nil)
+	mockRepo.On("UpdateOne", mock.Anything, mock.Anything).Return(before, nil)
+
+	invalid := service.UpdateForexRate(&ctx, 1, 1, "USD", "EUR", "1", request.UpdateForexDataRequest{BuyRate: 2.5})
+	assert.Equal(t, response.BadRequest, invalid.Status)
+	mockRepo.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything)
+
+	res := service.UpdateForexRate(&ctx, 1, 1, "USD", "EUR", "1", request.UpdateForexDataRequest{BuyRate: 2.5, SellRate: 3.5})
+	assert.Equal(t, response.Success, res.Status)
+	written := mockRepo.Calls[len(mockRepo.Calls)-1].Arguments.Get(1).(entity.ForexData)
+	assert.Equal(t, 2.5, written.BuyRate)
+	assert.Equal(t, 3.5, written.SellRate)
+	assert.Equal(t, before.ContractRequirementThreshold, written.ContractRequirementThreshold, "a threshold that is not given is kept")
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type memoryTenantService struct {
+	mutex   sync.Mutex
+	tenants map[int]entity.TenantConfig
+}
+
+func (m *memoryTenantService) CreateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.tenants[tenant.TenantID]; ok {
+		return tenant, dal.ErrDuplicateRecord
+	}
+	m.tenants[tenant.TenantID] = tenant
+	return tenant, nil
+}
+
+func (m *memoryTenantService) GetTenant(ctx context.Context, tenantId int) (entity.TenantConfig, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	tenant, ok := m.tenants[tenantId]
+	if !ok {
+		return tenant, dal.ErrNoRecord
+	}
+	return tenant, nil
+}
+
+func (m *memoryTenantService) GetTenants(ctx context.Context) ([]entity.TenantConfig, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var tenants []entity.TenantConfig
+	for _, tenant := range m.tenants {
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+func (m *memoryTenantService) UpdateTenant(ctx context.Context, tenant entity.TenantConfig) (entity.TenantConfig, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stored, ok := m.tenants[tenant.TenantID]
+	if !ok {
+		return tenant, dal.ErrNoRecord
+	}
+	if stored.DocVersion != tenant.DocVersion {
+		return tenant, dal.ErrVersionConflict
+	}
+	tenant.DocVersion++
+	m.tenants[tenant.TenantID] = tenant
+	return tenant, nil
+}
+
+func (m *memoryTenantService) DeleteTenant(ctx context.Context, tenantId int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.tenants[tenantId]; !ok {
+		return dal.ErrNoRecord
+	}
+	delete(m.tenants, tenantId)
+	return nil
+}
+
+func withPair(base string, target string) any {
+	return mock.MatchedBy(func(r request.FxDataRequest) bool {
+		return r.BaseCurrency == base && r.TargetCurrency == target
+	})
+}
+
+func newTenantTestService(mockRepo *MockDbService) bal.Fx_service {
+	service := bal.Fx_service{
+		DbService:     mockRepo,
+		TenantService: &memoryTenantService{tenants: map[int]entity.TenantConfig{}},
+	}
+	ctx := context.Background()
+	service.CreateTenantConfig(&ctx, request.TenantConfigRequest{
+		TenantId:      1,
+		Name:          "First Bank Group",
+		BaseCurrency:  "usd",
+		PivotCurrency: "USD",
+		DefaultTier:   "2",
+		AllowedPairs:  []string{"USD/EUR", "EUR/INR"},
+		Rounding:      &entity.RoundingPolicy{Decimals: 2},
+		Banks:         []entity.BankConfig{{BankID: 1, Name: "Retail"}},
+	})
+	return service
+}
+
+func TestConversionUsesTenantConfig(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", withTier("2")).Return(forexData("2", 0.91234, 0.95), nil)
+	service := newTenantTestService(mockRepo)
+	ctx := context.Background()
+
+	res := service.GetConvertedRate(&ctx, 1, 1, 1000, "", "EUR", "")
+	assert.Equal(t, response.Success, res.Status)
+	assert.Equal(t, "USD", res.Data.BaseCurrency)
+	assert.Equal(t, "2", res.Data.Tier)
+	assert.Equal(t, 912.34, res.Data.ConvertedAmount)
+
+	res = service.GetConvertedRate(&ctx, 1, 1, 1000, "USD", "GBP", "")
+	assert.Equal(t, response.BadRequest, res.Status)
+	assert.Equal(t, "PAIR_NOT_ALLOWED", (*res.Errors)[0].Code)
+
+	res = service.GetConvertedRate(&ctx, 1, 2, 1000, "USD", "EUR", "")
+	assert.Equal(t, "BANK_NOT_CONFIGURED", (*res.Errors)[0].Code)
+}
+
+func TestConversionCrossesThroughPivotCurrency(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("GetOne", withPair("EUR", "INR")).Return(entity.ForexData{}, dal.ErrNoRecord)
+	eurUsd := forexData("2", 1.1, 1.2)
+	eurUsd.BaseCurrency, eurUsd.TargetCurrency = "EUR", "USD"
+	mockRepo.On("GetOne", withPair("EUR", "USD")).Return(eurUsd, nil)
+	usdInr := forexData("2", 80, 82)
+	usdInr.TargetCurrency = "INR"
+	mockRepo.On("GetOne", withPair("USD", "INR")).Return(usdInr, nil)
+	service := newTenantTestService(mockRepo)
+	ctx := context.Background()
+
+	res := service.GetConvertedRate(&ctx, 1, 1, 100, "EUR", "INR", "")
+	assert.Equal(t, response.Success, res.Status)
+	assert.InDelta(t, 88.0, res.Data.Rate, 1e-9)
+	assert.Equal(t, 8800.0, res.Data.ConvertedAmount)
+}
+
+func TestRateDataIsValidatedAgainstTenantConfig(t *testing.T) {
+	mockRepo := new(MockDbService)
+	mockRepo.On("CreateOne", mock.Anything).Return(forexData("2", 0.9, 0.95), nil)
+	service := newTenantTestService(mockRepo)
+	ctx := context.Background()
+
+	res := service.CreateForexData(&ctx, request.CreateForexDataRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "JPY", Tier: "2"})
+	assert.Equal(t, response.BadRequest, res.Status)
+	assert.Equal(t, "PAIR_NOT_ALLOWED", (*res.Errors)[0].Code)
+
+	res = service.CreateForexData(&ctx, request.CreateForexDataRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "2"})
+	assert.Equal(t, response.Success, res.Status)
+
+	res = service.CreateForexData(&ctx, request.CreateForexDataRequest{TenantId: 9, BankId: 1, BaseCurrency: "USD", TargetCurrency: "JPY", Tier: "2"})
+	assert.Equal(t, response.Success, res.Status, "tenants without configuration are not checked")
+}
+
+func TestTenantConfigCrud(t *testing.T) {
+	service := newTenantTestService(new(MockDbService))
+	ctx := context.Background()
+
+	duplicate := service.CreateTenantConfig(&ctx, request.TenantConfigRequest{TenantId: 1, BaseCurrency: "USD"})
+	assert.Equal(t, response.Conflict, duplicate.Status)
+
+	invalid := service.CreateTenantConfig(&ctx, request.TenantConfigRequest{TenantId: 2, BaseCurrency: "dollar", AllowedPairs: []string{"USDEUR"}})
+	assert.Equal(t, response.BadRequest, invalid.Status)
+	assert.Len(t, *invalid.Errors, 2)
+
+	unversioned := service.UpsertBankConfig(&ctx, 1, 2, 0, entity.BankConfig{Name: "Corporate", DefaultTier: "1"})
+	assert.Equal(t, response.BadRequest, unversioned.Status)
+
+	updated := service.UpsertBankConfig(&ctx, 1, 2, 1, entity.BankConfig{Name: "Corporate", DefaultTier: "1"})
+	assert.Equal(t, response.Success, updated.Status)
+	assert.Len(t, updated.Data.Banks, 2)
+	assert.Equal(t, 2, updated.Data.DocVersion)
+
+	stale := service.DeleteBankConfig(&ctx, 1, 1, 1)
+	assert.Equal(t, response.Conflict, stale.Status, "the tenant was changed since version 1")
+
+	updated = service.DeleteBankConfig(&ctx, 1, 1, 2)
+	assert.Equal(t, []entity.BankConfig{{BankID: 2, Name: "Corporate", DefaultTier: "1"}}, updated.Data.Banks)
+
+	replaced := service.UpdateTenantConfig(&ctx, 1, request.TenantConfigRequest{BaseCurrency: "USD", DocVersion: 2})
+	assert.Equal(t, response.Conflict, replaced.Status)
+	replaced = service.UpdateTenantConfig(&ctx, 1, request.TenantConfigRequest{BaseCurrency: "USD", DocVersion: 3})
+	assert.Equal(t, response.Success, replaced.Status)
+	assert.Equal(t, 4, replaced.Data.DocVersion)
+
+	assert.Equal(t, response.Success, service.DeleteTenantConfig(&ctx, 1).Status)
+	assert.Equal(t, response.NotFound, service.GetTenantConfig(&ctx, 1).Status)
+}
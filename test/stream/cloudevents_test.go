@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eventMessage(t *testing.T, event stream.Event, mode string) *fakeMsg {
+	msg := stream.NewMsg("fx.convert")
+	require.NoError(t, stream.EncodeEvent(msg, event, mode))
+	return &fakeMsg{header: msg.Header, data: msg.Data}
+}
+
+func TestEventsRoundTripInBothModes(t *testing.T) {
+	event, err := stream.NewEvent(stream.EventRequest, 1, "/tests", request.NatConvertRequest{TenantID: 1, Amount: 10.5})
+	require.NoError(t, err)
+	event.CorrelationID = "order-7"
+
+	for _, mode := range []string{stream.ModeStructured, stream.ModeBinary} {
+		msg := stream.NewMsg("fx.convert")
+		require.NoError(t, stream.EncodeEvent(msg, event, mode))
+		decoded, err := stream.DecodeEvent(msg.Header, msg.Data)
+		require.NoError(t, err, mode)
+		assert.Equal(t, event.ID, decoded.ID, mode)
+		assert.Equal(t, "urn:aci-fx:schema:fx.request:v1", decoded.DataSchema, mode)
+		assert.Equal(t, "order-7", decoded.CorrelationID, mode)
+		assert.True(t, event.Time.Equal(*decoded.Time), mode)
+		assert.JSONEq(t, string(event.Data), string(decoded.Data), mode)
+		version, err := decoded.Version()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, version)
+	}
+
+	legacy, err := stream.DecodeEvent(nil, []byte(`{"tenantId":1}`))
+	require.NoError(t, err)
+	assert.True(t, legacy.Legacy())
+
+	_, err = stream.DecodeEvent(stream.Header{"Content-Type": {stream.ContentTypeCloudEvents}}, []byte(`{"specversion":"1.0"}`))
+	assert.Error(t, err)
+	assert.Error(t, stream.EncodeEvent(stream.NewMsg("fx.convert"), event, "xml"))
+}
+
+func TestHandlerDecodesRequestEvents(t *testing.T) {
+	publisher := &fakePublisher{}
+	handler := newHandler(publisher)
+	handler.EventMode = stream.ModeBinary
+
+	sentAt := time.Now().Add(-time.Second).UTC()
+	v1, err := stream.NewEvent(stream.EventRequest, 1, "/tests",
+		request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 100})
+	require.NoError(t, err)
+	handler.Handle(eventMessage(t, v1, stream.ModeStructured))
+	assert.Equal(t, 90.0, lastReply(t, publisher).Data.ConvertedAmount)
+
+	binary, err := stream.NewEvent(stream.EventRequest, 1, "/tests",
+		request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 200, InitiatedOn: sentAt.UnixMilli()})
+	require.NoError(t, err)
+	msg := eventMessage(t, binary, stream.ModeBinary)
+	handler.Handle(msg)
+	assert.Equal(t, int32(1), msg.acks.Load())
+	reply := lastReply(t, publisher)
+	assert.Equal(t, 180.0, reply.Data.ConvertedAmount)
+	assert.Equal(t, sentAt.UnixMilli(), reply.Data.InitiatedOn)
+
+	replyMsg := publisher.replies[len(publisher.replies)-1]
+	assert.Equal(t, binary.ID, replyMsg.Header.Get("ce-correlationid"), "the reply is correlated with the request")
+	assert.Equal(t, stream.SchemaURI(stream.EventReply, stream.ReplyVersion), replyMsg.Header.Get("ce-dataschema"))
+}
+
+func TestHandlerDeadLettersUnsupportedEvents(t *testing.T) {
+	publisher := &fakePublisher{}
+	handler := newHandler(publisher)
+
+	v2, err := stream.NewEvent(stream.EventRequest, 2, "/tests", map[string]any{"tenantId": 1})
+	require.NoError(t, err)
+	unknown, err := stream.NewEvent("fx.refund", 1, "/tests", map[string]any{"tenantId": 1})
+	require.NoError(t, err)
+
+	for _, event := range []stream.Event{v2, unknown} {
+		msg := eventMessage(t, event, stream.ModeBinary)
+		handler.Handle(msg)
+		assert.Equal(t, int32(1), msg.terms.Load(), event.Type)
+		reply := lastReply(t, publisher)
+		assert.Equal(t, response.BadRequest, reply.Status)
+		assert.Equal(t, "INVALID_MESSAGE", (*reply.Errors)[0].Code)
+	}
+	require.Len(t, publisher.msgs, 2)
+	assert.Equal(t, v2.ID, publisher.msgs[0].Header.Get("ce-id"), "dead letters keep the event attributes")
+}
+
+func TestEventSchemasArePublished(t *testing.T) {
+	for _, eventSchema := range stream.EventSchemas {
+		data, ok := stream.Schema(eventSchema.Type, eventSchema.Version)
+		require.True(t, ok, eventSchema.Type)
+		var schema map[string]any
+		require.NoError(t, json.Unmarshal(data, &schema), eventSchema.Type)
+		assert.Equal(t, stream.SchemaURI(eventSchema.Type, eventSchema.Version), schema["$id"])
+	}
+	_, ok := stream.Schema(stream.EventRequest, 2)
+	assert.False(t, ok)
+}
@@ -0,0 +1,228 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+type rateStore struct {
+	dal.DBService[entity.ForexData]
+	rates map[string]entity.ForexData
+}
+
+func (r rateStore) GetOne(ctx context.Context, filter any) (entity.ForexData, error) {
+	f := filter.(request.FxDataRequest)
+	rate, ok := r.rates[f.BaseCurrency+"/"+f.TargetCurrency]
+	if !ok {
+		return rate, dal.ErrNoRecord
+	}
+	return rate, nil
+}
+
+type fakeMsg struct {
+	header     stream.Header
+	data       []byte
+	delivered  int
+	delays     []time.Duration
+	acks       atomic.Int32
+	naks       atomic.Int32
+	terms      atomic.Int32
+	inProgress atomic.Int32
+}
+
+func (m *fakeMsg) Data() []byte           { return m.data }
+func (m *fakeMsg) Subject() string        { return "fx.convert" }
+func (m *fakeMsg) Headers() stream.Header { return m.header }
+func (m *fakeMsg) Sequence() uint64       { return 42 }
+func (m *fakeMsg) Pending() uint64        { return 3 }
+func (m *fakeMsg) Timestamp() time.Time   { return time.Time{} }
+func (m *fakeMsg) Ack() error             { m.acks.Add(1); return nil }
+func (m *fakeMsg) Term() error            { m.terms.Add(1); return nil }
+func (m *fakeMsg) InProgress() error      { m.inProgress.Add(1); return nil }
+func (m *fakeMsg) Retry(delay time.Duration) error {
+	m.naks.Add(1)
+	m.delays = append(m.delays, delay)
+	return nil
+}
+func (m *fakeMsg) Deliveries() int {
+	if m.delivered == 0 {
+		return 1
+	}
+	return m.delivered
+}
+
+// fakePublisher keeps the replies published to fx.converted apart from the other
+// messages, e.g. dead letters, err fails replies and msgErr the other messages.
+type fakePublisher struct {
+	err      error
+	msgErr   error
+	subjects []string
+	replies  []*stream.Msg
+	msgs     []*stream.Msg
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, msg *stream.Msg) error {
+	if msg.Subject == "fx.converted" {
+		if p.err != nil {
+			return p.err
+		}
+		p.subjects = append(p.subjects, msg.Subject)
+		p.replies = append(p.replies, msg)
+		return nil
+	}
+	if p.msgErr != nil {
+		return p.msgErr
+	}
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func newHandler(publisher *fakePublisher) *stream.Handler {
+	store := rateStore{rates: map[string]entity.ForexData{
+		"USD/EUR": {Tier: "1", BuyRate: 0.9, SellRate: 0.95, TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR"},
+	}}
+	return &stream.Handler{
+		FxService: &bal.Fx_service{DbService: store},
+		Publisher: publisher,
+		Subject:   "fx.converted",
+		HostName:  "worker-1",
+
+		DeadLetterSubject: "fx.deadletter",
+		MaxDeliver:        3,
+		RetryBackoff:      time.Second,
+		MaxRetryBackoff:   3 * time.Second,
+	}
+}
+
+func message(t *testing.T, convertRequest request.NatConvertRequest) *fakeMsg {
+	data, err := json.Marshal(convertRequest)
+	assert.NoError(t, err)
+	return &fakeMsg{data: data}
+}
+
+func lastReply(t *testing.T, publisher *fakePublisher) response.ResponseWithSimpleData[response.ConversionResponse] {
+	var reply response.ResponseWithSimpleData[response.ConversionResponse]
+	msg := publisher.replies[len(publisher.replies)-1]
+	event, err := stream.DecodeEvent(msg.Header, msg.Data)
+	assert.NoError(t, err)
+	assert.Equal(t, stream.EventReply, event.Type)
+	assert.NoError(t, json.Unmarshal(event.Data, &reply))
+	return reply
+}
+
+func TestHandlerConvertsAndAcks(t *testing.T) {
+	publisher := &fakePublisher{}
+	handler := newHandler(publisher)
+	initiatedOn := time.Now().UnixMilli()
+	msg := message(t, request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 100, InitiatedOn: initiatedOn})
+
+	handler.Handle(msg)
+
+	assert.Equal(t, int32(1), msg.acks.Load())
+	assert.Equal(t, []string{"fx.converted"}, publisher.subjects)
+	reply := lastReply(t, publisher)
+	assert.Equal(t, response.Success, reply.Status)
+	assert.Equal(t, 90.0, reply.Data.ConvertedAmount)
+	assert.Equal(t, 0.9, reply.Data.Rate)
+	assert.Equal(t, initiatedOn, reply.Data.InitiatedOn)
+	assert.Equal(t, "worker-1", reply.Data.HostName)
+}
+
+func TestHandlerRepliesWithErrors(t *testing.T) {
+	publisher := &fakePublisher{}
+	handler := newHandler(publisher)
+
+	msg := &fakeMsg{data: []byte("{not json")}
+	handler.Handle(msg)
+	reply := lastReply(t, publisher)
+	assert.Equal(t, response.BadRequest, reply.Status)
+	assert.Equal(t, "INVALID_MESSAGE", (*reply.Errors)[0].Code)
+
+	msg = message(t, request.NatConvertRequest{TenantID: 1, BaseCurrency: "USD", Amount: 100})
+	handler.Handle(msg)
+	reply = lastReply(t, publisher)
+	assert.Equal(t, "INVALID_INPUT", (*reply.Errors)[0].Code)
+	assert.Equal(t, "bankId, targetCurrency must be provided", (*reply.Errors)[0].Details)
+
+	msg = message(t, request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "JPY", Tier: "1", Amount: 100})
+	handler.Handle(msg)
+	assert.Equal(t, int32(1), msg.acks.Load())
+	reply = lastReply(t, publisher)
+	assert.Equal(t, response.NotFound, reply.Status)
+	assert.Nil(t, reply.Data)
+
+	msg = message(t, request.NatConvertRequest{Action: "refund"})
+	handler.Handle(msg)
+	assert.Equal(t, response.BadRequest, lastReply(t, publisher).Status)
+}
+
+func TestHandlerDeadLettersPoisonMessages(t *testing.T) {
+	publisher := &fakePublisher{}
+	handler := newHandler(publisher)
+	msg := &fakeMsg{data: []byte("{not json"), delivered: 1}
+
+	handler.Handle(msg)
+
+	assert.Equal(t, int32(0), msg.acks.Load())
+	assert.Equal(t, int32(0), msg.naks.Load())
+	assert.Equal(t, int32(1), msg.terms.Load())
+	assert.Len(t, publisher.replies, 1, "the requester still gets an error reply")
+	assert.Len(t, publisher.msgs, 1)
+	deadLetter := publisher.msgs[0]
+	assert.Equal(t, "fx.deadletter", deadLetter.Subject)
+	assert.Equal(t, []byte("{not json"), deadLetter.Data)
+	assert.Equal(t, "fx.convert", deadLetter.Header.Get(stream.HeaderOriginalSubject))
+	assert.Equal(t, stream.ErrorClassPoison, deadLetter.Header.Get(stream.HeaderErrorClass))
+	assert.Equal(t, "1", deadLetter.Header.Get(stream.HeaderDeliveries))
+	assert.Equal(t, "42", deadLetter.Header.Get(stream.HeaderStreamSequence))
+	assert.NotEmpty(t, deadLetter.Header.Get(stream.HeaderError))
+}
+
+func TestHandlerRetriesTransientFailuresWithBackoff(t *testing.T) {
+	publisher := &fakePublisher{err: errors.New("no responders")}
+	handler := newHandler(publisher)
+	convertRequest := request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 100}
+
+	var delays []time.Duration
+	for delivered := 1; delivered < 3; delivered++ {
+		msg := message(t, convertRequest)
+		msg.delivered = delivered
+		handler.Handle(msg)
+		assert.Equal(t, int32(0), msg.acks.Load())
+		assert.Equal(t, int32(0), msg.terms.Load())
+		delays = append(delays, msg.delays...)
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, delays)
+	assert.Empty(t, publisher.msgs)
+
+	msg := message(t, convertRequest)
+	msg.delivered = 3
+	handler.Handle(msg)
+	assert.Equal(t, int32(1), msg.terms.Load())
+	assert.Len(t, publisher.msgs, 1)
+	assert.Equal(t, stream.ErrorClassTransient, publisher.msgs[0].Header.Get(stream.HeaderErrorClass))
+}
+
+func TestHandlerKeepsMessagesThatCannotBeDeadLettered(t *testing.T) {
+	publisher := &fakePublisher{msgErr: errors.New("no responders")}
+	handler := newHandler(publisher)
+	msg := &fakeMsg{data: []byte("{not json"), delivered: 6}
+
+	handler.Handle(msg)
+
+	assert.Equal(t, int32(0), msg.terms.Load())
+	assert.Equal(t, []time.Duration{3 * time.Second}, msg.delays)
+	assert.Empty(t, publisher.replies, "messages delivered more than MaxDeliver times are not handled again")
+}
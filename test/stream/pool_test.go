@@ -0,0 +1,121 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBatch struct {
+	msgs chan stream.Message
+}
+
+func (b fakeBatch) Messages() <-chan stream.Message { return b.msgs }
+func (b fakeBatch) Error() error                    { return nil }
+
+type fakeFetcher struct {
+	mutex    sync.Mutex
+	queue    []*fakeMsg
+	requests []int
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, batch int, maxWait time.Duration) (stream.Batch, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.requests = append(f.requests, batch)
+	msgs := make(chan stream.Message, batch)
+	for len(f.queue) > 0 && len(msgs) < batch {
+		msgs <- f.queue[0]
+		f.queue = f.queue[1:]
+	}
+	if len(msgs) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(msgs)
+	return fakeBatch{msgs: msgs}, nil
+}
+
+// slowPublisher takes a while to publish and tracks how many replies are published at once.
+type slowPublisher struct {
+	delay     time.Duration
+	mutex     sync.Mutex
+	current   int
+	peak      int
+	published int
+}
+
+func (p *slowPublisher) Publish(ctx context.Context, msg *stream.Msg) error {
+	p.mutex.Lock()
+	p.current++
+	if p.current > p.peak {
+		p.peak = p.current
+	}
+	p.mutex.Unlock()
+
+	time.Sleep(p.delay)
+
+	p.mutex.Lock()
+	p.current--
+	p.published++
+	p.mutex.Unlock()
+	return nil
+}
+
+func TestPoolBoundsMessagesInFlight(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	var msgs []*fakeMsg
+	for i := 0; i < 10; i++ {
+		msg := message(t, request.NatConvertRequest{Action: "unknown"})
+		msgs = append(msgs, msg)
+		fetcher.queue = append(fetcher.queue, msg)
+	}
+	publisher := &slowPublisher{delay: 60 * time.Millisecond}
+	handler := newHandler(nil)
+	handler.Publisher = publisher
+	pool := &stream.Pool{Handler: handler, Workers: 3, FetchMaxWait: 10 * time.Millisecond, Heartbeat: 20 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Run(ctx, fetcher)
+	}()
+	assert.Eventually(t, func() bool {
+		publisher.mutex.Lock()
+		defer publisher.mutex.Unlock()
+		return publisher.published == len(msgs)
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.LessOrEqual(t, publisher.peak, 3)
+	fetcher.mutex.Lock()
+	assert.Equal(t, 3, fetcher.requests[0], "the first fetch is sized to every free worker")
+	for _, size := range fetcher.requests {
+		assert.LessOrEqual(t, size, 3)
+	}
+	fetcher.mutex.Unlock()
+	for _, msg := range msgs {
+		assert.Equal(t, int32(1), msg.acks.Load())
+		assert.Greater(t, msg.inProgress.Load(), int32(0), "slow messages are reported in progress")
+	}
+}
+
+func TestPoolWaitsForMessagesInFlightOnStop(t *testing.T) {
+	msg := message(t, request.NatConvertRequest{Action: "unknown"})
+	fetcher := &fakeFetcher{queue: []*fakeMsg{msg}}
+	handler := newHandler(nil)
+	handler.Publisher = &slowPublisher{delay: 50 * time.Millisecond}
+	pool := &stream.Pool{Handler: handler, Workers: 1, FetchMaxWait: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	pool.Run(ctx, fetcher)
+
+	assert.Equal(t, int32(1), msg.acks.Load())
+}
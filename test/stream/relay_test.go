@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryOutbox struct {
+	events []entity.RateEvent
+}
+
+func (o *memoryOutbox) AddRateEvents(ctx context.Context, events []entity.RateEvent) error {
+	o.events = append(o.events, events...)
+	return nil
+}
+
+func (o *memoryOutbox) GetPendingRateEvents(ctx context.Context, limit int) ([]entity.RateEvent, error) {
+	var pending []entity.RateEvent
+	for _, event := range o.events {
+		if event.PublishedAt == nil && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].OccurredAt.Before(pending[j].OccurredAt) })
+	return pending, nil
+}
+
+func (o *memoryOutbox) MarkRateEventPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	for i := range o.events {
+		if o.events[i].ID == id {
+			o.events[i].PublishedAt = &publishedAt
+			return nil
+		}
+	}
+	return errors.New("no record found")
+}
+
+func (o *memoryOutbox) DeletePublishedRateEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	var kept []entity.RateEvent
+	for _, event := range o.events {
+		if event.PublishedAt == nil || !event.PublishedAt.Before(publishedBefore) {
+			kept = append(kept, event)
+		}
+	}
+	deleted := int64(len(o.events) - len(kept))
+	o.events = kept
+	return deleted, nil
+}
+
+func rateEvent(id string, base string, target string) entity.RateEvent {
+	return entity.RateEvent{ID: id, Type: entity.RateEventUpdated, Version: entity.RateEventVersion, TenantID: 7,
+		BaseCurrency: base, TargetCurrency: target, DocVersion: 2,
+		Before: &entity.RateValues{BuyRate: 1.1}, After: &entity.RateValues{BuyRate: 1.2}, OccurredAt: time.Now()}
+}
+
+func TestRelayPublishesPendingEvents(t *testing.T) {
+	outbox := &memoryOutbox{events: []entity.RateEvent{rateEvent("1", "usd", "eur"), rateEvent("2", "USD", "INR"), rateEvent("3", "EUR", "GBP")}}
+	publisher := &fakePublisher{}
+	relay := &stream.Relay{Outbox: outbox, Publisher: publisher, SubjectPrefix: "fx.rates", Interval: 10 * time.Millisecond, BatchSize: 2}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	relay.Run(ctx)
+
+	assert.Len(t, publisher.msgs, 3)
+	msg := publisher.msgs[0]
+	assert.Equal(t, "fx.rates.7.USD.EUR", msg.Subject)
+	assert.Equal(t, "1", msg.Header.Get(stream.HeaderMsgID))
+	cloudEvent, err := stream.DecodeEvent(msg.Header, msg.Data)
+	assert.NoError(t, err)
+	assert.Equal(t, stream.EventRateChanged, cloudEvent.Type)
+	assert.Equal(t, "1", cloudEvent.ID)
+	assert.Equal(t, "USD/EUR", cloudEvent.Subject)
+	var event entity.RateEvent
+	assert.NoError(t, json.Unmarshal(cloudEvent.Data, &event))
+	assert.Equal(t, 1.1, event.Before.BuyRate)
+	assert.Equal(t, 1.2, event.After.BuyRate)
+	assert.Equal(t, 2, event.DocVersion)
+	for _, event := range outbox.events {
+		assert.NotNil(t, event.PublishedAt)
+	}
+}
+
+func TestRelayKeepsEventsWhileNatsIsDown(t *testing.T) {
+	outbox := &memoryOutbox{events: []entity.RateEvent{rateEvent("1", "USD", "EUR")}}
+	publisher := &fakePublisher{msgErr: errors.New("nats: no servers available")}
+	relay := &stream.Relay{Outbox: outbox, Publisher: publisher, SubjectPrefix: "fx.rates", Interval: 5 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	relay.Run(ctx)
+	cancel()
+	assert.Nil(t, outbox.events[0].PublishedAt)
+
+	publisher.msgErr = nil
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	relay.Run(ctx)
+	cancel()
+	assert.NotNil(t, outbox.events[0].PublishedAt)
+	assert.Len(t, publisher.msgs, 1)
+}
+
+func TestRelayDeletesPublishedEventsPastTheirRetention(t *testing.T) {
+	published := time.Now().Add(-2 * time.Hour)
+	old := rateEvent("1", "USD", "EUR")
+	old.PublishedAt = &published
+	outbox := &memoryOutbox{events: []entity.RateEvent{old, rateEvent("2", "USD", "INR")}}
+	publisher := &fakePublisher{msgErr: errors.New("nats: no servers available")}
+	relay := &stream.Relay{Outbox: outbox, Publisher: publisher, SubjectPrefix: "fx.rates", Interval: 5 * time.Millisecond, Retention: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	relay.Run(ctx)
+	cancel()
+	assert.Len(t, outbox.events, 1, "pending events are kept")
+	assert.Equal(t, "2", outbox.events[0].ID)
+}
@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/service/dal"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (r rateStore) Get(ctx context.Context, filter any) ([]entity.ForexData, error) {
+	f := filter.(request.FxDataRequest)
+	rate, ok := r.rates[f.BaseCurrency+"/"+f.TargetCurrency]
+	if !ok {
+		return nil, nil
+	}
+	return []entity.ForexData{rate}, nil
+}
+
+type quoteStore struct {
+	dal.QuoteDBService
+	err error
+}
+
+func (q quoteStore) CreateQuote(ctx context.Context, quote entity.Quote) (entity.Quote, error) {
+	return quote, q.err
+}
+
+func startServer(t *testing.T) *nats.Conn {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	require.NoError(t, err)
+	go ns.Start()
+	require.True(t, ns.ReadyForConnections(5*time.Second))
+	t.Cleanup(ns.Shutdown)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func startService(t *testing.T, nc *nats.Conn, quotes quoteStore) {
+	handler := newHandler(nil)
+	fxService := &bal.Fx_service{DbService: handler.FxService.DbService, QuoteService: quotes}
+	svc, err := stream.AddService(nc, fxService, "fx.svc", "fx-rates")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = svc.Stop() })
+}
+
+func requestReply[T any](t *testing.T, nc *nats.Conn, subject string, body any) (T, *nats.Msg) {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	msg, err := nc.Request(subject, data, 2*time.Second)
+	require.NoError(t, err)
+	var reply T
+	require.NoError(t, json.Unmarshal(msg.Data, &reply))
+	return reply, msg
+}
+
+func TestServiceConvertsOverRequestReply(t *testing.T) {
+	nc := startServer(t)
+	startService(t, nc, quoteStore{})
+
+	conversion, _ := requestReply[response.ResponseWithSimpleData[response.ConversionResponse]](t, nc, "fx.svc.convert",
+		request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 100})
+	assert.Equal(t, response.Success, conversion.Status)
+	assert.Equal(t, 90.0, conversion.Data.ConvertedAmount)
+
+	missing, _ := requestReply[response.ResponseWithSimpleData[response.ConversionResponse]](t, nc, "fx.svc.convert",
+		request.NatConvertRequest{TenantID: 1, BaseCurrency: "USD", Amount: 100})
+	assert.Equal(t, response.BadRequest, missing.Status)
+	assert.Equal(t, "INVALID_INPUT", (*missing.Errors)[0].Code)
+
+	notFound, _ := requestReply[response.ResponseWithSimpleData[response.ConversionResponse]](t, nc, "fx.svc.convert",
+		request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "JPY", Tier: "1", Amount: 100})
+	assert.Equal(t, response.NotFound, notFound.Status)
+
+	rates, _ := requestReply[response.ResponseWithArrayData[response.ForexDataResponse]](t, nc, "fx.svc.rate",
+		request.NatRateRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR"})
+	assert.Equal(t, response.Success, rates.Status)
+	assert.Equal(t, 0.95, (*rates.Data)[0].SellRate)
+
+	quote, _ := requestReply[response.ResponseWithSimpleData[response.QuoteResponse]](t, nc, "fx.svc.quote",
+		request.QuoteRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Side: "SELL", Amount: 100})
+	assert.Equal(t, response.Success, quote.Status)
+	assert.Equal(t, 0.95, quote.Data.Rate)
+	assert.NotEmpty(t, quote.Data.QuoteId)
+}
+
+func TestServiceReportsErrors(t *testing.T) {
+	nc := startServer(t)
+	startService(t, nc, quoteStore{err: errors.New("connection refused")})
+
+	msg, err := nc.Request("fx.svc.convert", []byte("{not json"), 2*time.Second)
+	require.NoError(t, err)
+	var invalid response.ResponseWithSimpleData[response.ConversionResponse]
+	require.NoError(t, json.Unmarshal(msg.Data, &invalid))
+	assert.Equal(t, "INVALID_MESSAGE", (*invalid.Errors)[0].Code)
+
+	quote, msg := requestReply[response.ResponseWithSimpleData[response.QuoteResponse]](t, nc, "fx.svc.quote",
+		request.QuoteRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Side: "BUY", Amount: 100})
+	assert.Equal(t, response.InternalError, quote.Status)
+	assert.Equal(t, "500", msg.Header.Get(micro.ErrorCodeHeader))
+}
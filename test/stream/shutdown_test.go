@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowQuotes holds every quote until release is closed.
+type slowQuotes struct {
+	quoteStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (q slowQuotes) CreateQuote(ctx context.Context, quote entity.Quote) (entity.Quote, error) {
+	q.started <- struct{}{}
+	<-q.release
+	return quote, nil
+}
+
+func startSlowService(t *testing.T, nc *nats.Conn) (*stream.Service, slowQuotes) {
+	quotes := slowQuotes{started: make(chan struct{}, 1), release: make(chan struct{})}
+	fxConfig := &config.Config{}
+	fxConfig.Nats.Url = nc.ConnectedUrl()
+	fxConfig.Nats.ServicePrefix = "fx.svc"
+	fxConfig.Nats.ServiceQueueGroup = "fx-rates"
+	fxService := &bal.Fx_service{DbService: newHandler(nil).FxService.DbService, QuoteService: quotes}
+	svc, err := stream.StartService(fxConfig, fxService)
+	require.NoError(t, err)
+	// the service answers once the server registered its endpoints
+	rate, _ := json.Marshal(request.NatRateRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR"})
+	require.Eventually(t, func() bool {
+		_, err := nc.Request("fx.svc.rate", rate, time.Second)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return svc, quotes
+}
+
+func requestQuote(nc *nats.Conn) <-chan *nats.Msg {
+	replies := make(chan *nats.Msg, 1)
+	data, _ := json.Marshal(request.QuoteRequest{TenantId: 1, BankId: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Side: "SELL", Amount: 100})
+	go func() {
+		msg, _ := nc.Request("fx.svc.quote", data, 5*time.Second)
+		replies <- msg
+	}()
+	return replies
+}
+
+func TestServiceShutdownAnswersRequestsInFlight(t *testing.T) {
+	nc := startServer(t)
+	svc, quotes := startSlowService(t, nc)
+
+	replies := requestQuote(nc)
+	<-quotes.started
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- svc.Shutdown(context.Background()) }()
+
+	time.Sleep(50 * time.Millisecond)
+	close(quotes.release)
+	require.NoError(t, <-shutdown)
+
+	msg := <-replies
+	require.NotNil(t, msg)
+	var quote response.ResponseWithSimpleData[response.QuoteResponse]
+	require.NoError(t, json.Unmarshal(msg.Data, &quote))
+	assert.Equal(t, response.Success, quote.Status)
+
+	_, err := nc.Request("fx.svc.quote", msg.Data, 200*time.Millisecond)
+	assert.Error(t, err, "no request is taken after shutdown")
+}
+
+func TestServiceShutdownGivesUpAtTheDeadline(t *testing.T) {
+	nc := startServer(t)
+	svc, quotes := startSlowService(t, nc)
+	defer close(quotes.release)
+
+	requestQuote(nc)
+	<-quotes.started
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	assert.ErrorIs(t, svc.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 2*time.Second)
+}
@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/model/entity"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording the ended spans for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// remoteParent returns the headers of a message sent within a trace of another service.
+func remoteParent(t *testing.T) (stream.Header, trace.SpanContext) {
+	ctx, span := otel.Tracer("payments").Start(context.Background(), "payment")
+	span.End()
+	header := stream.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	require.NotEmpty(t, header)
+	return header, span.SpanContext()
+}
+
+// traceOf is the trace context a message continues, published messages carry it in
+// the lower case traceparent header.
+func traceOf(header stream.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{"traceparent": header.Get("traceparent")})
+}
+
+func spanNamed(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestHandlerContinuesTheTraceOfTheRequest(t *testing.T) {
+	recorder := recordSpans(t)
+	header, parent := remoteParent(t)
+	publisher := &fakePublisher{}
+	handler := newHandler(publisher)
+
+	data, err := json.Marshal(request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 100})
+	require.NoError(t, err)
+	handler.Handle(&fakeMsg{header: header, data: data})
+
+	process := spanNamed(recorder, "fx.convert process")
+	require.NotNil(t, process)
+	assert.Equal(t, trace.SpanKindConsumer, process.SpanKind())
+	assert.Equal(t, parent.TraceID(), process.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanID(), process.Parent().SpanID())
+	assert.Contains(t, process.Attributes(), attribute.String("messaging.system", "nats"))
+	assert.Contains(t, process.Attributes(), attribute.String("messaging.operation", "process"))
+
+	publish := spanNamed(recorder, "fx.converted publish")
+	require.NotNil(t, publish)
+	assert.Equal(t, trace.SpanKindProducer, publish.SpanKind())
+	assert.Equal(t, process.SpanContext().SpanID(), publish.Parent().SpanID())
+
+	reply := traceOf(publisher.replies[0].Header)
+	assert.Equal(t, publish.SpanContext().SpanID(), trace.SpanContextFromContext(reply).SpanID(), "the reply continues the trace")
+}
+
+func TestRelayContinuesTheTraceOfTheChange(t *testing.T) {
+	recordSpans(t)
+	ctx, parent := otel.Tracer("admin").Start(context.Background(), "update rate")
+	parent.End()
+	event := rateEvent("1", "USD", "EUR")
+	event.TraceContext = propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(event.TraceContext))
+	outbox := &memoryOutbox{events: []entity.RateEvent{event}}
+	publisher := &fakePublisher{}
+	relay := &stream.Relay{Outbox: outbox, Publisher: publisher, SubjectPrefix: "fx.rates", Interval: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	relay.Run(ctx)
+
+	require.Len(t, publisher.msgs, 1)
+	published := traceOf(publisher.msgs[0].Header)
+	assert.Equal(t, parent.SpanContext().TraceID(), trace.SpanContextFromContext(published).TraceID())
+}
+
+func TestServiceContinuesTheTraceOfTheRequest(t *testing.T) {
+	recorder := recordSpans(t)
+	nc := startServer(t)
+	startService(t, nc, quoteStore{})
+	header, parent := remoteParent(t)
+
+	msg := nats.NewMsg("fx.svc.convert")
+	msg.Header = nats.Header(header)
+	msg.Data, _ = json.Marshal(request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 100})
+	reply, err := nc.RequestMsg(msg, 2*time.Second)
+	require.NoError(t, err)
+
+	replied := traceOf(stream.Header(reply.Header))
+	assert.Equal(t, parent.TraceID(), trace.SpanContextFromContext(replied).TraceID())
+	require.Eventually(t, func() bool { return spanNamed(recorder, "fx.svc.convert process") != nil }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,163 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// kafkaBroker stands in for the reader and the writer of a kafka transport.
+type kafkaBroker struct {
+	fetched chan kafka.Message
+
+	mu        sync.Mutex
+	written   []kafka.Message
+	committed []int64
+}
+
+func newKafkaBroker() *kafkaBroker {
+	return &kafkaBroker{fetched: make(chan kafka.Message, 16)}
+}
+
+func (b *kafkaBroker) add(partition int, offsets ...int64) {
+	for _, offset := range offsets {
+		b.fetched <- kafka.Message{Topic: "fx.convert", Partition: partition, Offset: offset, Value: []byte("{}")}
+	}
+}
+
+func (b *kafkaBroker) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case km := <-b.fetched:
+		return km, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (b *kafkaBroker) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, km := range msgs {
+		b.committed = append(b.committed, km.Offset)
+	}
+	return nil
+}
+
+func (b *kafkaBroker) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.written = append(b.written, msgs...)
+	return nil
+}
+
+func (b *kafkaBroker) Close() error { return nil }
+
+func (b *kafkaBroker) commits() []int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]int64(nil), b.committed...)
+}
+
+// fetch returns the messages of a fetch by their offset.
+func fetch(t *testing.T, transport stream.Transport, batch int, maxWait time.Duration) map[uint64]stream.Message {
+	b, err := transport.Fetch(context.Background(), batch, maxWait)
+	require.NoError(t, err)
+	messages := map[uint64]stream.Message{}
+	for m := range b.Messages() {
+		messages[m.Sequence()] = m
+	}
+	require.NoError(t, b.Error())
+	return messages
+}
+
+func TestKafkaCommitsOnlyOffsetsWithoutGaps(t *testing.T) {
+	broker := newKafkaBroker()
+	transport := stream.NewKafkaTransport(broker, broker, "fx.convert")
+	broker.add(0, 0, 1, 2)
+	messages := fetch(t, transport, 3, time.Second)
+	require.Len(t, messages, 3)
+
+	require.NoError(t, messages[1].Ack())
+	require.NoError(t, messages[2].Term())
+	assert.Empty(t, broker.commits(), "offset 0 is still in flight")
+
+	require.NoError(t, messages[0].Ack())
+	assert.Equal(t, []int64{2}, broker.commits(), "every offset up to 2 is done")
+}
+
+func TestKafkaForgetsOffsetsOfAReassignedPartition(t *testing.T) {
+	broker := newKafkaBroker()
+	transport := stream.NewKafkaTransport(broker, broker, "fx.convert")
+	broker.add(0, 5, 6)
+	before := fetch(t, transport, 2, time.Second)
+
+	// the partition was assigned again and is read from its committed offset
+	broker.add(0, 3)
+	after := fetch(t, transport, 1, time.Second)
+	require.NoError(t, after[3].Ack())
+	assert.Equal(t, []int64{3}, broker.commits())
+
+	require.NoError(t, before[5].Ack())
+	require.NoError(t, before[6].Ack())
+	assert.Equal(t, []int64{3}, broker.commits(), "offsets of the previous assignment are not committed")
+}
+
+func TestKafkaHoldsRetriesBackUntilTheyAreDue(t *testing.T) {
+	broker := newKafkaBroker()
+	transport := stream.NewKafkaTransport(broker, broker, "fx.convert")
+	broker.add(0, 0)
+	first := fetch(t, transport, 1, time.Second)
+	require.NoError(t, first[0].Retry(100*time.Millisecond))
+	assert.Equal(t, []int64{0}, broker.commits(), "the delivery is done once its retry is published")
+
+	require.Len(t, broker.written, 1)
+	retry := broker.written[0]
+	retry.Partition, retry.Offset = 0, 1
+	broker.fetched <- retry
+
+	assert.Empty(t, fetch(t, transport, 1, 20*time.Millisecond), "the retry is not due yet")
+	started := time.Now()
+	due := fetch(t, transport, 1, time.Second)
+	require.Len(t, due, 1)
+	assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond, "the fetch waits for the retry")
+	assert.Equal(t, 2, due[1].Deliveries())
+}
+
+func TestKafkaPublishesToTheTopicOfTheLongestPrefix(t *testing.T) {
+	broker := newKafkaBroker()
+	transport := stream.NewKafkaTransport(broker, nil, "fx.rates", "fx.rates.7", "fx.converted")
+
+	for _, subject := range []string{"fx.rates.7.USD.EUR", "fx.rates.1.USD.EUR", "fx.converted", "fx.ratesX"} {
+		require.NoError(t, transport.Publish(context.Background(), stream.NewMsg(subject)))
+	}
+	topics := map[string]string{}
+	for _, km := range broker.written {
+		subject := ""
+		for _, header := range km.Headers {
+			if header.Key == stream.HeaderSubject {
+				subject = string(header.Value)
+			}
+		}
+		topics[string(km.Key)] = km.Topic
+		if km.Topic == string(km.Key) {
+			assert.Empty(t, subject, "a subject published to its own topic needs no header")
+		} else {
+			assert.Equal(t, string(km.Key), subject)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"fx.rates.7.USD.EUR": "fx.rates.7",
+		"fx.rates.1.USD.EUR": "fx.rates",
+		"fx.converted":       "fx.converted",
+		"fx.ratesX":          "fx.ratesX",
+	}, topics)
+
+	_, err := transport.Fetch(context.Background(), 1, time.Millisecond)
+	assert.Error(t, err, "the transport was not opened to consume")
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/model/dto/request"
+	"github.com/PeerIslands/aci-fx-go/service/bal"
+	"github.com/PeerIslands/aci-fx-go/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runPool consumes fx.convert from the memory transport until the condition holds.
+func runPool(t *testing.T, transport *stream.MemoryTransport, handler *stream.Handler, condition func() bool) {
+	handler.Publisher = transport
+	pool := &stream.Pool{Handler: handler, Workers: 4, FetchMaxWait: 10 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Run(ctx, transport)
+	}()
+	assert.Eventually(t, condition, 2*time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func publishRequest(t *testing.T, transport *stream.MemoryTransport, convertRequest request.NatConvertRequest) {
+	data, err := json.Marshal(convertRequest)
+	require.NoError(t, err)
+	msg := stream.NewMsg("fx.convert")
+	msg.Data = data
+	require.NoError(t, transport.Publish(context.Background(), msg))
+}
+
+func TestMemoryTransportRunsConversions(t *testing.T) {
+	transport := stream.NewMemoryTransport("fx.convert")
+	for i := 0; i < 5; i++ {
+		publishRequest(t, transport, request.NatConvertRequest{TenantID: 1, BankID: 1, BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Amount: 100})
+	}
+
+	runPool(t, transport, newHandler(nil), func() bool {
+		acked, _ := transport.Outcomes()
+		return acked == 5
+	})
+	assert.Len(t, transport.Published("fx.converted"), 5)
+}
+
+func TestMemoryTransportRetriesThenDeadLetters(t *testing.T) {
+	transport := stream.NewMemoryTransport("fx.convert")
+	handler := newHandler(nil)
+	handler.FxService = &bal.Fx_service{DbService: handler.FxService.DbService, QuoteService: quoteStore{err: errors.New("connection refused")}}
+	handler.RetryBackoff = time.Millisecond
+	handler.MaxRetryBackoff = 5 * time.Millisecond
+	publishRequest(t, transport, request.NatConvertRequest{Action: request.NatActionQuote, TenantID: 1, BankID: 1,
+		BaseCurrency: "USD", TargetCurrency: "EUR", Tier: "1", Side: "BUY", Amount: 100})
+
+	runPool(t, transport, handler, func() bool {
+		_, termed := transport.Outcomes()
+		return termed == 1
+	})
+	deadLetters := transport.Published("fx.deadletter")
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "3", deadLetters[0].Header.Get(stream.HeaderDeliveries))
+	assert.Equal(t, stream.ErrorClassTransient, deadLetters[0].Header.Get(stream.HeaderErrorClass))
+	assert.Empty(t, transport.Published("fx.converted"), "failed quotes are retried rather than answered")
+}
+
+func TestTransportIsSelectedByConfig(t *testing.T) {
+	fxConfig := &config.Config{}
+	fxConfig.Stream.Transport = stream.TransportKafka
+	assert.False(t, stream.Configured(fxConfig))
+	fxConfig.Kafka.Brokers = "localhost:9092"
+	assert.True(t, stream.Configured(fxConfig))
+	fxConfig.Nats.DeadLetterSubject = "fx.deadletter"
+	assert.Nil(t, stream.GetDeadLetters(fxConfig), "kafka does not use the nats dead letter subject")
+	fxConfig.Kafka.DeadLetterTopic = "fx.deadletter"
+	assert.NotNil(t, stream.GetDeadLetters(fxConfig))
+
+	_, err := stream.OpenTransport(fxConfig, true)
+	assert.Error(t, err, "consuming kafka needs a consumer group and a listen topic")
+
+	fxConfig.Stream.Transport = "rabbitmq"
+	_, err = stream.OpenTransport(fxConfig, false)
+	assert.Error(t, err)
+}
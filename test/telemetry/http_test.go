@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/model/dto/response"
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording the ended spans for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func newApp() *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.Status(fiber.StatusOK).JSON(common.GetSimpleResponse[response.QuoteResponse](nil, response.InternalError, nil))
+		},
+	})
+	app.Use(telemetry.Middleware())
+	app.Get("/api/quotes/:id", func(c *fiber.Ctx) error {
+		// handlers add to the server span rather than starting their own
+		span := trace.SpanFromContext(c.UserContext())
+		span.SetAttributes(attribute.String("quote.id", c.Params("id")))
+		member := baggage.FromContext(c.UserContext()).Member("tenant")
+		return c.JSON(common.GetSimpleResponse[response.QuoteResponse](&response.QuoteResponse{QuoteId: member.Value()}, response.Success, nil))
+	})
+	app.Post("/api/quotes", func(c *fiber.Ctx) error {
+		return errors.New("connection refused")
+	})
+	app.Get("/api/tenants", func(c *fiber.Ctx) error {
+		return c.JSON(common.GetArrayResponse[response.QuoteResponse](nil, response.InternalError, nil))
+	})
+	return app
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestMiddlewareContinuesTheTraceOfTheCaller(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, parent := otel.Tracer("gateway").Start(context.Background(), "route")
+	parent.End()
+	member, _ := baggage.NewMember("tenant", "7")
+	bag, _ := baggage.New(member)
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/quotes/42", nil)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := newApp().Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var quote response.ResponseWithSimpleData[response.QuoteResponse]
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&quote))
+	assert.Equal(t, "7", quote.Data.QuoteId, "baggage reaches the handler")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	span := spans[1]
+	assert.Equal(t, "GET /api/quotes/:id", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	attrs := attributes(span)
+	assert.Equal(t, "GET", attrs["http.request.method"].AsString())
+	assert.Equal(t, "/api/quotes/:id", attrs["http.route"].AsString())
+	assert.Equal(t, "/api/quotes/42", attrs["url.path"].AsString())
+	assert.Equal(t, int64(200), attrs["http.response.status_code"].AsInt64())
+	assert.Positive(t, attrs["http.response.body.size"].AsInt64())
+	assert.Equal(t, "Success", attrs["fx.response.status"].AsString())
+	assert.Equal(t, "42", attrs["quote.id"].AsString())
+}
+
+func TestMiddlewareRecordsErrors(t *testing.T) {
+	recorder := recordSpans(t)
+	app := newApp()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/api/quotes", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	span := recorder.Ended()[0]
+	assert.Equal(t, "POST /api/quotes", span.Name())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, "connection refused", span.Status().Description)
+	require.Len(t, span.Events(), 1)
+	assert.Equal(t, "exception", span.Events()[0].Name)
+	assert.Equal(t, "InternalServerError", attributes(span)["fx.response.status"].AsString())
+
+	_, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/tenants", nil))
+	require.NoError(t, err)
+	span = recorder.Ended()[1]
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	_, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/unknown", nil))
+	require.NoError(t, err)
+	span = recorder.Ended()[2]
+	assert.Equal(t, "GET", span.Name(), "unmatched paths do not name the span")
+	assert.NotContains(t, attributes(span), attribute.Key("http.route"))
+}
@@ -0,0 +1,145 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/PeerIslands/aci-fx-go/service/common"
+	"github.com/PeerIslands/aci-fx-go/service/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// captureLogs sends the logs of the test to a buffer as json.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buffer bytes.Buffer
+	out, formatter, level, hooks := common.Logger.Out, common.Logger.Formatter, common.LogLevel(), common.Logger.Hooks
+	common.Logger.SetOutput(&buffer)
+	common.Logger.ReplaceHooks(logrus.LevelHooks{})
+	require.NoError(t, common.InitLog("info", common.LogFormatJson))
+	t.Cleanup(func() {
+		common.Logger.SetOutput(out)
+		common.Logger.SetFormatter(formatter)
+		common.Logger.ReplaceHooks(hooks)
+		_ = common.SetLogLevel(level)
+	})
+	return &buffer
+}
+
+func TestLogCarriesTheRequestContext(t *testing.T) {
+	recorder := recordSpans(t)
+	logs := captureLogs(t)
+	app := fiber.New()
+	app.Use(telemetry.Middleware())
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(common.WithTenantScope(c.UserContext(), common.TenantScope{TenantId: 7}))
+		return c.Next()
+	})
+	app.Get("/api/quotes/:id", func(c *fiber.Ctx) error {
+		common.Log(c.UserContext()).Infof("Quote %s requested", c.Params("id"))
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	_, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/quotes/42", nil))
+	require.NoError(t, err)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+	span := recorder.Ended()[0]
+	assert.Equal(t, "Quote 42 requested", line["msg"])
+	assert.Equal(t, span.SpanContext().TraceID().String(), line["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), line["span_id"])
+	assert.Equal(t, float64(7), line["tenant"])
+	assert.Equal(t, "/api/quotes/:id", line["route"])
+
+	logs.Reset()
+	common.Log(context.Background()).Info("Background work")
+	var background map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &background))
+	assert.NotContains(t, background, "trace_id", "work outside a request has no trace")
+}
+
+func TestLogLevelChangesWhileRunning(t *testing.T) {
+	logs := captureLogs(t)
+	common.Log(context.Background()).Debug("hidden")
+	assert.Empty(t, logs.String())
+
+	require.NoError(t, common.SetLogLevel("debug"))
+	assert.Equal(t, "debug", common.LogLevel())
+	common.Log(context.Background()).Debug("shown")
+	assert.Contains(t, logs.String(), "shown")
+
+	assert.Error(t, common.SetLogLevel("verbose"))
+	assert.Equal(t, "debug", common.LogLevel())
+	assert.Error(t, common.InitLog("info", "xml"))
+}
+
+// logExporter keeps the exported log records.
+type logExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *logExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *logExporter) Shutdown(context.Context) error   { return nil }
+func (e *logExporter) ForceFlush(context.Context) error { return nil }
+
+func TestLogHookExportsLogsWithTheirSpan(t *testing.T) {
+	recordSpans(t)
+	captureLogs(t)
+	exporter := &logExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	common.Logger.AddHook(telemetry.LogHook(provider))
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "request")
+	ctx = common.WithTenantScope(ctx, common.TenantScope{TenantId: 7})
+	common.Log(ctx).Warnf("Rate of %s is stale", "USD/EUR")
+	span.End()
+
+	require.Len(t, exporter.records, 1)
+	record := exporter.records[0]
+	assert.Equal(t, "Rate of USD/EUR is stale", record.Body().AsString())
+	assert.Equal(t, log.SeverityWarn, record.Severity())
+	assert.Equal(t, span.SpanContext().TraceID(), record.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), record.SpanID())
+	attrs := map[string]log.Value{}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	assert.Equal(t, int64(7), attrs["tenant"].AsInt64())
+	assert.NotContains(t, attrs, "trace_id")
+}
+
+func TestExportErrorsAreNotExported(t *testing.T) {
+	logs := captureLogs(t)
+	exporter := &logExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	common.Logger.AddHook(telemetry.LogHook(provider))
+
+	telemetry.ErrorHandler(common.Logger).Handle(errors.New("collector unreachable"))
+
+	assert.Empty(t, exporter.records)
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+	assert.Equal(t, "Error in exporting telemetry. Exception:collector unreachable", line["msg"])
+}
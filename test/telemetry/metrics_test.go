@@ -0,0 +1,76 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var (
+	metricReader     *sdkmetric.ManualReader
+	metricReaderOnce sync.Once
+)
+
+// requestCounts returns the requests measured so far by method, route, envelope status
+// and failure. Instruments are bound to the first meter provider installed, so every
+// test shares the same reader.
+func requestCounts(t *testing.T) map[string]uint64 {
+	metricReaderOnce.Do(func() {
+		metricReader = sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metricReader)))
+	})
+	var metrics metricdata.ResourceMetrics
+	require.NoError(t, metricReader.Collect(context.Background(), &metrics))
+	counts := map[string]uint64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != "http.server.request.duration" {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				method, _ := point.Attributes.Value("http.request.method")
+				route, _ := point.Attributes.Value("http.route")
+				status, _ := point.Attributes.Value("fx.response.status")
+				key := method.AsString() + " " + route.AsString() + " " + status.AsString()
+				if _, failed := point.Attributes.Value(attribute.Key("error.type")); failed {
+					key += " failed"
+				}
+				counts[key] += point.Count
+			}
+		}
+	}
+	return counts
+}
+
+func TestMiddlewareMeasuresRequestsByRoute(t *testing.T) {
+	before := requestCounts(t)
+	app := newApp()
+
+	for _, path := range []string{"/api/quotes/1", "/api/quotes/2", "/api/tenants"} {
+		_, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		require.NoError(t, err)
+	}
+	_, err := app.Test(httptest.NewRequest(http.MethodPost, "/api/quotes", nil))
+	require.NoError(t, err)
+
+	measured := map[string]uint64{}
+	for key, count := range requestCounts(t) {
+		if count > before[key] {
+			measured[key] = count - before[key]
+		}
+	}
+	assert.Equal(t, map[string]uint64{
+		"GET /api/quotes/:id Success":                 2,
+		"GET /api/tenants InternalServerError":        1,
+		"POST /api/quotes InternalServerError failed": 1,
+	}, measured)
+}
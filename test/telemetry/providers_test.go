@@ -0,0 +1,113 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PeerIslands/aci-fx-go/config"
+	"github.com/PeerIslands/aci-fx-go/service/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func telemetryConfig(t *testing.T, env map[string]string) *config.Config {
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	return config.GetConfig()
+}
+
+func TestResourceDescribesTheService(t *testing.T) {
+	fxConfig := telemetryConfig(t, map[string]string{
+		"OTEL_SERVICE_NAME":        "fx-rates",
+		"SERVICE_VERSION":          "1.4.0",
+		"DEPLOYMENT_ENVIRONMENT":   "staging",
+		"OTEL_RESOURCE_ATTRIBUTES": "service.version=0.0.1,team=payments",
+	})
+	resource, err := telemetry.NewResource(fxConfig)
+	require.NoError(t, err)
+
+	attrs := map[attribute.Key]string{}
+	for _, attr := range resource.Attributes() {
+		attrs[attr.Key] = attr.Value.Emit()
+	}
+	assert.Equal(t, "fx-rates", attrs["service.name"])
+	assert.Equal(t, "1.4.0", attrs["service.version"], "the configuration wins over the environment")
+	assert.Equal(t, "staging", attrs["deployment.environment"])
+	assert.Equal(t, "payments", attrs["team"])
+}
+
+func TestTracerProviderSamplesNewTracesByRatio(t *testing.T) {
+	fxConfig := telemetryConfig(t, map[string]string{
+		"TELEMETRY_EXPORTER":     telemetry.ExporterNone,
+		"TELEMETRY_SAMPLE_RATIO": "0",
+	})
+	resource, err := telemetry.NewResource(fxConfig)
+	require.NoError(t, err)
+	tp, err := telemetry.NewTracerProvider(fxConfig, resource)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	_, root := tp.Tracer("test").Start(context.Background(), "root")
+	root.End()
+	assert.False(t, root.SpanContext().IsSampled())
+
+	// traces continued from a caller keep its decision
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	_, child := tp.Tracer("test").Start(trace.ContextWithRemoteSpanContext(context.Background(), parent), "child")
+	child.End()
+	assert.True(t, child.SpanContext().IsSampled())
+}
+
+func TestProvidersRunWithoutACollector(t *testing.T) {
+	fxConfig := telemetryConfig(t, map[string]string{
+		"TELEMETRY_ENDPOINT": "127.0.0.1:1",
+		"TELEMETRY_INSECURE": "true",
+		"TELEMETRY_HEADERS":  "authorization=Bearer token, x-tenant=acme",
+		"PROMETHEUS_ADDRESS": "127.0.0.1:0",
+	})
+	for _, exporter := range []string{telemetry.ExporterOtlpGrpc, telemetry.ExporterOtlpHttp} {
+		fxConfig.Telemetry.Exporter = exporter
+		resource, err := telemetry.NewResource(fxConfig)
+		require.NoError(t, err)
+		tp, err := telemetry.NewTracerProvider(fxConfig, resource)
+		require.NoError(t, err, exporter)
+		mp, server, err := telemetry.NewMeterProvider(fxConfig, resource)
+		require.NoError(t, err, exporter)
+		assert.NotNil(t, server, "metrics are served for scraping")
+
+		_, span := tp.Tracer("test").Start(context.Background(), "request")
+		span.End()
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		// the export fails, shutting down gives up at the deadline
+		_ = tp.Shutdown(ctx)
+		_ = mp.Shutdown(ctx)
+		cancel()
+	}
+}
+
+func TestProvidersRejectInvalidSettings(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"exporter":     {"TELEMETRY_EXPORTER": "jaeger"},
+		"sample ratio": {"TELEMETRY_SAMPLE_RATIO": "2"},
+		"insecure":     {"TELEMETRY_INSECURE": "maybe"},
+		"headers":      {"TELEMETRY_HEADERS": "authorization"},
+		"ca file":      {"TELEMETRY_CA_FILE": "missing.pem"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fxConfig := telemetryConfig(t, env)
+			resource, err := telemetry.NewResource(fxConfig)
+			require.NoError(t, err)
+			_, err = telemetry.NewTracerProvider(fxConfig, resource)
+			assert.Error(t, err)
+		})
+	}
+}